@@ -0,0 +1,90 @@
+package ancestrymanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ReadAncestryFile reads a YAML or JSON file mapping projects, folders and
+// project numbers to ancestry paths, for example:
+//
+//	my-project: organization/123/folder/456
+//	projects/1234567890: organization/123/folder/456
+//	folders/456: organization/123
+//
+// The result can be passed as entries to New. Keys without a `projects/`,
+// `folders/` or `organizations/` prefix are treated as projects.
+func ReadAncestryFile(path string) (map[string]string, error) {
+	return readMappingFile(path, "ancestry")
+}
+
+// ReadBucketProjectFile reads a YAML or JSON file mapping storage bucket names
+// to the ID or number of their project, for example:
+//
+//	my-bucket: my-project
+//	other-bucket: "1234567890"
+//
+// The result can be passed as Options.BucketProjects to NewWithOptions.
+func ReadBucketProjectFile(path string) (map[string]string, error) {
+	return readMappingFile(path, "bucket project")
+}
+
+// ReadProjectNumberFile reads a YAML or JSON file mapping project IDs to
+// project numbers, for example:
+//
+//	my-project: 1234567890
+//
+// The result can be passed as Options.ProjectNumbers to NewWithOptions.
+func ReadProjectNumberFile(path string) (map[string]string, error) {
+	entries, err := readMappingFile(path, "project number")
+	if err != nil {
+		return nil, err
+	}
+	for id, number := range entries {
+		if !isProjectNumber(strings.TrimPrefix(number, "projects/")) {
+			return nil, fmt.Errorf("project number file %s: invalid project number %q for %q", path, number, id)
+		}
+	}
+	return entries, nil
+}
+
+// readMappingFile reads a YAML or JSON mapping to non-empty strings or
+// numbers, such as project numbers. kind describes the file in errors.
+func readMappingFile(path, kind string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s file: %w", kind, err)
+	}
+	content, err = yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s file %s: %w", kind, path, err)
+	}
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("parsing %s file %s: %w", kind, path, err)
+	}
+	entries := make(map[string]string, len(values))
+	for key, value := range values {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		default:
+			return nil, fmt.Errorf("%s file %s: value for %q must be a string, got %v", kind, path, key, value)
+		}
+		if key == "" || s == "" {
+			return nil, fmt.Errorf("%s file %s: empty key or value for %q", kind, path, key)
+		}
+		entries[key] = s
+	}
+	return entries, nil
+}
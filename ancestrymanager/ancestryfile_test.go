@@ -0,0 +1,158 @@
+package ancestrymanager
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadAncestryFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name: "yaml",
+			file: "ancestry.yaml",
+			content: `
+my-project: organization/123/folder/456
+projects/1234567890: organization/123/folder/456
+12345: organizations/123
+folders/456: organization/123
+`,
+			want: map[string]string{
+				"my-project":          "organization/123/folder/456",
+				"projects/1234567890": "organization/123/folder/456",
+				"12345":               "organizations/123",
+				"folders/456":         "organization/123",
+			},
+		},
+		{
+			name:    "json",
+			file:    "ancestry.json",
+			content: `{"my-project": "organization/123/folder/456", "folders/456": "organization/123"}`,
+			want: map[string]string{
+				"my-project":  "organization/123/folder/456",
+				"folders/456": "organization/123",
+			},
+		},
+		{
+			name:    "empty",
+			file:    "ancestry.yaml",
+			content: "",
+			want:    map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadAncestryFile(path)
+			if err != nil {
+				t.Fatalf("ReadAncestryFile() = %s, want = nil", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ReadAncestryFile() returned unexpected diff (-want +got):\n%s", diff)
+			}
+
+			m := &manager{
+				ancestorCache: make(map[string][]string),
+				sources:       map[string]ancestrySource{},
+			}
+			if err := m.initAncestryCache(got); err != nil {
+				t.Errorf("initAncestryCache(%v) = %s, want = nil", got, err)
+			}
+		})
+	}
+}
+
+func TestReadAncestryFile_Fail(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "not a mapping",
+			content: "- organization/123",
+		},
+		{
+			name:    "nested value",
+			content: "my-project:\n  organization: 123",
+		},
+		{
+			name:    "empty ancestry",
+			content: "my-project: ''",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ancestry.yaml")
+			if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadAncestryFile(path); err == nil {
+				t.Errorf("ReadAncestryFile() = nil, want = err")
+			}
+		})
+	}
+	if _, err := ReadAncestryFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("ReadAncestryFile() on missing file = nil, want = err")
+	}
+}
+
+func TestReadBucketProjectFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buckets.yaml")
+	content := `
+my-bucket: my-project
+other-bucket: 1234567890
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBucketProjectFile(path)
+	if err != nil {
+		t.Fatalf("ReadBucketProjectFile() = %s, want = nil", err)
+	}
+	want := map[string]string{
+		"my-bucket":    "my-project",
+		"other-bucket": "1234567890",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadBucketProjectFile() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadProjectNumberFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.yaml")
+	content := `
+my-project: 1234567890
+other-project: projects/987
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadProjectNumberFile(path)
+	if err != nil {
+		t.Fatalf("ReadProjectNumberFile() = %s, want = nil", err)
+	}
+	want := map[string]string{
+		"my-project":    "1234567890",
+		"other-project": "projects/987",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadProjectNumberFile() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("my-project: other-project\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadProjectNumberFile(path); err == nil {
+		t.Error("ReadProjectNumberFile() = nil, want error for a project ID value")
+	}
+}
@@ -2,9 +2,12 @@
 package ancestrymanager
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
 	crmv3 "google.golang.org/api/cloudresourcemanager/v3"
@@ -17,7 +20,7 @@ import (
 
 // AncestryManager is the interface that fetch ancestors for a resource.
 type AncestryManager interface {
-	// Ancestors returns a list of ancestors.
+	// Ancestors returns a list of ancestors. It may be called concurrently.
 	Ancestors(config *resources.Config, tfData resources.TerraformResourceData, cai *resources.Asset) ([]string, string, error)
 }
 
@@ -35,6 +38,58 @@ type manager struct {
 	// resource's ancestry. The map key is the resource itself, in the format of
 	// "<type>/<id>", ancestors are sorted from closest to furthest.
 	ancestorCache map[string][]string
+	// Where the ancestors of each key of ancestorCache come from.
+	sources map[string]ancestrySource
+	// mu guards ancestorCache and sources, as Ancestors may be called
+	// concurrently.
+	mu sync.Mutex
+	// Deduplicate API lookups of the same ancestry key or bucket, so that
+	// concurrent and repeated lookups only call the API once.
+	ancestryLookups flightGroup
+	bucketLookups   flightGroup
+	projectLookups  flightGroup
+	// Persists ancestry fetched from the API between runs. If this field is
+	// nil, ancestry is only cached in memory.
+	diskCache *DiskCache
+	// Folders, projects and buckets created or updated in the plan. May be
+	// nil.
+	planData *PlanData
+	// Maps storage bucket names to their project ID or number.
+	bucketProjects map[string]string
+	// Maps project IDs to project numbers.
+	projectNumbers map[string]string
+}
+
+// ancestrySource is where cached ancestors come from.
+type ancestrySource int
+
+const (
+	// sourceLookup ancestors were found with folders and projects of the plan
+	// or placeholders, possibly together with the API.
+	sourceLookup ancestrySource = iota
+	// sourceAPI ancestors were all fetched from the API, during this run or
+	// an earlier one saved to the disk cache. Only those are persisted.
+	sourceAPI
+	// sourceEntries ancestors were passed to New. They take precedence over
+	// the plan.
+	sourceEntries
+)
+
+// Options holds the optional inputs of the ancestry manager.
+type Options struct {
+	// DiskCache persists ancestry fetched from the API between runs. Its
+	// unexpired entries are used after the entries passed to NewWithOptions.
+	DiskCache *DiskCache
+	// PlanData holds the folders, projects and storage buckets of the plan,
+	// which are used after the entries passed to NewWithOptions, and before
+	// the disk cache and the API.
+	PlanData *PlanData
+	// BucketProjects maps storage bucket names to the ID or number of their
+	// project. It is used after PlanData and before the API.
+	BucketProjects map[string]string
+	// ProjectNumbers maps project IDs to project numbers. It is used before
+	// PlanData and the API to find the canonical name of a project.
+	ProjectNumbers map[string]string
 }
 
 // New returns AncestryManager that can be used to fetch ancestry information.
@@ -43,9 +98,19 @@ type manager struct {
 // `folders/`, it will be considered as a project. If offline is true, resource
 // manager API requests for ancestry will be disabled.
 func New(cfg *resources.Config, offline bool, entries map[string]string, errorLogger *zap.Logger) (AncestryManager, error) {
+	return NewWithOptions(cfg, offline, entries, errorLogger, Options{})
+}
+
+// NewWithOptions is like New, with the optional inputs given by opts.
+func NewWithOptions(cfg *resources.Config, offline bool, entries map[string]string, errorLogger *zap.Logger, opts Options) (AncestryManager, error) {
 	am := &manager{
-		ancestorCache: map[string][]string{},
-		errorLogger:   errorLogger,
+		ancestorCache:  map[string][]string{},
+		sources:        map[string]ancestrySource{},
+		errorLogger:    errorLogger,
+		diskCache:      opts.DiskCache,
+		planData:       opts.PlanData,
+		bucketProjects: opts.BucketProjects,
+		projectNumbers: opts.ProjectNumbers,
 	}
 	if !offline {
 		am.resourceManagerV1 = cfg.NewResourceManagerClient(cfg.GetUserAgent())
@@ -56,25 +121,53 @@ func New(cfg *resources.Config, offline bool, entries map[string]string, errorLo
 	if err != nil {
 		return nil, err
 	}
+	am.initFromDiskCache()
 	return am, nil
 }
 
+// initFromDiskCache adds the entries of the disk cache to the ancestor cache.
+// It runs after initAncestryCache, so entries passed to New take precedence.
+// A corrupt or unreadable cache is not fatal as the API can be used instead.
+func (m *manager) initFromDiskCache() {
+	if m.diskCache == nil {
+		return
+	}
+	entries, err := m.diskCache.Load()
+	if err != nil {
+		m.errorLogger.Warn(fmt.Sprintf("Ignoring ancestry cache: %s", err))
+		return
+	}
+	m.errorLogger.Debug(fmt.Sprintf("Loaded %d ancestry entries from %s", len(entries), m.diskCache.Path()))
+	for key, ancestors := range entries {
+		m.store(key, ancestors, sourceAPI, 0)
+	}
+}
+
+// initAncestryCache adds entries to the ancestor cache. Entries with an empty
+// key or ancestry path are ignored; malformed ones are an error.
 func (m *manager) initAncestryCache(entries map[string]string) error {
-	for item, ancestry := range entries {
+	items := make([]string, 0, len(entries))
+	for item := range entries {
+		items = append(items, item)
+	}
+	// Sort for a deterministic result when several entries are malformed.
+	sort.Strings(items)
+	for _, item := range items {
+		ancestry := entries[item]
 		if item != "" && ancestry != "" {
 			ancestors, err := parseAncestryPath(ancestry)
 			if err != nil {
-				continue
+				return fmt.Errorf("invalid ancestry for %s: %w", item, err)
 			}
 			key, err := parseAncestryKey(item)
 			if err != nil {
-				return err
+				return fmt.Errorf("invalid ancestry key %s: %w", item, err)
 			}
 			// ancestry path should include the item itself
 			if ancestors[0] != key {
 				ancestors = append([]string{key}, ancestors...)
 			}
-			m.store(key, ancestors)
+			m.store(key, ancestors, sourceEntries, len(ancestors))
 		}
 	}
 	return nil
@@ -136,7 +229,11 @@ func (m *manager) fetchAncestors(config *resources.Config, tfData resources.Terr
 			folderKey = fmt.Sprintf("folders/%s", folderKey)
 		}
 	}
-	project, _ := m.getProjectFromResource(tfData, config, cai)
+	project, err := m.getProjectFromResource(tfData, config, cai)
+	var bucketErr *UnresolvedBucketError
+	if errors.As(err, &bucketErr) {
+		return nil, err
+	}
 	if project != "" {
 		projectKey = project
 		if !strings.HasPrefix(projectKey, "projects/") {
@@ -210,11 +307,50 @@ func (m *manager) fetchAncestors(config *resources.Config, tfData resources.Terr
 }
 
 func (m *manager) getAncestorsWithCache(key string) ([]string, error) {
+	val, shared, err := m.ancestryLookups.do(key, func() (interface{}, error) {
+		return m.lookupAncestors(key)
+	})
+	if shared {
+		m.errorLogger.Debug(fmt.Sprintf("Reusing ancestry lookup for %s", key))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), val.([]string)...), nil
+}
+
+// lookupAncestors walks up the resource hierarchy from key, using the entries
+// passed to New first, then folders and projects created or changed in the
+// plan, then other cached ancestors, and the resource manager API otherwise.
+// The ancestors are persisted to the disk cache only if they were all fetched
+// from the API.
+func (m *manager) lookupAncestors(key string) ([]string, error) {
 	var ancestors []string
+	fromAPI := false
+	// Ancestors from apiFrom on were all fetched from the API.
+	apiFrom := 0
 	cur := key
 	for cur != "" {
-		if cachedAncestors, ok := m.ancestorCache[cur]; ok {
+		cachedAncestors, source, cached := m.cached(cur)
+		if cached && source == sourceEntries {
+			ancestors = append(ancestors, cachedAncestors...)
+			apiFrom = len(ancestors)
+			break
+		}
+		if planned, next, ok := m.planData.ancestors(cur); ok {
+			ancestors = append(ancestors, planned...)
+			if next == "" {
+				ancestors = append(ancestors, "organizations/unknown")
+			}
+			apiFrom = len(ancestors)
+			cur = next
+			continue
+		}
+		if cached {
 			ancestors = append(ancestors, cachedAncestors...)
+			if source != sourceAPI {
+				apiFrom = len(ancestors)
+			}
 			break
 		}
 		if strings.HasPrefix(cur, "organizations/") {
@@ -224,6 +360,7 @@ func (m *manager) getAncestorsWithCache(key string) ([]string, error) {
 		if m.resourceManagerV3 == nil || m.resourceManagerV1 == nil {
 			return nil, fmt.Errorf("resourceManager required to fetch ancestry for %s from the API", cur)
 		}
+		fromAPI = true
 		if strings.HasPrefix(cur, "projects") {
 			// fall back to use v1 API GetAncestry to avoid requiring extra folder permission
 			projectID := strings.TrimPrefix(cur, "projects/")
@@ -246,7 +383,10 @@ func (m *manager) getAncestorsWithCache(key string) ([]string, error) {
 			cur = project.Parent
 		}
 	}
-	m.store(key, ancestors)
+	m.store(key, ancestors, sourceLookup, apiFrom)
+	if fromAPI && apiFrom == 0 && m.diskCache != nil {
+		m.diskCache.Add(key, ancestors)
+	}
 	return ancestors, nil
 }
 
@@ -258,21 +398,43 @@ func handleCRMError(resource string, err error) error {
 	return err
 }
 
-func (m *manager) store(key string, ancestors []string) {
+// cached returns the cached ancestors of key and where they come from.
+func (m *manager) cached(key string) ([]string, ancestrySource, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ancestors, ok := m.ancestorCache[key]
+	return ancestors, m.sources[key], ok
+}
+
+// store caches the ancestors of key and of each of its ancestors. The
+// ancestors come from source, except that those from apiFrom on were all
+// fetched from the API.
+func (m *manager) store(key string, ancestors []string, source ancestrySource, apiFrom int) {
 	if key == "" || len(ancestors) == 0 {
 		return
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, ok := m.ancestorCache[key]; !ok {
 		m.ancestorCache[key] = ancestors
+		m.sources[key] = storedSource(source, apiFrom, 0)
 	}
 	// cache ancestors along the ancestry path
 	for i, ancestor := range ancestors {
 		if _, ok := m.ancestorCache[ancestor]; !ok {
 			m.ancestorCache[ancestor] = ancestors[i:]
+			m.sources[ancestor] = storedSource(source, apiFrom, i)
 		}
 	}
 }
 
+func storedSource(source ancestrySource, apiFrom, i int) ancestrySource {
+	if i >= apiFrom {
+		return sourceAPI
+	}
+	return source
+}
+
 func parseAncestryPath(path string) ([]string, error) {
 	normStr := normalizeAncestry(path)
 	splits := strings.Split(normStr, "/")
@@ -289,6 +451,9 @@ func parseAncestryPath(path string) ([]string, error) {
 		if _, ok := allowedPrefixes[splits[i]]; !ok {
 			return nil, fmt.Errorf("invalid ancestry path %s with %s", path, splits[i])
 		}
+		if splits[i+1] == "" {
+			return nil, fmt.Errorf("invalid ancestry path %s with empty %s id", path, splits[i])
+		}
 		ancestors = append(ancestors, fmt.Sprintf("%s/%s", splits[i], splits[i+1]))
 	}
 	// reverse the sequence
@@ -315,6 +480,45 @@ func normalizeAncestry(val string) string {
 	return val
 }
 
+// getBucketProject returns the project ID or number of a storage bucket. It
+// looks for the bucket in the plan, then in the bucket mapping, and then uses
+// the storage API if online. It returns an *UnresolvedBucketError if the
+// bucket's project cannot be found.
+func (m *manager) getBucketProject(bucket string, config *resources.Config) (string, error) {
+	if project, ok := m.planData.bucketProject(bucket); ok {
+		if project != "" {
+			return project, nil
+		}
+		// The bucket is created in the provider's project.
+		if config != nil && config.Project != "" {
+			return config.Project, nil
+		}
+	}
+	if project := m.bucketProjects[bucket]; project != "" {
+		return project, nil
+	}
+	if m.storageClient == nil {
+		return "", &UnresolvedBucketError{
+			Bucket: bucket,
+			Err:    errors.New("bucket is not in the plan or the bucket mapping, and the storage API is not used offline"),
+		}
+	}
+	project, shared, err := m.bucketLookups.do(bucket, func() (interface{}, error) {
+		resp, err := m.storageClient.Buckets.Get(bucket).Do()
+		if err != nil {
+			return nil, err
+		}
+		return strconv.Itoa(int(resp.ProjectNumber)), nil
+	})
+	if shared {
+		m.errorLogger.Debug(fmt.Sprintf("Reusing lookup for bucket %s", bucket))
+	}
+	if err != nil {
+		return "", &UnresolvedBucketError{Bucket: bucket, Err: err}
+	}
+	return project.(string), nil
+}
+
 // getProjectFromResource reads the "project" field from the given resource data and falls
 // back to the provider's value if not given. If the provider's value is not
 // given, an error is returned.
@@ -344,16 +548,20 @@ func (m *manager) getProjectFromResource(d resources.TerraformResourceData, conf
 		m.errorLogger.Warn(fmt.Sprintf("Failed to retrieve project_id for %s from cai resource", cai.Name))
 
 		bucketField, ok := d.GetOk("bucket")
-		if ok {
-			bucket := bucketField.(string)
-			resp, err := m.storageClient.Buckets.Get(bucket).Do()
-			if err == nil {
-				projectNum := resp.ProjectNumber
-				return strconv.Itoa(int(projectNum)), nil
-			}
-			m.errorLogger.Warn(fmt.Sprintf("Failed to get bucket %s", bucket))
+		if !ok {
+			m.errorLogger.Warn("Failed to retrieve bucket field from tf data")
+			break
+		}
+		bucket := strings.TrimPrefix(bucketField.(string), "b/")
+		project, err := m.getBucketProject(bucket, config)
+		if err == nil {
+			return project, nil
+		}
+		m.errorLogger.Warn(err.Error())
+		if project, schemaErr := getProjectFromSchema("project", d, config); schemaErr == nil {
+			return project, nil
 		}
-		m.errorLogger.Warn("Failed to retrieve bucket field from tf data")
+		return "", err
 	}
 
 	return getProjectFromSchema("project", d, config)
@@ -2,6 +2,7 @@ package ancestrymanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,9 +11,11 @@ import (
 	"testing"
 
 	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/fakegcp"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfdata"
 
 	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
 	provider "github.com/hashicorp/terraform-provider-google/google"
 	"go.uber.org/zap"
 	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
@@ -508,6 +511,7 @@ func TestGetAncestors(t *testing.T) {
 				ancestryManager := &manager{
 					errorLogger:   zap.NewExample(),
 					ancestorCache: make(map[string][]string),
+					sources:       map[string]ancestrySource{},
 				}
 				if !offline {
 					ancestryManager.resourceManagerV3 = mockV3Client
@@ -772,6 +776,7 @@ func TestGetAncestors_Folder(t *testing.T) {
 			ancestryManager := &manager{
 				errorLogger:       zap.NewExample(),
 				ancestorCache:     make(map[string][]string),
+				sources:           map[string]ancestrySource{},
 				resourceManagerV3: mockV3Client,
 				resourceManagerV1: mockV1Client,
 			}
@@ -898,6 +903,7 @@ func TestGetAncestorsWithCache(t *testing.T) {
 			m := &manager{
 				errorLogger:       zap.NewExample(),
 				ancestorCache:     test.cache,
+				sources:           map[string]ancestrySource{},
 				resourceManagerV3: mockV3Client,
 				resourceManagerV1: mockV1Client,
 			}
@@ -950,6 +956,7 @@ func TestGetAncestorsWithCache_Fail(t *testing.T) {
 			m := &manager{
 				errorLogger:       zap.NewExample(),
 				ancestorCache:     test.cache,
+				sources:           map[string]ancestrySource{},
 				resourceManagerV3: mockV3Client,
 				resourceManagerV1: mockV1Client,
 			}
@@ -1018,6 +1025,11 @@ func TestParseAncestryPath_Fail(t *testing.T) {
 			path:    "org/123/folders/123",
 			wantErr: "invalid ancestry path",
 		},
+		{
+			name:    "empty id",
+			path:    "organizations//folders/123",
+			wantErr: "empty organizations id",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -1085,6 +1097,7 @@ func TestInitAncestryCache(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			m := &manager{
 				ancestorCache: make(map[string][]string),
+				sources:       map[string]ancestrySource{},
 			}
 			err := m.initAncestryCache(test.entries)
 			if err != nil {
@@ -1108,11 +1121,25 @@ func TestInitAncestryCache_Fail(t *testing.T) {
 				"foldres/def": "organizations/123",
 			},
 		},
+		{
+			name: "malformed ancestry path",
+			entries: map[string]string{
+				"test-proj": "organizations/123/folders",
+			},
+		},
+		{
+			name: "malformed ancestry path among valid entries",
+			entries: map[string]string{
+				"test-proj":   "organizations/123/folders/345",
+				"folders/345": "organization/123/folder",
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			m := &manager{
 				ancestorCache: make(map[string][]string),
+				sources:       map[string]ancestrySource{},
 			}
 			err := m.initAncestryCache(test.entries)
 			if err == nil {
@@ -1286,6 +1313,7 @@ func TestUnknownProject(t *testing.T) {
 			ancestryManager := &manager{
 				errorLogger:       zap.NewExample(),
 				ancestorCache:     make(map[string][]string),
+				sources:           map[string]ancestrySource{},
 				resourceManagerV3: mockV3Client,
 				resourceManagerV1: mockV1Client,
 			}
@@ -1421,3 +1449,194 @@ func TestGetProjectFromResource(t *testing.T) {
 		})
 	}
 }
+
+func TestGetProjectFromResource_bucketOffline(t *testing.T) {
+	p := provider.Provider()
+	planData := NewPlanData([]*tfjson.ResourceChange{
+		plannedChange("google_storage_bucket.with_project", "google_storage_bucket", tfjson.Actions{tfjson.ActionCreate}, map[string]interface{}{"name": "planned-bucket", "project": "planned-project"}),
+		plannedChange("google_storage_bucket.default_project", "google_storage_bucket", tfjson.Actions{tfjson.ActionCreate}, map[string]interface{}{"name": "default-project-bucket"}),
+	}, nil)
+	bucketProjects := map[string]string{
+		"mapped-bucket":  "mapped-project",
+		"planned-bucket": "ignored-project",
+	}
+	cases := []struct {
+		name   string
+		bucket string
+		config *resources.Config
+		want   string
+	}{
+		{
+			name:   "bucket in plan",
+			bucket: "planned-bucket",
+			config: &resources.Config{Project: "test-project"},
+			want:   "planned-project",
+		},
+		{
+			name:   "bucket in plan with provider project",
+			bucket: "default-project-bucket",
+			config: &resources.Config{Project: "test-project"},
+			want:   "test-project",
+		},
+		{
+			name:   "bucket in mapping",
+			bucket: "mapped-bucket",
+			config: &resources.Config{},
+			want:   "mapped-project",
+		},
+		{
+			name:   "bucket in mapping with prefix",
+			bucket: "b/mapped-bucket",
+			config: &resources.Config{},
+			want:   "mapped-project",
+		},
+		{
+			name:   "unresolved bucket falls back to provider project",
+			bucket: "other-bucket",
+			config: &resources.Config{Project: "test-project"},
+			want:   "test-project",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &manager{
+				errorLogger:    zap.NewExample(),
+				planData:       planData,
+				bucketProjects: bucketProjects,
+			}
+			d := tfdata.NewFakeResourceData(
+				"google_storage_bucket_iam_member",
+				p.ResourcesMap["google_storage_bucket_iam_member"].Schema,
+				map[string]interface{}{"bucket": c.bucket},
+			)
+			got, err := m.getProjectFromResource(d, c.config, &resources.Asset{Type: "storage.googleapis.com/Bucket"})
+			if err != nil {
+				t.Fatalf("getProjectFromResource() = %s, want = nil", err)
+			}
+			if got != c.want {
+				t.Errorf("getProjectFromResource() = %s, want = %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAncestors_unresolvedBucket(t *testing.T) {
+	p := provider.Provider()
+	m := &manager{
+		errorLogger:   zap.NewExample(),
+		ancestorCache: map[string][]string{},
+		sources:       map[string]ancestrySource{},
+	}
+	d := tfdata.NewFakeResourceData(
+		"google_storage_bucket_iam_member",
+		p.ResourcesMap["google_storage_bucket_iam_member"].Schema,
+		map[string]interface{}{"bucket": "unknown-bucket"},
+	)
+	_, _, err := m.Ancestors(&resources.Config{}, d, &resources.Asset{Type: "storage.googleapis.com/Bucket"})
+	var bucketErr *UnresolvedBucketError
+	if !errors.As(err, &bucketErr) {
+		t.Fatalf("Ancestors() = %v, want = *UnresolvedBucketError", err)
+	}
+	if bucketErr.Bucket != "unknown-bucket" {
+		t.Errorf("UnresolvedBucketError.Bucket = %s, want = unknown-bucket", bucketErr.Bucket)
+	}
+	if !strings.Contains(err.Error(), `"unknown-bucket"`) {
+		t.Errorf("Ancestors() error = %q, want it to name the bucket", err)
+	}
+}
+
+func TestAncestors_fakeGCP(t *testing.T) {
+	fake := fakegcp.NewServer()
+	defer fake.Close()
+	fake.AddFolder(fakegcp.Folder{ID: "1", Parent: "organizations/100"})
+	fake.AddFolder(fakegcp.Folder{ID: "2", Parent: "folders/1"})
+	fake.AddProject(fakegcp.Project{ID: "my-project", Number: "123", Parent: "folders/2"})
+	fake.AddProject(fakegcp.Project{ID: "flaky-project", Number: "456", Parent: "organizations/100"})
+	fake.AddProject(fakegcp.Project{ID: "denied-project", Number: "789", Parent: "organizations/100"})
+	fake.AddBucket(fakegcp.Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	fake.AddFault(fakegcp.Fault{
+		Name:   "//cloudresourcemanager.googleapis.com/projects/flaky-project",
+		Method: fakegcp.MethodGetAncestry,
+		Code:   http.StatusInternalServerError,
+		Count:  1,
+	})
+	fake.AddFault(fakegcp.Fault{
+		Name: "//cloudresourcemanager.googleapis.com/projects/denied-project",
+		Code: http.StatusForbidden,
+	})
+
+	cfg, err := resources.NewConfigWithOptions(context.Background(), "my-project", "", "", false, "", fake.Client(), resources.ConfigOptions{
+		BasePaths: fake.BasePaths(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(cfg, false, nil, zap.NewExample())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := provider.Provider()
+	cases := []struct {
+		name      string
+		resource  string
+		fields    map[string]interface{}
+		assetType string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "project",
+			resource:  "google_project_iam_member",
+			fields:    map[string]interface{}{"project": "my-project"},
+			assetType: "cloudresourcemanager.googleapis.com/Project",
+			want:      []string{"projects/my-project", "folders/2", "folders/1", "organizations/100"},
+		},
+		{
+			name:      "folder",
+			resource:  "google_folder_iam_member",
+			fields:    map[string]interface{}{"folder": "folders/2"},
+			assetType: "cloudresourcemanager.googleapis.com/Folder",
+			want:      []string{"folders/2", "folders/1", "organizations/100"},
+		},
+		{
+			name:      "bucket",
+			resource:  "google_storage_bucket_iam_member",
+			fields:    map[string]interface{}{"bucket": "my-bucket"},
+			assetType: "storage.googleapis.com/Bucket",
+			want:      []string{"projects/my-project", "folders/2", "folders/1", "organizations/100"},
+		},
+		{
+			name:      "retried error",
+			resource:  "google_project_iam_member",
+			fields:    map[string]interface{}{"project": "flaky-project"},
+			assetType: "cloudresourcemanager.googleapis.com/Project",
+			want:      []string{"projects/flaky-project", "organizations/100"},
+		},
+		{
+			name:      "permission denied",
+			resource:  "google_project_iam_member",
+			fields:    map[string]interface{}{"project": "denied-project"},
+			assetType: "cloudresourcemanager.googleapis.com/Project",
+			wantErr:   "user does not have the correct permissions for projects/denied-project",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := tfdata.NewFakeResourceData(c.resource, p.ResourcesMap[c.resource].Schema, c.fields)
+			got, _, err := m.Ancestors(cfg, d, &resources.Asset{Type: c.assetType})
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("Ancestors() = %v, want error containing %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Ancestors() = %s, want = nil", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Ancestors() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
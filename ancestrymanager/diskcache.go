@@ -0,0 +1,293 @@
+package ancestrymanager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDiskCacheTTL is how long ancestry paths stay valid in the disk
+	// cache if no TTL is configured.
+	DefaultDiskCacheTTL = 24 * time.Hour
+
+	diskCacheVersion = 1
+	// lockTimeout is how long to wait for another process holding the cache
+	// lock, and lockStaleAfter is the age after which a lock file is assumed
+	// to be left behind by a crashed process.
+	lockTimeout    = 10 * time.Second
+	lockStaleAfter = 30 * time.Second
+	lockRetryDelay = 50 * time.Millisecond
+)
+
+// DiskCache persists ancestry paths resolved through the resource manager API
+// between runs. Entries expire after the configured TTL. The cache file may be
+// shared by several processes: writes are serialized with a lock file and the
+// cache file is replaced atomically, so readers never see a partial file.
+type DiskCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]diskCacheEntry
+}
+
+type diskCacheFile struct {
+	Version int                       `json:"version"`
+	Entries map[string]diskCacheEntry `json:"entries"`
+}
+
+type diskCacheEntry struct {
+	Ancestors []string  `json:"ancestors"`
+	Updated   time.Time `json:"updated"`
+}
+
+// DefaultDiskCachePath returns the location of the ancestry cache in the
+// user cache directory.
+func DefaultDiskCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding user cache dir: %w", err)
+	}
+	return filepath.Join(dir, "terraform-validator", "ancestry.json"), nil
+}
+
+// NewDiskCache returns a DiskCache stored at path. Entries older than ttl are
+// ignored when loading and dropped when saving.
+func NewDiskCache(path string, ttl time.Duration) (*DiskCache, error) {
+	if path == "" {
+		return nil, errors.New("ancestry cache path must not be empty")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ancestry cache TTL must be positive, got %s", ttl)
+	}
+	return &DiskCache{
+		path:    path,
+		ttl:     ttl,
+		now:     time.Now,
+		pending: map[string]diskCacheEntry{},
+	}, nil
+}
+
+// Path returns the location of the cache file.
+func (c *DiskCache) Path() string {
+	return c.path
+}
+
+// Load returns the unexpired ancestry paths in the cache, keyed by
+// `<type>/<id>`. A missing cache file is not an error.
+func (c *DiskCache) Load() (map[string][]string, error) {
+	f, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	now := c.now()
+	entries := make(map[string][]string, len(f.Entries))
+	for key, entry := range f.Entries {
+		if c.expired(entry, now) {
+			continue
+		}
+		entries[key] = entry.Ancestors
+	}
+	return entries, nil
+}
+
+// Add records ancestors for key, to be written by the next Save.
+func (c *DiskCache) Add(key string, ancestors []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key] = diskCacheEntry{
+		Ancestors: append([]string(nil), ancestors...),
+		Updated:   c.now(),
+	}
+}
+
+// Save merges the entries added since the last Save into the cache file and
+// drops expired entries.
+func (c *DiskCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("creating ancestry cache dir: %w", err)
+	}
+	unlock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := c.read()
+	if err != nil {
+		return err
+	}
+	now := c.now()
+	for key, entry := range f.Entries {
+		if c.expired(entry, now) {
+			delete(f.Entries, key)
+		}
+	}
+	for key, entry := range c.pending {
+		f.Entries[key] = entry
+	}
+	if err := c.write(f); err != nil {
+		return err
+	}
+	c.pending = map[string]diskCacheEntry{}
+	return nil
+}
+
+// Clear removes the cache file.
+func (c *DiskCache) Clear() error {
+	unlock, err := c.lock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// The cache dir does not exist, so there is nothing to clear.
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing ancestry cache: %w", err)
+	}
+	return nil
+}
+
+func (c *DiskCache) expired(entry diskCacheEntry, now time.Time) bool {
+	return len(entry.Ancestors) == 0 || now.Sub(entry.Updated) > c.ttl
+}
+
+func (c *DiskCache) read() (*diskCacheFile, error) {
+	f := &diskCacheFile{Version: diskCacheVersion, Entries: map[string]diskCacheEntry{}}
+	content, err := ioutil.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ancestry cache: %w", err)
+	}
+	if err := json.Unmarshal(content, f); err != nil {
+		return nil, fmt.Errorf("parsing ancestry cache %s: %w", c.path, err)
+	}
+	if f.Version != diskCacheVersion {
+		// Written by an incompatible version; start over.
+		return &diskCacheFile{Version: diskCacheVersion, Entries: map[string]diskCacheEntry{}}, nil
+	}
+	if f.Entries == nil {
+		f.Entries = map[string]diskCacheEntry{}
+	}
+	return f, nil
+}
+
+// write replaces the cache file by renaming a temporary file over it.
+func (c *DiskCache) write(f *diskCacheFile) error {
+	content, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding ancestry cache: %w", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("writing ancestry cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing ancestry cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing ancestry cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("writing ancestry cache: %w", err)
+	}
+	return nil
+}
+
+// lock acquires the cache lock file, waiting for other processes that hold
+// it. The lock file records the owner's pid and a random nonce, so a lock is
+// only ever removed by its owner or, once stale, by a process that has checked
+// it still holds the same stale owner. The returned function releases the
+// lock.
+func (c *DiskCache) lock() (func(), error) {
+	lockPath := c.path + ".lock"
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, fmt.Errorf("locking ancestry cache: %w", err)
+	}
+	deadline := c.now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, werr := f.WriteString(owner)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("locking ancestry cache: %w", werr)
+			}
+			return func() { removeLockIfOwned(lockPath, owner) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("locking ancestry cache: %w", err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && c.now().Sub(info.ModTime()) > lockStaleAfter {
+			if stale, err := ioutil.ReadFile(lockPath); err == nil {
+				breakStaleLock(lockPath, string(stale), owner)
+			}
+			continue
+		}
+		if c.now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for ancestry cache lock %s", lockPath)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// newLockOwner returns the contents written to a lock file held by this
+// call: the pid and a random nonce, which tells apart several locks taken by
+// the same process.
+func newLockOwner() (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s\n", os.Getpid(), hex.EncodeToString(nonce)), nil
+}
+
+// removeLockIfOwned removes the lock file if it still belongs to owner. A
+// lock that was taken over after going stale is left to its new owner.
+func removeLockIfOwned(lockPath, owner string) {
+	if b, err := ioutil.ReadFile(lockPath); err == nil && string(b) == owner {
+		os.Remove(lockPath)
+	}
+}
+
+// breakStaleLock removes a lock file that was found stale with the contents
+// stale. The lock file is first renamed to a name only this caller uses, so
+// that of several processes breaking the same lock only one gets it. If the
+// renamed file turns out to be a fresh lock taken after the stale one was
+// broken elsewhere, it is put back.
+func breakStaleLock(lockPath, stale, owner string) {
+	broken := lockPath + "." + strings.Fields(owner)[1]
+	if err := os.Rename(lockPath, broken); err != nil {
+		return
+	}
+	if b, err := ioutil.ReadFile(broken); err == nil && string(b) != stale {
+		// Link fails rather than replace a lock taken in the meantime.
+		os.Link(broken, lockPath)
+	}
+	os.Remove(broken)
+}
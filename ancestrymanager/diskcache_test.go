@@ -0,0 +1,424 @@
+package ancestrymanager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+	crmv3 "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+func TestDiskCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "ancestry.json")
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return now }
+
+	got, err := c.Load()
+	if err != nil {
+		t.Fatalf("Load() on missing file = %s, want nil", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Load() on missing file = %v, want empty", got)
+	}
+
+	c.Add("projects/old", []string{"projects/old", "organizations/1"})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(50 * time.Minute)
+	c.Add("projects/new", []string{"projects/new", "folders/2", "organizations/1"})
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = c.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"projects/old": {"projects/old", "organizations/1"},
+		"projects/new": {"projects/new", "folders/2", "organizations/1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	// projects/old expires 60 minutes after it was added.
+	now = now.Add(20 * time.Minute)
+	got, err = c.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string][]string{
+		"projects/new": {"projects/new", "folders/2", "organizations/1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() after expiry returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cache file exists after Clear(): %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file exists after Clear(): %v", err)
+	}
+	// Clearing a missing cache is not an error.
+	if err := c.Clear(); err != nil {
+		t.Errorf("Clear() on missing file = %s, want nil", err)
+	}
+}
+
+func TestDiskCache_concurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Each cache stands for a separate process sharing the file.
+			c, err := NewDiskCache(path, time.Hour)
+			if err != nil {
+				errs <- err
+				return
+			}
+			key := fmt.Sprintf("projects/p%d", i)
+			c.Add(key, []string{key, "organizations/1"})
+			errs <- c.Save()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 20 {
+		t.Errorf("Load() returned %d entries, want 20", len(got))
+	}
+}
+
+func TestDiskCache_staleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	if err := ioutil.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add("projects/abc", []string{"projects/abc", "organizations/1"})
+	if err := c.Save(); err != nil {
+		t.Errorf("Save() with stale lock = %s, want nil", err)
+	}
+}
+
+func TestDiskCache_lockOwnership(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	now := time.Now()
+	c := &DiskCache{path: path, ttl: time.Hour, now: func() time.Time { return now }}
+	unlockStale, err := c.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the first lock is stale, a second caller takes it over.
+	now = now.Add(time.Hour)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := c.lock()
+	if err != nil {
+		t.Fatalf("lock() with stale lock = %s, want nil", err)
+	}
+	taken, err := ioutil.ReadFile(path + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Releasing the stale lock must not remove the lock that replaced it.
+	unlockStale()
+	got, err := ioutil.ReadFile(path + ".lock")
+	if err != nil {
+		t.Fatalf("lock file removed by previous owner: %s", err)
+	}
+	if string(got) != string(taken) {
+		t.Errorf("lock file = %q after previous owner released it, want %q", got, taken)
+	}
+
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file exists after owner released it: %v", err)
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("cache dir has %d leftover files, want 0", len(files))
+	}
+}
+
+func TestBreakStaleLock_keepsFreshLock(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "ancestry.json.lock")
+	// The lock was broken and retaken by another process after this caller
+	// read the stale contents.
+	if err := ioutil.WriteFile(lockPath, []byte("2 fresh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	breakStaleLock(lockPath, "1 stale\n", "3 breaker\n")
+	got, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		t.Fatalf("fresh lock removed: %s", err)
+	}
+	if string(got) != "2 fresh\n" {
+		t.Errorf("lock file = %q, want %q", got, "2 fresh\n")
+	}
+	if _, err := os.Stat(lockPath + ".breaker"); !os.IsNotExist(err) {
+		t.Errorf("renamed lock file left behind: %v", err)
+	}
+}
+
+func TestDiskCache_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	if err := ioutil.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Load(); err == nil {
+		t.Error("Load() on corrupt file = nil, want error")
+	}
+
+	// A corrupt cache is ignored by the manager.
+	m, err := NewWithOptions(nil, true, nil, zap.NewExample(), Options{DiskCache: c})
+	if err != nil {
+		t.Fatalf("New() with corrupt cache = %s, want nil", err)
+	}
+	if got := len(m.(*manager).ancestorCache); got != 0 {
+		t.Errorf("ancestorCache has %d entries, want 0", got)
+	}
+}
+
+func TestNewDiskCache_Fail(t *testing.T) {
+	if _, err := NewDiskCache("", time.Hour); err == nil {
+		t.Error("NewDiskCache with empty path = nil, want error")
+	}
+	if _, err := NewDiskCache("ancestry.json", 0); err == nil {
+		t.Error("NewDiskCache with zero TTL = nil, want error")
+	}
+}
+
+func TestGetAncestorsWithDiskCache(t *testing.T) {
+	v1Responses := map[string][]*crmv1.Ancestor{
+		"abc": {
+			{ResourceId: &crmv1.ResourceId{Id: "abc", Type: "project"}},
+			{ResourceId: &crmv1.ResourceId{Id: "456", Type: "folder"}},
+			{ResourceId: &crmv1.ResourceId{Id: "321", Type: "organization"}},
+		},
+		"def": {
+			{ResourceId: &crmv1.ResourceId{Id: "def", Type: "project"}},
+			{ResourceId: &crmv1.ResourceId{Id: "321", Type: "organization"}},
+		},
+	}
+	ts := newTestServer(t, v1Responses, map[string]*crmv3.Project{})
+	defer ts.Close()
+	mockV1Client, err := crmv1.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockV3Client, err := crmv3.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	cache, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manager{
+		errorLogger:       zap.NewExample(),
+		ancestorCache:     map[string][]string{},
+		sources:           map[string]ancestrySource{},
+		resourceManagerV3: mockV3Client,
+		resourceManagerV1: mockV1Client,
+		diskCache:         cache,
+	}
+	for _, key := range []string{"projects/abc", "projects/def"} {
+		if _, err := m.getAncestorsWithCache(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if ts.v1Count != 2 {
+		t.Errorf("v1 API called %d times, want 2", ts.v1Count)
+	}
+
+	// A later offline run reads the cache; entries passed to New win.
+	cache, err = NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{"def": "organizations/999/projects/def"}
+	am, err := NewWithOptions(nil, true, entries, zap.NewExample(), Options{DiskCache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := am.(*manager)
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "projects/abc", want: []string{"projects/abc", "folders/456", "organizations/321"}},
+		{key: "projects/def", want: []string{"projects/def", "organizations/999"}},
+	}
+	for _, test := range tests {
+		got, err := offline.getAncestorsWithCache(test.key)
+		if err != nil {
+			t.Fatalf("getAncestorsWithCache(%s) = %s, want nil", test.key, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("getAncestorsWithCache(%s) returned unexpected diff (-want +got):\n%s", test.key, diff)
+		}
+	}
+	if ts.v1Count != 2 {
+		t.Errorf("v1 API called %d times after offline run, want 2", ts.v1Count)
+	}
+}
+
+func TestGetAncestorsWithDiskCache_planData(t *testing.T) {
+	v1Responses := map[string][]*crmv1.Ancestor{
+		"moved": {
+			{ResourceId: &crmv1.ResourceId{Id: "moved", Type: "project"}},
+			{ResourceId: &crmv1.ResourceId{Id: "111", Type: "folder"}},
+			{ResourceId: &crmv1.ResourceId{Id: "321", Type: "organization"}},
+		},
+	}
+	v3Responses := map[string]*crmv3.Project{
+		"folders/456": {Name: "folders/456", Parent: "organizations/321"},
+	}
+	ts := newTestServer(t, v1Responses, v3Responses)
+	defer ts.Close()
+	mockV1Client, err := crmv1.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockV3Client, err := crmv3.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An earlier run caches the ancestry of the project before it moves.
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	cache, err := NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Add("projects/moved", []string{"projects/moved", "folders/111", "organizations/321"})
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	create := tfjson.Actions{tfjson.ActionCreate}
+	update := tfjson.Actions{tfjson.ActionUpdate}
+	changes := []*tfjson.ResourceChange{
+		plannedChange("google_project.app", "google_project", create, map[string]interface{}{"project_id": "app", "folder_id": "456"}),
+		plannedChange("google_project.orphan", "google_project", create, map[string]interface{}{"project_id": "orphan"}),
+		plannedChange("google_project.moved", "google_project", update, map[string]interface{}{"project_id": "moved", "folder_id": "456"}),
+		plannedChange("google_project.pinned", "google_project", create, map[string]interface{}{"project_id": "pinned", "folder_id": "456"}),
+	}
+	cache, err = NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manager{
+		errorLogger:       zap.NewExample(),
+		ancestorCache:     map[string][]string{},
+		sources:           map[string]ancestrySource{},
+		resourceManagerV3: mockV3Client,
+		resourceManagerV1: mockV1Client,
+		diskCache:         cache,
+		planData:          NewPlanData(changes, nil),
+	}
+	// Entries passed to New win over the plan.
+	if err := m.initAncestryCache(map[string]string{"pinned": "organizations/321/folders/777/projects/pinned"}); err != nil {
+		t.Fatal(err)
+	}
+	m.initFromDiskCache()
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "projects/app", want: []string{"projects/app", "folders/456", "organizations/321"}},
+		{key: "projects/orphan", want: []string{"projects/orphan", "organizations/unknown"}},
+		{key: "projects/moved", want: []string{"projects/moved", "folders/456", "organizations/321"}},
+		{key: "projects/pinned", want: []string{"projects/pinned", "folders/777", "organizations/321"}},
+	}
+	for _, test := range tests {
+		got, err := m.getAncestorsWithCache(test.key)
+		if err != nil {
+			t.Fatalf("getAncestorsWithCache(%s) = %s, want nil", test.key, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("getAncestorsWithCache(%s) returned unexpected diff (-want +got):\n%s", test.key, diff)
+		}
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ancestry involving the plan is not persisted.
+	cache, err = NewDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"projects/moved": {"projects/moved", "folders/111", "organizations/321"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,20 @@
+package ancestrymanager
+
+import "fmt"
+
+// UnresolvedBucketError is returned when the project of a storage bucket
+// cannot be found, so the ancestry of the bucket's resources is unknown.
+type UnresolvedBucketError struct {
+	// Bucket is the name of the bucket.
+	Bucket string
+	// Err is the reason the project could not be found.
+	Err error
+}
+
+func (e *UnresolvedBucketError) Error() string {
+	return fmt.Sprintf("unable to resolve the project of storage bucket %q: %s", e.Bucket, e.Err)
+}
+
+func (e *UnresolvedBucketError) Unwrap() error {
+	return e.Err
+}
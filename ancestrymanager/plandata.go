@@ -0,0 +1,275 @@
+package ancestrymanager
+
+import (
+	"regexp"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// maxPlannedDepth bounds walks through the planned hierarchy, which could
+// contain a cycle in an invalid plan.
+const maxPlannedDepth = 64
+
+var addressIndexRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+// PlanData holds the folders, projects and storage buckets created or updated
+// in a Terraform plan. The ancestry manager uses it to find the ancestry of
+// resources inside them before they exist, for example a project created in a
+// new folder, without calling the API.
+type PlanData struct {
+	// Projects of the storage buckets in the plan, keyed by bucket name. The
+	// project is empty if the bucket uses the provider's project.
+	buckets map[string]string
+	// Nodes keyed by the Terraform address of their resource change.
+	byAddress map[string]*plannedNode
+	// Nodes keyed by their address in configuration, which has no instance
+	// keys, as references in configuration use those.
+	byConfigAddress map[string][]*plannedNode
+	// Nodes keyed by resource names, e.g. "projects/my-project".
+	byName map[string]*plannedNode
+}
+
+type plannedNode struct {
+	// name is the resource name, e.g. "projects/my-project". It is
+	// "folders/unknown" for folders whose ID is known only after apply.
+	name string
+	// parent is the name of a parent that is known at plan time.
+	parent string
+	// parentRefs are the configuration references of the parent field, used
+	// to find a parent created in the same plan if parent is not known.
+	parentRefs []string
+	// parentNode is the parent created in the same plan, if any.
+	parentNode *plannedNode
+}
+
+// NewPlanData indexes the google_folder, google_project and
+// google_storage_bucket changes in a plan.
+// config is the configuration block of the plan and may be nil, in which case
+// parents created in the same plan cannot be found.
+func NewPlanData(changes []*tfjson.ResourceChange, config *tfjson.Config) *PlanData {
+	p := &PlanData{
+		buckets:         map[string]string{},
+		byAddress:       map[string]*plannedNode{},
+		byConfigAddress: map[string][]*plannedNode{},
+		byName:          map[string]*plannedNode{},
+	}
+	var expressions map[string]map[string]*tfjson.Expression
+	if config != nil {
+		expressions = configExpressions(config.RootModule, "", nil)
+	}
+
+	for _, rc := range changes {
+		if rc.Mode != tfjson.ManagedResourceMode || rc.Change == nil {
+			continue
+		}
+		after, ok := rc.Change.After.(map[string]interface{})
+		if !ok || len(after) == 0 {
+			continue
+		}
+		configAddress := addressIndexRegexp.ReplaceAllString(rc.Address, "")
+		var node *plannedNode
+		var names []string
+		switch rc.Type {
+		case "google_folder":
+			node, names = plannedFolder(after, expressions[configAddress])
+		case "google_project":
+			node, names = plannedProject(after, expressions[configAddress])
+		case "google_storage_bucket":
+			if name := stringValue(after, "name"); name != "" {
+				p.buckets[name] = stringValue(after, "project")
+			}
+			continue
+		default:
+			continue
+		}
+		if node.parent == "" && len(node.parentRefs) == 0 && len(rc.Change.Actions) == 1 && rc.Change.Actions[0] != tfjson.ActionCreate {
+			// The parent of an existing resource is better found with the API.
+			continue
+		}
+		p.byAddress[rc.Address] = node
+		p.byConfigAddress[configAddress] = append(p.byConfigAddress[configAddress], node)
+		for _, name := range names {
+			p.byName[name] = node
+		}
+	}
+
+	for _, node := range p.byAddress {
+		node.parentNode = p.resolve(node.parentRefs)
+	}
+	return p
+}
+
+func plannedFolder(after map[string]interface{}, expressions map[string]*tfjson.Expression) (*plannedNode, []string) {
+	node := &plannedNode{name: "folders/unknown"}
+	var names []string
+	if id := stringValue(after, "folder_id"); id != "" {
+		node.name = "folders/" + strings.TrimPrefix(id, "folders/")
+		names = append(names, node.name)
+	}
+	if parent := stringValue(after, "parent"); parent != "" {
+		node.parent = normalizeAncestry(parent)
+	} else {
+		node.parentRefs = expressionReferences(expressions, "parent")
+	}
+	return node, names
+}
+
+func plannedProject(after map[string]interface{}, expressions map[string]*tfjson.Expression) (*plannedNode, []string) {
+	node := &plannedNode{}
+	var names []string
+	if number := stringValue(after, "number"); number != "" {
+		names = append(names, "projects/"+number)
+	}
+	if id := stringValue(after, "project_id"); id != "" {
+		names = append(names, "projects/"+id)
+	}
+	if len(names) > 0 {
+		// Like the project asset, prefer the project number.
+		node.name = names[0]
+	}
+	if org := stringValue(after, "org_id"); org != "" {
+		node.parent = "organizations/" + strings.TrimPrefix(org, "organizations/")
+	} else if folder := stringValue(after, "folder_id"); folder != "" {
+		node.parent = "folders/" + strings.TrimPrefix(folder, "folders/")
+	} else {
+		node.parentRefs = append(expressionReferences(expressions, "folder_id"), expressionReferences(expressions, "org_id")...)
+	}
+	return node, names
+}
+
+// resolve returns the node created in the plan that refs refer to. A
+// reference may point to an attribute, e.g. "google_folder.team.name".
+func (p *PlanData) resolve(refs []string) *plannedNode {
+	for _, ref := range refs {
+		for address := ref; address != ""; address = trimLastSegment(address) {
+			if node, ok := p.byAddress[address]; ok {
+				return node
+			}
+			// Only use a reference without instance key if it is unambiguous.
+			if nodes := p.byConfigAddress[address]; len(nodes) == 1 {
+				return nodes[0]
+			}
+		}
+	}
+	return nil
+}
+
+func trimLastSegment(address string) string {
+	ix := strings.LastIndex(address, ".")
+	if ix == -1 {
+		return ""
+	}
+	return address[:ix]
+}
+
+// ancestors returns the ancestors of name found in the plan, starting with
+// name itself, and the name of the closest ancestor outside of the plan. The
+// latter is empty if it is unknown. ok is false if name is not part of the
+// plan.
+func (p *PlanData) ancestors(name string) (ancestors []string, next string, ok bool) {
+	if p == nil {
+		return nil, "", false
+	}
+	node, ok := p.byName[name]
+	if !ok || node.name == "" {
+		return nil, "", false
+	}
+	for i := 0; i < maxPlannedDepth; i++ {
+		ancestors = append(ancestors, node.name)
+		if node.parentNode == nil {
+			return ancestors, node.parent, true
+		}
+		node = node.parentNode
+	}
+	return ancestors, "", true
+}
+
+// projectName returns the name of a project in the plan, which is
+// "projects/<number>" if the project number is known. ok is false if the
+// project is not part of the plan.
+func (p *PlanData) projectName(name string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	node, ok := p.byName[name]
+	if !ok || !strings.HasPrefix(node.name, "projects/") {
+		return "", false
+	}
+	return node.name, true
+}
+
+// bucketProject returns the project of a storage bucket in the plan, which is
+// empty if the bucket uses the provider's project. ok is false if the bucket
+// is not part of the plan.
+func (p *PlanData) bucketProject(bucket string) (project string, ok bool) {
+	if p == nil {
+		return "", false
+	}
+	project, ok = p.buckets[bucket]
+	return project, ok
+}
+
+// configExpressions returns the expressions of every resource in module and
+// its child modules, keyed by resource address and attribute. References in
+// child modules are made absolute, and references to input variables are
+// replaced by the references passed to the module, as given by vars.
+func configExpressions(module *tfjson.ConfigModule, prefix string, vars map[string][]string) map[string]map[string]*tfjson.Expression {
+	result := map[string]map[string]*tfjson.Expression{}
+	if module == nil {
+		return result
+	}
+	for _, r := range module.Resources {
+		exprs := map[string]*tfjson.Expression{}
+		for attr, expr := range r.Expressions {
+			if expr == nil || expr.ExpressionData == nil {
+				continue
+			}
+			data := *expr.ExpressionData
+			data.References = absoluteReferences(expr.References, prefix, vars)
+			exprs[attr] = &tfjson.Expression{ExpressionData: &data}
+		}
+		result[prefix+r.Address] = exprs
+	}
+	for name, call := range module.ModuleCalls {
+		if call == nil {
+			continue
+		}
+		callVars := map[string][]string{}
+		for attr, expr := range call.Expressions {
+			if expr != nil && expr.ExpressionData != nil {
+				callVars[attr] = absoluteReferences(expr.References, prefix, vars)
+			}
+		}
+		for address, exprs := range configExpressions(call.Module, prefix+"module."+name+".", callVars) {
+			result[address] = exprs
+		}
+	}
+	return result
+}
+
+func absoluteReferences(refs []string, prefix string, vars map[string][]string) []string {
+	var result []string
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "var.") {
+			name := strings.SplitN(strings.TrimPrefix(ref, "var."), ".", 2)[0]
+			result = append(result, vars[name]...)
+			continue
+		}
+		result = append(result, prefix+ref)
+	}
+	return result
+}
+
+func expressionReferences(expressions map[string]*tfjson.Expression, attr string) []string {
+	expr, ok := expressions[attr]
+	if !ok || expr == nil || expr.ExpressionData == nil {
+		return nil
+	}
+	return expr.References
+}
+
+func stringValue(values map[string]interface{}, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
@@ -0,0 +1,157 @@
+package ancestrymanager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+)
+
+func plannedChange(address, resourceType string, actions tfjson.Actions, after map[string]interface{}) *tfjson.ResourceChange {
+	return &tfjson.ResourceChange{
+		Address: address,
+		Mode:    tfjson.ManagedResourceMode,
+		Type:    resourceType,
+		Change:  &tfjson.Change{Actions: actions, After: after},
+	}
+}
+
+func references(refs ...string) *tfjson.Expression {
+	return &tfjson.Expression{ExpressionData: &tfjson.ExpressionData{References: refs}}
+}
+
+func TestPlanDataAncestors(t *testing.T) {
+	create := tfjson.Actions{tfjson.ActionCreate}
+	update := tfjson.Actions{tfjson.ActionUpdate}
+	changes := []*tfjson.ResourceChange{
+		plannedChange("google_folder.team", "google_folder", create, map[string]interface{}{"parent": "organizations/123"}),
+		plannedChange("module.apps.google_folder.apps", "google_folder", create, map[string]interface{}{"display_name": "Apps"}),
+		plannedChange("module.apps.google_project.app[0]", "google_project", create, map[string]interface{}{"project_id": "app-0"}),
+		plannedChange("module.apps.google_project.app[1]", "google_project", create, map[string]interface{}{"project_id": "app-1"}),
+		plannedChange("google_project.in_existing_folder", "google_project", create, map[string]interface{}{"project_id": "in-existing", "folder_id": "456"}),
+		plannedChange("google_project.in_org", "google_project", create, map[string]interface{}{"project_id": "in-org", "number": "789", "org_id": "123"}),
+		plannedChange("google_project.unknown_parent", "google_project", create, map[string]interface{}{"project_id": "unknown-parent"}),
+		plannedChange("google_project.updated", "google_project", update, map[string]interface{}{"project_id": "updated"}),
+		plannedChange("google_folder.moved", "google_folder", update, map[string]interface{}{"folder_id": "555", "parent": "folders/team"}),
+		plannedChange("google_project_iam_member.member", "google_project_iam_member", create, map[string]interface{}{"project": "app-0"}),
+	}
+	config := &tfjson.Config{
+		RootModule: &tfjson.ConfigModule{
+			Resources: []*tfjson.ConfigResource{
+				{Address: "google_project.unknown_parent", Expressions: map[string]*tfjson.Expression{
+					"folder_id": references("data.google_folder.existing.name", "data.google_folder.existing"),
+				}},
+				{Address: "google_project.updated", Expressions: map[string]*tfjson.Expression{}},
+			},
+			ModuleCalls: map[string]*tfjson.ModuleCall{
+				"apps": {
+					Expressions: map[string]*tfjson.Expression{
+						"parent": references("google_folder.team.name", "google_folder.team"),
+					},
+					Module: &tfjson.ConfigModule{
+						Resources: []*tfjson.ConfigResource{
+							{Address: "google_folder.apps", Expressions: map[string]*tfjson.Expression{
+								"parent": references("var.parent"),
+							}},
+							{Address: "google_project.app", Expressions: map[string]*tfjson.Expression{
+								"folder_id": references("google_folder.apps.name", "google_folder.apps"),
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	p := NewPlanData(changes, config)
+
+	tests := []struct {
+		name     string
+		want     []string
+		wantNext string
+		wantOK   bool
+	}{
+		{name: "projects/app-0", want: []string{"projects/app-0", "folders/unknown", "folders/unknown"}, wantNext: "organizations/123", wantOK: true},
+		{name: "projects/app-1", want: []string{"projects/app-1", "folders/unknown", "folders/unknown"}, wantNext: "organizations/123", wantOK: true},
+		{name: "projects/in-existing", want: []string{"projects/in-existing"}, wantNext: "folders/456", wantOK: true},
+		{name: "projects/in-org", want: []string{"projects/789"}, wantNext: "organizations/123", wantOK: true},
+		{name: "projects/789", want: []string{"projects/789"}, wantNext: "organizations/123", wantOK: true},
+		{name: "projects/unknown-parent", want: []string{"projects/unknown-parent"}, wantNext: "", wantOK: true},
+		{name: "folders/555", want: []string{"folders/555"}, wantNext: "folders/team", wantOK: true},
+		{name: "projects/updated", wantOK: false},
+		{name: "projects/not-in-plan", wantOK: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, next, ok := p.ancestors(test.name)
+			if ok != test.wantOK {
+				t.Fatalf("ancestors(%s) ok = %t, want = %t", test.name, ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ancestors(%s) returned unexpected diff (-want +got):\n%s", test.name, diff)
+			}
+			if next != test.wantNext {
+				t.Errorf("ancestors(%s) next = %s, want = %s", test.name, next, test.wantNext)
+			}
+		})
+	}
+}
+
+func TestPlanDataAncestors_cycle(t *testing.T) {
+	create := tfjson.Actions{tfjson.ActionCreate}
+	changes := []*tfjson.ResourceChange{
+		plannedChange("google_folder.a", "google_folder", create, map[string]interface{}{"folder_id": "a"}),
+		plannedChange("google_folder.b", "google_folder", create, map[string]interface{}{"folder_id": "b"}),
+	}
+	config := &tfjson.Config{
+		RootModule: &tfjson.ConfigModule{
+			Resources: []*tfjson.ConfigResource{
+				{Address: "google_folder.a", Expressions: map[string]*tfjson.Expression{"parent": references("google_folder.b")}},
+				{Address: "google_folder.b", Expressions: map[string]*tfjson.Expression{"parent": references("google_folder.a")}},
+			},
+		},
+	}
+	got, next, ok := NewPlanData(changes, config).ancestors("folders/a")
+	if !ok || next != "" || len(got) != maxPlannedDepth {
+		t.Errorf("ancestors(folders/a) = %d ancestors, %q, %t, want = %d ancestors, \"\", true", len(got), next, ok, maxPlannedDepth)
+	}
+}
+
+func TestGetAncestorsWithPlanData(t *testing.T) {
+	create := tfjson.Actions{tfjson.ActionCreate}
+	changes := []*tfjson.ResourceChange{
+		plannedChange("google_folder.team", "google_folder", create, map[string]interface{}{"parent": "folders/456"}),
+		plannedChange("google_project.app", "google_project", create, map[string]interface{}{"project_id": "app"}),
+		plannedChange("google_project.orphan", "google_project", create, map[string]interface{}{"project_id": "orphan"}),
+	}
+	config := &tfjson.Config{
+		RootModule: &tfjson.ConfigModule{
+			Resources: []*tfjson.ConfigResource{
+				{Address: "google_project.app", Expressions: map[string]*tfjson.Expression{"folder_id": references("google_folder.team.name")}},
+			},
+		},
+	}
+	entries := map[string]string{"folders/456": "organizations/123"}
+	am, err := NewWithOptions(nil, true, entries, zap.NewExample(), Options{PlanData: NewPlanData(changes, config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := am.(*manager)
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "projects/app", want: []string{"projects/app", "folders/unknown", "folders/456", "organizations/123"}},
+		{key: "projects/orphan", want: []string{"projects/orphan", "organizations/unknown"}},
+	}
+	for _, test := range tests {
+		got, err := m.getAncestorsWithCache(test.key)
+		if err != nil {
+			t.Fatalf("getAncestorsWithCache(%s) = %s, want = nil", test.key, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("getAncestorsWithCache(%s) returned unexpected diff (-want +got):\n%s", test.key, diff)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ancestrymanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProjectAliasResolver is implemented by ancestry managers that can find the
+// project number of a project ID, so that the assets of a project addressed
+// by its ID and by its number can be merged.
+type ProjectAliasResolver interface {
+	// CanonicalProject returns the canonical name of a project given as
+	// "projects/<id>" or "projects/<number>", which is "projects/<number>" if
+	// the number is known. Otherwise it returns project unchanged. It may be
+	// called concurrently.
+	CanonicalProject(project string) string
+}
+
+// CanonicalProject returns the name of a project by number. It looks for the
+// project in the project number mapping, then in the plan, and then uses the
+// resource manager API if online. Projects whose number cannot be found keep
+// their name.
+func (m *manager) CanonicalProject(project string) string {
+	id := strings.TrimPrefix(project, "projects/")
+	if id == project || id == "" || isProjectNumber(id) {
+		return project
+	}
+	if number, ok := m.projectNumbers[id]; ok {
+		return "projects/" + strings.TrimPrefix(number, "projects/")
+	}
+	if name, ok := m.planData.projectName(project); ok {
+		if isProjectNumber(strings.TrimPrefix(name, "projects/")) {
+			return name
+		}
+		// The project is created in the plan and its number is not known yet.
+		return project
+	}
+	if m.resourceManagerV3 == nil {
+		return project
+	}
+	val, _, err := m.projectLookups.do(project, func() (interface{}, error) {
+		p, err := m.resourceManagerV3.Projects.Get(project).Do()
+		if err != nil {
+			return nil, err
+		}
+		return p.Name, nil
+	})
+	if err != nil {
+		m.errorLogger.Debug(fmt.Sprintf("Unable to find the project number of %s: %s", project, err))
+		return project
+	}
+	if name, _ := val.(string); strings.HasPrefix(name, "projects/") {
+		return name
+	}
+	return project
+}
+
+func isProjectNumber(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package ancestrymanager
+
+import (
+	"context"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+	crmv3 "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+func TestCanonicalProject(t *testing.T) {
+	create := tfjson.Actions{tfjson.ActionCreate}
+	planData := NewPlanData([]*tfjson.ResourceChange{
+		plannedChange("google_project.known", "google_project", create, map[string]interface{}{"project_id": "planned-project", "number": "222"}),
+		plannedChange("google_project.new", "google_project", create, map[string]interface{}{"project_id": "new-project"}),
+	}, nil)
+	ts := newTestServer(t, nil, map[string]*crmv3.Project{
+		"projects/api-project": {Name: "projects/333", ProjectId: "api-project"},
+	})
+	defer ts.Close()
+	v3Client, err := crmv3.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		project string
+		want    string
+	}{
+		{name: "number", project: "projects/444", want: "projects/444"},
+		{name: "mapping", project: "projects/mapped-project", want: "projects/111"},
+		{name: "mapping with prefix", project: "projects/prefixed-project", want: "projects/555"},
+		{name: "plan", project: "projects/planned-project", want: "projects/222"},
+		{name: "plan without number", project: "projects/new-project", want: "projects/new-project"},
+		{name: "api", project: "projects/api-project", want: "projects/333"},
+		{name: "api error", project: "projects/denied-project", want: "projects/denied-project"},
+		{name: "not a project", project: "folders/123", want: "folders/123"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &manager{
+				errorLogger: zap.NewExample(),
+				planData:    planData,
+				projectNumbers: map[string]string{
+					"mapped-project":   "111",
+					"prefixed-project": "projects/555",
+				},
+				resourceManagerV3: v3Client,
+			}
+			if got := m.CanonicalProject(c.project); got != c.want {
+				t.Errorf("CanonicalProject(%s) = %s, want = %s", c.project, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalProject_offline(t *testing.T) {
+	m := &manager{errorLogger: zap.NewExample()}
+	if got := m.CanonicalProject("projects/my-project"); got != "projects/my-project" {
+		t.Errorf("CanonicalProject() = %s, want = projects/my-project", got)
+	}
+}
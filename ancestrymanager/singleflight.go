@@ -0,0 +1,43 @@
+package ancestrymanager
+
+import (
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// flightGroup runs a lookup once per key. Concurrent callers asking for the
+// same key wait for the first lookup to finish, and later callers get its
+// cached result. Failed lookups are not cached, so that a later caller tries
+// again. The zero value is ready to use.
+type flightGroup struct {
+	group singleflight.Group
+
+	mu      sync.Mutex
+	results map[string]interface{}
+}
+
+// do returns the result of fn for key, calling fn only if no lookup of key is
+// in flight or has succeeded. shared reports whether the result came from
+// another call.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (val interface{}, shared bool, err error) {
+	g.mu.Lock()
+	val, ok := g.results[key]
+	g.mu.Unlock()
+	if ok {
+		return val, true, nil
+	}
+	val, err, shared = g.group.Do(key, func() (interface{}, error) {
+		val, err := fn()
+		if err == nil {
+			g.mu.Lock()
+			if g.results == nil {
+				g.results = make(map[string]interface{})
+			}
+			g.results[key] = val
+			g.mu.Unlock()
+		}
+		return val, err
+	})
+	return val, shared, err
+}
@@ -0,0 +1,58 @@
+package ancestrymanager
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	calls := 0
+	lookup := func(err error) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			calls++
+			if err != nil {
+				return nil, err
+			}
+			return "value", nil
+		}
+	}
+
+	// Failed lookups are not cached.
+	if _, _, err := g.do("key", lookup(errors.New("transient"))); err == nil {
+		t.Fatal("do() = nil error, want error")
+	}
+	val, shared, err := g.do("key", lookup(nil))
+	if err != nil || val != "value" || shared {
+		t.Fatalf("do() = %v, %v, %v, want value, false, nil", val, shared, err)
+	}
+	// Successful lookups are.
+	val, shared, err = g.do("key", lookup(nil))
+	if err != nil || val != "value" || !shared {
+		t.Fatalf("do() = %v, %v, %v, want value, true, nil", val, shared, err)
+	}
+	if calls != 2 {
+		t.Errorf("lookups = %d, want 2", calls)
+	}
+}
+
+func TestFlightGroup_panic(t *testing.T) {
+	var g flightGroup
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("do() did not panic")
+			}
+		}()
+		g.do("key", func() (interface{}, error) {
+			panic("lookup failed")
+		})
+	}()
+	// The key is released, so a later lookup does not wait forever.
+	val, _, err := g.do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+	if err != nil || val != "value" {
+		t.Errorf("do() = %v, %v, want value, nil", val, err)
+	}
+}
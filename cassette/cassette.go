@@ -0,0 +1,215 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassette records the Google API calls of online conversions to a
+// directory of cassettes, and replays them without credentials, so that a
+// conversion can be reproduced exactly. Both the Recorder and the Replayer
+// are http.RoundTrippers, for the client of resources.NewConfig or
+// tfgcv.ReadOptions.HTTPClient.
+//
+// Each interaction is a JSON file in the directory, named after its order
+// and its request. Cassettes are sanitized: request headers are not
+// recorded, and credentials in URLs and JSON bodies are redacted.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the credentials in cassettes.
+const redacted = "REDACTED"
+
+// sensitiveParams are the URL query parameters holding credentials.
+var sensitiveParams = map[string]bool{
+	"access_token": true,
+	"key":          true,
+}
+
+// sensitiveFields are the fields of JSON bodies holding credentials.
+var sensitiveFields = map[string]bool{
+	"access_token":  true,
+	"accessToken":   true,
+	"client_secret": true,
+	"id_token":      true,
+	"private_key":   true,
+	"refresh_token": true,
+}
+
+// recordedHeaders are the response headers kept in cassettes.
+var recordedHeaders = []string{"Content-Type"}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a sanitized request.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a sanitized response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// key identifies the requests that an interaction is replayed for.
+func (r Request) key() string {
+	return r.Method + " " + r.URL + "\n" + r.Body
+}
+
+// newRequest returns the sanitized request of req, whose body is read and
+// replaced. req must not be shared with the caller of RoundTrip.
+func newRequest(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return Request{
+		Method: req.Method,
+		URL:    sanitizeURL(req.URL),
+		Body:   sanitizeBody(body),
+	}, nil
+}
+
+// newResponse returns the sanitized response of resp, whose body is read and
+// replaced.
+func newResponse(resp *http.Response) (Response, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return Response{}, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	header := http.Header{}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			header[h] = v
+		}
+	}
+	return Response{StatusCode: resp.StatusCode, Header: header, Body: sanitizeBody(body)}, nil
+}
+
+// sanitizeURL drops the user info of u, and redacts credentials in its
+// query. Query parameters are sorted.
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+	query := sanitized.Query()
+	for param := range query {
+		if sensitiveParams[param] {
+			query.Set(param, redacted)
+		}
+	}
+	sanitized.RawQuery = query.Encode()
+	return sanitized.String()
+}
+
+// sanitizeBody redacts credentials in JSON bodies. Other bodies are kept as
+// is.
+func sanitizeBody(body []byte) string {
+	var v interface{}
+	if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &v) != nil {
+		return string(body)
+	}
+	b, err := json.Marshal(redact(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for field, value := range v {
+			if sensitiveFields[field] {
+				v[field] = redacted
+			} else {
+				v[field] = redact(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redact(value)
+		}
+	}
+	return v
+}
+
+var fileNameRE = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+
+// fileName returns the name of the n-th interaction of a cassette, like
+// 00001-GET-storage.googleapis.com-storage-v1-b-my-bucket.json.
+func fileName(n int, req Request) string {
+	name := req.URL
+	if u, err := url.Parse(req.URL); err == nil {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(fileNameRE.ReplaceAllString(req.Method+"-"+name, "-"), "-")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return fmt.Sprintf("%05d-%s.json", n, name)
+}
+
+// ReadDir returns the interactions of the cassette in dir, in the order they
+// were recorded.
+func ReadDir(dir string) ([]Interaction, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	interactions := make([]Interaction, 0, len(paths))
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		var i Interaction
+		if err := json.Unmarshal(b, &i); err != nil {
+			return nil, fmt.Errorf("parsing cassette interaction %s: %w", path, err)
+		}
+		if i.Request.Method == "" || i.Request.URL == "" || i.Response.StatusCode == 0 {
+			return nil, fmt.Errorf("cassette interaction %s has no request or response", path)
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, nil
+}
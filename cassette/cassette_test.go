@@ -0,0 +1,197 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/fakegcp"
+)
+
+func newTestConfig(t *testing.T, rt http.RoundTripper, basePaths map[string]string) *resources.Config {
+	t.Helper()
+	cfg, err := resources.NewConfigWithOptions(context.Background(), "my-project", "", "", false, "", &http.Client{Transport: rt}, resources.ConfigOptions{
+		BasePaths: basePaths,
+	})
+	require.NoError(t, err)
+	return cfg
+}
+
+// calls makes the API calls of an online conversion, and returns their
+// results.
+func calls(cfg *resources.Config) []string {
+	var results []string
+	add := func(v interface{}, err error) {
+		if err != nil {
+			results = append(results, "error: "+err.Error())
+			return
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		results = append(results, string(b))
+	}
+	add(cfg.NewResourceManagerClient("").Projects.GetAncestry("my-project", &crmv1.GetAncestryRequest{}).Do())
+	add(cfg.NewStorageClient("").Buckets.Get("my-bucket").Do())
+	add(cfg.NewStorageClient("").Buckets.Get("my-bucket").Do())
+	add(cfg.NewStorageClient("").Buckets.Get("unknown").Do())
+	add(resources.SendRequest(cfg, "POST", "", cfg.PubsubBasePath+"projects/my-project/topics/topic:getIamPolicy", "", map[string]interface{}{
+		"options": map[string]interface{}{"requestedPolicyVersion": 3},
+	}))
+	return results
+}
+
+func TestRecordReplay(t *testing.T) {
+	fake := fakegcp.NewServer()
+	fake.AddProject(fakegcp.Project{ID: "my-project", Number: "123", Parent: "organizations/100"})
+	fake.AddBucket(fakegcp.Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	fake.SetIamPolicy("//pubsub.googleapis.com/projects/my-project/topics/topic", &crmv1.Policy{
+		Bindings: []*crmv1.Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com"}}},
+	})
+	// The bucket is only found the second time.
+	fake.AddFault(fakegcp.Fault{Name: "//storage.googleapis.com/my-bucket", Code: http.StatusForbidden, Count: 1})
+	basePaths := fake.BasePaths()
+
+	dir := filepath.Join(t.TempDir(), "cassette")
+	recorder, err := NewRecorder(dir, fake.Client().Transport)
+	require.NoError(t, err)
+	recorded := calls(newTestConfig(t, recorder, basePaths))
+	fake.Close()
+	assert.Contains(t, recorded[1], "403")
+	assert.Contains(t, recorded[2], `"projectNumber":"123"`)
+	assert.Contains(t, recorded[3], "404")
+	assert.Contains(t, recorded[4], "user:a@example.com")
+
+	interactions, err := ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, interactions, 5)
+
+	replayer, err := NewReplayer(dir)
+	require.NoError(t, err)
+	cfg := newTestConfig(t, replayer, basePaths)
+	assert.Equal(t, recorded, calls(cfg))
+
+	// Requests fail once their recorded responses are used up.
+	_, err = cfg.NewStorageClient("").Buckets.Get("my-bucket").Do()
+	assert.True(t, errors.Is(err, ErrExhausted), "Buckets.Get() = %v, want = ErrExhausted", err)
+
+	_, err = cfg.NewStorageClient("").Buckets.Get("other").Do()
+	assert.True(t, errors.Is(err, ErrUnmatched), "Buckets.Get() = %v, want = ErrUnmatched", err)
+	_, err = resources.SendRequest(cfg, "POST", "", cfg.PubsubBasePath+"projects/my-project/topics/topic:getIamPolicy", "", nil)
+	assert.True(t, errors.Is(err, ErrUnmatched), "SendRequest() with another body = %v, want = ErrUnmatched", err)
+}
+
+func TestRecordReplay_retries(t *testing.T) {
+	fake := fakegcp.NewServer()
+	fake.AddBucket(fakegcp.Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	fake.AddFault(fakegcp.Fault{Name: "//storage.googleapis.com/my-bucket", Code: http.StatusServiceUnavailable, Count: 1})
+	basePaths := fake.BasePaths()
+
+	dir := filepath.Join(t.TempDir(), "cassette")
+	recorder, err := NewRecorder(dir, fake.Client().Transport)
+	require.NoError(t, err)
+	_, err = newTestConfig(t, recorder, basePaths).NewStorageClient("").Buckets.Get("my-bucket").Do()
+	require.NoError(t, err)
+	fake.Close()
+
+	// The recorder is below the retries of the config, so the failed attempt
+	// and its retry are both recorded, once.
+	interactions, err := ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, interactions, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, interactions[0].Response.StatusCode)
+	assert.Equal(t, http.StatusOK, interactions[1].Response.StatusCode)
+
+	// Replaying goes through the same retry.
+	replayer, err := NewReplayer(dir)
+	require.NoError(t, err)
+	bucket, err := newTestConfig(t, replayer, basePaths).NewStorageClient("").Buckets.Get("my-bucket").Do()
+	require.NoError(t, err)
+	assert.EqualValues(t, 123, bucket.ProjectNumber)
+}
+
+func TestRecord_sanitized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Write([]byte(`{"name": "projects/123", "nested": [{"access_token": "secret-response-token"}]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, nil)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/projects/p?access_token=secret-query&alt=json", strings.NewReader(`{"refresh_token": "secret-body"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret-header")
+	resp, err := (&http.Client{Transport: recorder}).Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "secret-response-token", "responses are given unchanged")
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	assert.Equal(t, "00001-POST-127.0.0.1-"+strings.Split(server.Listener.Addr().String(), ":")[1]+"-v1-projects-p.json", filepath.Base(paths[0]))
+	content, err := ioutil.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+	assert.Contains(t, string(content), "projects/123")
+
+	// Replayed requests are sanitized the same way.
+	replayer, err := NewReplayer(dir)
+	require.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/v1/projects/p?alt=json&access_token=other", strings.NewReader(`{"refresh_token":"other"}`))
+	require.NoError(t, err)
+	resp, err = (&http.Client{Transport: replayer}).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Set-Cookie"))
+}
+
+func TestNewRecorder_existingCassette(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00001-GET.json"), []byte(`{}`), 0644))
+	_, err := NewRecorder(dir, nil)
+	assert.Error(t, err)
+}
+
+func TestNewReplayer_errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewReplayer(filepath.Join(dir, "missing"))
+	assert.Error(t, err, "missing directory")
+	_, err = NewReplayer(dir)
+	assert.Error(t, err, "empty directory")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00001-GET.json"), []byte(`{"request": {}}`), 0644))
+	_, err = NewReplayer(dir)
+	assert.Error(t, err, "invalid interaction")
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Recorder is an http.RoundTripper that records the responses of another
+// RoundTripper to a cassette. Requests that fail without a response are not
+// recorded. It is safe for concurrent use.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewRecorder returns a Recorder of the requests sent through next, which
+// defaults to http.DefaultTransport. The cassette is written to dir, which is
+// created if needed, and must not already hold a cassette.
+//
+// next should be the raw transport: the Recorder is meant to be the transport
+// of the client given to resources.NewConfig, which adds request logging and
+// retries on top, so that each attempt is recorded once and replayed through
+// the same retries.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cassette: %w", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("%s already holds a cassette; record to an empty directory", dir)
+	}
+	return &Recorder{dir: dir, next: next}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	recordedReq, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	recordedResp, err := newResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.write(Interaction{Request: recordedReq, Response: recordedResp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) write(i Interaction) error {
+	b, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cassette interaction: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n++
+	path := filepath.Join(r.dir, fileName(r.n, i.Request))
+	if err := ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnmatched is returned by Replayer.RoundTrip for requests that are not
+	// in the cassette.
+	ErrUnmatched = errors.New("request is not in the cassette")
+	// ErrExhausted is returned by Replayer.RoundTrip for requests made more
+	// times than they were recorded.
+	ErrExhausted = errors.New("request was already replayed as many times as it was recorded")
+)
+
+// Replayer is an http.RoundTripper that answers requests with the responses
+// of a cassette, without network requests. It is safe for concurrent use.
+type Replayer struct {
+	mu sync.Mutex
+	// responses are the recorded responses of each request key, in order.
+	// Replayed responses are removed.
+	responses map[string][]Response
+}
+
+// NewReplayer returns a Replayer of the cassette in dir.
+//
+// Requests are matched on their method, URL and body. A request made several
+// times gets the recorded responses in order, and fails with ErrExhausted once
+// they are used up. Requests that do not match fail with ErrUnmatched.
+func NewReplayer(dir string) (*Replayer, error) {
+	interactions, err := ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("%s holds no cassette", dir)
+	}
+	r := &Replayer{responses: map[string][]Response{}}
+	for _, i := range interactions {
+		key := i.Request.key()
+		r.responses[key] = append(r.responses[key], i.Response)
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordedReq, err := newRequest(req.Clone(req.Context()))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next(recordedReq.key())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s", err, recordedReq.Method, recordedReq.URL)
+	}
+	header := resp.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next removes and returns the next response of a request key.
+func (r *Replayer) next(key string) (Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	responses, ok := r.responses[key]
+	if !ok {
+		return Response{}, ErrUnmatched
+	}
+	if len(responses) == 0 {
+		return Response{}, ErrExhausted
+	}
+	r.responses[key] = responses[1:]
+	return responses[0], nil
+}
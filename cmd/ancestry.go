@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+)
+
+// ancestryEntries combines the entries of ancestryFile with the ancestry set
+// for project via --ancestry, which takes precedence.
+func ancestryEntries(project, ancestry, ancestryFile string) (map[string]string, error) {
+	entries := map[string]string{}
+	if ancestryFile != "" {
+		var err error
+		entries, err = ancestrymanager.ReadAncestryFile(ancestryFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if project != "" {
+		if ancestry != "" {
+			// Drop file entries for the same project under another key form.
+			for _, prefix := range []string{"projects/", "project/"} {
+				delete(entries, prefix+strings.TrimPrefix(project, prefix))
+			}
+		}
+		if _, ok := entries[project]; !ok || ancestry != "" {
+			entries[project] = ancestry
+		}
+	}
+	return entries, nil
+}
+
+// readOptions builds the optional inputs of converting a plan from the flags
+// shared by the convert, serve and validate commands.
+func readOptions(ancestryCache ancestryCacheOptions, bucketProjectFile, projectNumberFile string, converters converterOptions, endpoints endpointOptions, cassettes cassetteOptions) (tfgcv.ReadOptions, error) {
+	var opts tfgcv.ReadOptions
+	diskCache, err := ancestryCache.diskCache()
+	if err != nil {
+		return opts, err
+	}
+	opts.AncestryCache = diskCache
+	if bucketProjectFile != "" {
+		opts.BucketProjects, err = ancestrymanager.ReadBucketProjectFile(bucketProjectFile)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if projectNumberFile != "" {
+		opts.ProjectNumbers, err = ancestrymanager.ReadProjectNumberFile(projectNumberFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.MergeProjectAliases = true
+	}
+	if err := converters.apply(&opts); err != nil {
+		return opts, err
+	}
+	if err := endpoints.apply(&opts); err != nil {
+		return opts, err
+	}
+	if err := cassettes.apply(&opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
+	"github.com/spf13/cobra"
+)
+
+// ancestryCacheOptions configures the on-disk ancestry cache shared by the
+// convert and validate commands. The zero value disables the cache.
+type ancestryCacheOptions struct {
+	enabled bool
+	path    string
+	ttl     time.Duration
+}
+
+func (o *ancestryCacheOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.enabled, "ancestry-cache", false, "Cache ancestry fetched from the resource manager API on disk between runs")
+	cmd.Flags().StringVar(&o.path, "ancestry-cache-path", "", "Location of the ancestry cache file (implies --ancestry-cache). Defaults to a file in the user cache dir")
+	cmd.Flags().DurationVar(&o.ttl, "ancestry-cache-ttl", ancestrymanager.DefaultDiskCacheTTL, "How long cached ancestry stays valid")
+}
+
+// diskCache returns the configured cache, or nil if the cache is disabled.
+func (o *ancestryCacheOptions) diskCache() (*ancestrymanager.DiskCache, error) {
+	if !o.enabled && o.path == "" {
+		return nil, nil
+	}
+	return newDiskCache(o.path, o.ttl)
+}
+
+func newDiskCache(path string, ttl time.Duration) (*ancestrymanager.DiskCache, error) {
+	if path == "" {
+		var err error
+		path, err = ancestrymanager.DefaultDiskCachePath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ttl == 0 {
+		ttl = ancestrymanager.DefaultDiskCacheTTL
+	}
+	return ancestrymanager.NewDiskCache(path, ttl)
+}
+
+type clearAncestryCacheOptions struct {
+	path string
+}
+
+func newClearAncestryCacheCmd() *cobra.Command {
+	o := clearAncestryCacheOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "clear-ancestry-cache",
+		Short: "Remove the on-disk ancestry cache.",
+		RunE: func(c *cobra.Command, args []string) error {
+			return o.run()
+		},
+	}
+
+	cmd.Flags().StringVar(&o.path, "ancestry-cache-path", "", "Location of the ancestry cache file. Defaults to a file in the user cache dir")
+
+	return cmd
+}
+
+func (o *clearAncestryCacheOptions) run() error {
+	cache, err := newDiskCache(o.path, 0)
+	if err != nil {
+		return err
+	}
+	if err := cache.Clear(); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared ancestry cache %s\n", cache.Path())
+	return nil
+}
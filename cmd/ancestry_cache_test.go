@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAncestryCacheOptions(t *testing.T) {
+	a := assert.New(t)
+
+	o := ancestryCacheOptions{ttl: time.Hour}
+	cache, err := o.diskCache()
+	a.Nil(err)
+	a.Nil(cache)
+
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	o = ancestryCacheOptions{path: path, ttl: time.Hour}
+	cache, err = o.diskCache()
+	a.Nil(err)
+	a.Equal(path, cache.Path())
+
+	o = ancestryCacheOptions{path: path, ttl: -time.Hour}
+	_, err = o.diskCache()
+	a.NotNil(err)
+}
+
+func TestClearAncestryCacheRun(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "ancestry.json")
+	o := clearAncestryCacheOptions{path: path}
+
+	cache, err := newDiskCache(path, time.Hour)
+	a.Nil(err)
+	cache.Add("projects/abc", []string{"projects/abc", "organizations/1"})
+	a.Nil(cache.Save())
+
+	a.Nil(o.run())
+	_, err = os.Stat(path)
+	a.True(os.IsNotExist(err))
+}
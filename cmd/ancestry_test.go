@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAncestryEntries(t *testing.T) {
+	ancestryFile := filepath.Join(t.TempDir(), "ancestry.yaml")
+	content := `
+projects/my-project: organization/123/folder/456
+other-project: organization/123
+folders/456: organization/123
+`
+	if err := ioutil.WriteFile(ancestryFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name         string
+		project      string
+		ancestry     string
+		ancestryFile string
+		want         map[string]string
+	}{
+		{
+			name:     "flags only",
+			project:  "my-project",
+			ancestry: "organization/1",
+			want:     map[string]string{"my-project": "organization/1"},
+		},
+		{
+			name:         "file only",
+			ancestryFile: ancestryFile,
+			want: map[string]string{
+				"projects/my-project": "organization/123/folder/456",
+				"other-project":       "organization/123",
+				"folders/456":         "organization/123",
+			},
+		},
+		{
+			name:         "ancestry flag takes precedence",
+			project:      "my-project",
+			ancestry:     "organization/1",
+			ancestryFile: ancestryFile,
+			want: map[string]string{
+				"my-project":    "organization/1",
+				"other-project": "organization/123",
+				"folders/456":   "organization/123",
+			},
+		},
+		{
+			name:         "project without ancestry flag uses file",
+			project:      "other-project",
+			ancestryFile: ancestryFile,
+			want: map[string]string{
+				"projects/my-project": "organization/123/folder/456",
+				"other-project":       "organization/123",
+				"folders/456":         "organization/123",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ancestryEntries(c.project, c.ancestry, c.ancestryFile)
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestAncestryEntries_Fail(t *testing.T) {
+	ancestryFile := filepath.Join(t.TempDir(), "ancestry.yaml")
+	if err := ioutil.WriteFile(ancestryFile, []byte("- organization/123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ancestryEntries("my-project", "", ancestryFile)
+	assert.NotNil(t, err)
+}
+
+func TestValidateArgsOfflineAncestryFile(t *testing.T) {
+	a := assert.New(t)
+	c := &convertOptions{offline: true}
+	a.NotNil(c.validateArgs([]string{"plan.json"}))
+	c.ancestryFile = "ancestry.yaml"
+	a.Nil(c.validateArgs([]string{"plan.json"}))
+
+	v := &validateOptions{offline: true, policyPath: "policies"}
+	a.NotNil(v.validateArgs([]string{"plan.json"}))
+	v.ancestryFile = "ancestry.yaml"
+	a.Nil(v.validateArgs([]string{"plan.json"}))
+}
+
+func TestReadOptions(t *testing.T) {
+	a := assert.New(t)
+
+	opts, err := readOptions(ancestryCacheOptions{}, "", "", converterOptions{}, endpointOptions{}, cassetteOptions{})
+	a.Nil(err)
+	a.Nil(opts.AncestryCache)
+	a.Nil(opts.BucketProjects)
+	a.False(opts.MergeProjectAliases)
+
+	bucketFile := filepath.Join(t.TempDir(), "buckets.yaml")
+	a.Nil(ioutil.WriteFile(bucketFile, []byte("my-bucket: my-project\n"), 0644))
+	opts, err = readOptions(ancestryCacheOptions{}, bucketFile, "", converterOptions{}, endpointOptions{}, cassetteOptions{})
+	a.Nil(err)
+	a.Equal(map[string]string{"my-bucket": "my-project"}, opts.BucketProjects)
+
+	_, err = readOptions(ancestryCacheOptions{}, filepath.Join(t.TempDir(), "missing.yaml"), "", converterOptions{}, endpointOptions{}, cassetteOptions{})
+	a.NotNil(err)
+
+	projectFile := filepath.Join(t.TempDir(), "projects.yaml")
+	a.Nil(ioutil.WriteFile(projectFile, []byte("my-project: 1234567890\n"), 0644))
+	opts, err = readOptions(ancestryCacheOptions{}, "", projectFile, converterOptions{}, endpointOptions{}, cassetteOptions{})
+	a.Nil(err)
+	a.Equal(map[string]string{"my-project": "1234567890"}, opts.ProjectNumbers)
+	a.True(opts.MergeProjectAliases)
+
+	a.Nil(ioutil.WriteFile(projectFile, []byte("my-project: other-project\n"), 0644))
+	_, err = readOptions(ancestryCacheOptions{}, "", projectFile, converterOptions{}, endpointOptions{}, cassetteOptions{})
+	a.NotNil(err)
+
+	pluginDir := t.TempDir()
+	a.Nil(ioutil.WriteFile(filepath.Join(pluginDir, "factory.yaml"), []byte("command: [./factory]\nresource_types: [mycorp_gcp_project_factory]\n"), 0644))
+	opts, err = readOptions(ancestryCacheOptions{}, "", "", converterOptions{pluginDir: pluginDir}, endpointOptions{}, cassetteOptions{})
+	a.Nil(err)
+	a.Len(opts.Plugins, 1)
+	a.Equal("factory", opts.Plugins[0].Name)
+
+	converterDir := t.TempDir()
+	a.Nil(ioutil.WriteFile(filepath.Join(converterDir, "topic.yaml"), []byte("resource_type: google_pubsub_topic\nasset_type: pubsub.googleapis.com/Topic\nasset_name: //pubsub.googleapis.com/projects/{{project}}/topics/{{name}}\n"), 0644))
+	opts, err = readOptions(ancestryCacheOptions{}, "", "", converterOptions{converterDir: converterDir}, endpointOptions{}, cassetteOptions{})
+	a.Nil(err)
+	a.Len(opts.ConverterDefinitions, 1)
+	a.Equal("google_pubsub_topic", opts.ConverterDefinitions[0].ResourceType)
+
+	endpointsFile := filepath.Join(t.TempDir(), "endpoints.yaml")
+	a.Nil(ioutil.WriteFile(endpointsFile, []byte("resource_manager: http://localhost:8080/v1/\n"), 0644))
+	opts, err = readOptions(ancestryCacheOptions{}, "", "", converterOptions{}, endpointOptions{endpointsFile: endpointsFile}, cassetteOptions{})
+	a.Nil(err)
+	a.Equal(map[string]string{"ResourceManager": "http://localhost:8080/v1/"}, opts.BasePaths)
+
+	a.Nil(ioutil.WriteFile(endpointsFile, []byte("unknown: http://localhost:8080/v1/\n"), 0644))
+	_, err = readOptions(ancestryCacheOptions{}, "", "", converterOptions{}, endpointOptions{endpointsFile: endpointsFile}, cassetteOptions{})
+	a.NotNil(err)
+}
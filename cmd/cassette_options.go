@@ -0,0 +1,84 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/cassette"
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+// cassetteOptions are the flags recording the Google API calls of a run to a
+// cassette, or replaying them from one without credentials.
+type cassetteOptions struct {
+	recordDir string
+	replayDir string
+}
+
+func (o *cassetteOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.recordDir, "record-dir", "", "If specified, empty directory to record the Google API calls to, as a sanitized cassette for --replay-dir")
+	cmd.Flags().StringVar(&o.replayDir, "replay-dir", "", "If specified, directory of a cassette recorded with --record-dir, whose responses are given instead of calling Google APIs. Requests that are not in the cassette fail")
+}
+
+// validate checks that the flags are consistent with offline mode, which
+// makes no API calls.
+func (o *cassetteOptions) validate(offline bool) error {
+	if o.recordDir != "" && o.replayDir != "" {
+		return errors.New("--record-dir and --replay-dir cannot be used together")
+	}
+	if offline && (o.recordDir != "" || o.replayDir != "") {
+		return errors.New("--record-dir and --replay-dir cannot be used in offline mode")
+	}
+	return nil
+}
+
+// apply sets the client of opts to record to or replay from a cassette.
+// Recording loads the default credentials. Both clients are given the raw
+// transport: request logging and retries are added on top of them when the
+// config is built, so each attempt is recorded and replayed once.
+func (o *cassetteOptions) apply(opts *tfgcv.ReadOptions) error {
+	if o.replayDir != "" {
+		replayer, err := cassette.NewReplayer(o.replayDir)
+		if err != nil {
+			return err
+		}
+		opts.HTTPClient = &http.Client{Transport: replayer}
+	}
+	if o.recordDir != "" {
+		// An offline config only looks up the credentials, without building
+		// its own client.
+		cfg, err := resources.NewConfig(context.Background(), "", "", "", true, "", nil)
+		if err != nil {
+			return fmt.Errorf("building google client to record: %w", err)
+		}
+		creds, err := cfg.GetCredentials(resources.DefaultClientScopes, false)
+		if err != nil {
+			return fmt.Errorf("building google client to record: %w", err)
+		}
+		recorder, err := cassette.NewRecorder(o.recordDir, http.DefaultTransport)
+		if err != nil {
+			return err
+		}
+		opts.HTTPClient = &http.Client{Transport: &oauth2.Transport{Source: creds.TokenSource, Base: recorder}}
+	}
+	return nil
+}
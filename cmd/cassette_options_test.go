@@ -0,0 +1,69 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    cassetteOptions
+		offline bool
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "none offline", offline: true},
+		{name: "record", opts: cassetteOptions{recordDir: "dir"}},
+		{name: "replay", opts: cassetteOptions{replayDir: "dir"}},
+		{name: "record and replay", opts: cassetteOptions{recordDir: "dir", replayDir: "dir"}, wantErr: true},
+		{name: "replay offline", opts: cassetteOptions{replayDir: "dir"}, offline: true, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.validate(c.offline)
+			if (err != nil) != c.wantErr {
+				t.Errorf("validate(%t) = %v, wantErr = %t", c.offline, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadOptions_replay(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+
+	_, err := readOptions(ancestryCacheOptions{}, "", "", converterOptions{}, endpointOptions{}, cassetteOptions{replayDir: dir})
+	a.NotNil(err, "empty cassette")
+
+	interaction := `{"request": {"method": "GET", "url": "https://storage.googleapis.com/storage/v1/b/my-bucket?alt=json"}, "response": {"status_code": 200, "body": "{}"}}`
+	a.Nil(ioutil.WriteFile(filepath.Join(dir, "00001-GET.json"), []byte(interaction), 0644))
+	opts, err := readOptions(ancestryCacheOptions{}, "", "", converterOptions{}, endpointOptions{}, cassetteOptions{replayDir: dir})
+	a.Nil(err)
+	a.NotNil(opts.HTTPClient)
+	resp, err := opts.HTTPClient.Get("https://storage.googleapis.com/storage/v1/b/my-bucket?alt=json")
+	a.Nil(err)
+	if resp != nil {
+		resp.Body.Close()
+		a.Equal(200, resp.StatusCode)
+	}
+	_, err = opts.HTTPClient.Get("https://storage.googleapis.com/storage/v1/b/other?alt=json")
+	a.NotNil(err, "unmatched request")
+}
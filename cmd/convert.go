@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/discovery"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
 	"github.com/GoogleCloudPlatform/terraform-validator/version"
 	"github.com/pkg/errors"
@@ -37,6 +39,11 @@ Note:
   Run "terraform-validator list-supported-resources" to see all supported
   resources.
 
+  With --strict-schema, the command fails if the data of converted assets has
+  fields, types or enum values that are not in the schemas of their discovery
+  documents. Known deviations of the built-in converters, and assets whose
+  discovery document is not a Google API, are logged as warnings.
+
 Example:
   terraform-validator convert ./example/terraform.tfplan --project my-project \
     --ancestry organization/my-org/folder/my-folder
@@ -52,19 +59,30 @@ func multiEnvSearch(ks []string) string {
 }
 
 type convertOptions struct {
-	project           string
-	ancestry          string
-	offline           bool
-	rootOptions       *rootOptions
-	readPlannedAssets tfgcv.ReadPlannedAssetsFunc
-	outputPath        string
-	dryRun            bool
+	project              string
+	ancestry             string
+	ancestryFile         string
+	bucketProjectFile    string
+	projectNumberFile    string
+	mergeProjectAliases  bool
+	concurrency          int
+	converters           converterOptions
+	ancestryCache        ancestryCacheOptions
+	endpoints            endpointOptions
+	cassettes            cassetteOptions
+	normalizeOrgPolicies bool
+	offline              bool
+	strictSchema         bool
+	rootOptions          *rootOptions
+	readPlannedAssets    tfgcv.ReadPlannedAssetsWithOptionsFunc
+	outputPath           string
+	dryRun               bool
 }
 
 func newConvertCmd(rootOptions *rootOptions) *cobra.Command {
 	o := &convertOptions{
 		rootOptions:       rootOptions,
-		readPlannedAssets: tfgcv.ReadPlannedAssets,
+		readPlannedAssets: tfgcv.ReadPlannedAssetsWithOptions,
 	}
 
 	cmd := &cobra.Command{
@@ -84,7 +102,18 @@ func newConvertCmd(rootOptions *rootOptions) *cobra.Command {
 
 	cmd.Flags().StringVar(&o.project, "project", "", "Provider project override (override the default project configuration assigned to the google terraform provider when converting resources)")
 	cmd.Flags().StringVar(&o.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
+	cmd.Flags().StringVar(&o.ancestryFile, "ancestry-file", "", "Path to a YAML or JSON file mapping projects, folders and project numbers to ancestry paths")
+	cmd.Flags().StringVar(&o.bucketProjectFile, "bucket-project-file", "", "Path to a YAML or JSON file mapping storage bucket names to project IDs or numbers, used for buckets that are not in the plan")
+	cmd.Flags().StringVar(&o.projectNumberFile, "project-number-file", "", "Path to a YAML or JSON file mapping project IDs to project numbers, used to merge the assets of a project addressed by ID and by number")
+	cmd.Flags().BoolVar(&o.mergeProjectAliases, "merge-project-aliases", false, "Name project assets addressed by project ID by their project number, so that they merge with the assets of the project addressed by number. Online, the number is looked up with Google API once per project. Implied by --project-number-file")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", google.DefaultConcurrency, "Maximum number of resources converted, fetched or looked up at the same time")
+	o.converters.addFlags(cmd)
+	o.ancestryCache.addFlags(cmd)
+	o.endpoints.addFlags(cmd)
+	o.cassettes.addFlags(cmd)
 	cmd.Flags().BoolVar(&o.offline, "offline", false, "Do not make network requests")
+	cmd.Flags().BoolVar(&o.normalizeOrgPolicies, "normalize-org-policies", false, "Give organization policies in both the v1 (org_policy) and v2 (v2_org_policies) formats")
+	cmd.Flags().BoolVar(&o.strictSchema, "strict-schema", false, "Fail if the data of converted assets does not match the schemas of their discovery documents")
 	cmd.Flags().StringVar(&o.outputPath, "output-path", "", "If specified, write the convert result into the specified output file")
 	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only parse & validate args")
 	cmd.Flags().MarkHidden("dry-run")
@@ -96,18 +125,30 @@ func (o *convertOptions) validateArgs(args []string) error {
 	if len(args) != 1 {
 		return errors.New("missing required argument TFPLAN_JSON")
 	}
-	if o.offline && o.ancestry == "" {
-		return errors.New("please set ancestry via --ancestry in offline mode")
+	if o.offline && o.ancestry == "" && o.ancestryFile == "" {
+		return errors.New("please set ancestry via --ancestry or --ancestry-file in offline mode")
+	}
+	if err := o.cassettes.validate(o.offline); err != nil {
+		return err
 	}
 	return nil
 }
 
 func (o *convertOptions) run(plan string) error {
 	ctx := context.Background()
-	ancestryCache := map[string]string{}
-	if o.project != "" {
-		ancestryCache[o.project] = o.ancestry
+	ancestryCache, err := ancestryEntries(o.project, o.ancestry, o.ancestryFile)
+	if err != nil {
+		return err
+	}
+	readOpts, err := readOptions(o.ancestryCache, o.bucketProjectFile, o.projectNumberFile, o.converters, o.endpoints, o.cassettes)
+	if err != nil {
+		return err
+	}
+	if o.mergeProjectAliases {
+		readOpts.MergeProjectAliases = true
 	}
+	readOpts.Concurrency = o.concurrency
+	readOpts.NormalizeOrgPolicies = o.normalizeOrgPolicies
 	zone := multiEnvSearch([]string{
 		"GOOGLE_ZONE",
 		"GCLOUD_ZONE",
@@ -120,11 +161,30 @@ func (o *convertOptions) run(plan string) error {
 		"CLOUDSDK_COMPUTE_REGION",
 	})
 	userAgent := fmt.Sprintf("config-validator-tf/%s", version.BuildVersion())
-	assets, err := o.readPlannedAssets(ctx, plan, o.project, zone, region, ancestryCache, o.offline, false, o.rootOptions.errorLogger, userAgent)
+	assets, err := o.readPlannedAssets(ctx, plan, o.project, zone, region, ancestryCache, o.offline, false, o.rootOptions.errorLogger, userAgent, readOpts)
 	if err != nil {
 		return err
 	}
 
+	if o.strictSchema {
+		issues, err := discovery.NewChecker().CheckAssets(assets)
+		if err != nil {
+			return err
+		}
+		errs := 0
+		for _, issue := range issues {
+			if issue.Warning {
+				o.rootOptions.errorLogger.Warn(issue.String())
+				continue
+			}
+			o.rootOptions.errorLogger.Error(issue.String())
+			errs++
+		}
+		if errs > 0 {
+			return fmt.Errorf("found %d schema issues in converted assets", errs)
+		}
+	}
+
 	if len(o.outputPath) > 0 {
 		f, err := os.OpenFile(o.outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
 		if err != nil {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
 	"github.com/GoogleCloudPlatform/terraform-validator/version"
 
 	"github.com/stretchr/testify/assert"
@@ -62,7 +63,7 @@ func testAssets(path, project, zone, region string, ancestry map[string]string,
 	}
 }
 
-func MockReadPlannedAssets(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string) ([]google.Asset, error) {
+func MockReadPlannedAssets(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts tfgcv.ReadOptions) ([]google.Asset, error) {
 	return testAssets(path, project, zone, region, ancestry, offline, convertUnchanged, errorLogger, userAgent), nil
 }
 
@@ -350,3 +351,27 @@ func TestConvertRun_passesCorrectArguments(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertRunStrictSchema(t *testing.T) {
+	a := assert.New(t)
+	verbosity := "debug"
+	useStructuredLogging := false
+	errorLogger, errorBuf := newTestErrorLogger(verbosity, useStructuredLogging)
+	outputLogger, _ := newTestOutputLogger()
+	ro := &rootOptions{
+		verbosity:            verbosity,
+		useStructuredLogging: useStructuredLogging,
+		errorLogger:          errorLogger,
+		outputLogger:         outputLogger,
+	}
+	o := convertOptions{
+		rootOptions:       ro,
+		readPlannedAssets: MockReadPlannedAssets,
+		strictSchema:      true,
+	}
+
+	// The test assets have an "arguments" field that disks do not have.
+	err := o.run("/path/to/plan")
+	a.EqualError(err, "found 1 schema issues in converted assets")
+	a.Contains(errorBuf.String(), "//compute.googleapis.com/projects/my-project/zones/us-central1-a/disks/test-disk: arguments: unknown field: no such field")
+}
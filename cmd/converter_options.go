@@ -0,0 +1,51 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/plugin"
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/spf13/cobra"
+)
+
+// converterOptions are the flags adding converters at runtime.
+type converterOptions struct {
+	pluginDir    string
+	converterDir string
+}
+
+func (o *converterOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.pluginDir, "plugin-dir", "", "Path to a directory of plugin manifests, for external converters of resource types that have no built-in converter")
+	cmd.Flags().StringVar(&o.converterDir, "converter-dir", "", "Path to a directory of YAML converter definitions, which add or replace the converters of google provider resource types")
+}
+
+// apply loads the plugins and converter definitions into opts.
+func (o *converterOptions) apply(opts *tfgcv.ReadOptions) error {
+	var err error
+	if o.pluginDir != "" {
+		opts.Plugins, err = plugin.LoadDir(o.pluginDir)
+		if err != nil {
+			return err
+		}
+	}
+	if o.converterDir != "" {
+		opts.ConverterDefinitions, err = resources.ReadConverterDefinitions(o.converterDir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/GoogleCloudPlatform/terraform-validator/version"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const effectiveOrgPolicyDesc = `
+This command computes the organization policies in effect for the resources of
+a Terraform plan, combining the v1 and v2 org policies of the plan, of an
+optional CAI (Cloud Asset Inventory) export and the ancestry of each resource.
+It outputs, for each resource and each constraint set on its ancestors, the
+effective policy as JSON, along with the planned resources that violate the
+list constraints gcp.resourceLocations and compute.vmExternalIpAccess.
+
+The command exits with status 2 if violations are found.
+
+Example:
+  terraform-validator effective-org-policy ./example/terraform.tfplan \
+    --project my-project --cai-export ./org-policies.json
+`
+
+type effectiveOrgPolicyOptions struct {
+	project             string
+	ancestry            string
+	ancestryFile        string
+	bucketProjectFile   string
+	projectNumberFile   string
+	mergeProjectAliases bool
+	concurrency         int
+	converters          converterOptions
+	caiExport           string
+	ancestryCache       ancestryCacheOptions
+	endpoints           endpointOptions
+	cassettes           cassetteOptions
+	offline             bool
+	rootOptions         *rootOptions
+	readPlannedAssets   tfgcv.ReadPlannedAssetsWithOptionsFunc
+	dryRun              bool
+}
+
+// effectiveOrgPolicyResult is the output of the effective-org-policy command.
+type effectiveOrgPolicyResult struct {
+	EffectivePolicies []tfgcv.EffectiveOrgPolicy `json:"effective_policies"`
+	Violations        []tfgcv.OrgPolicyViolation `json:"violations"`
+}
+
+func newEffectiveOrgPolicyCmd(rootOptions *rootOptions) *cobra.Command {
+	o := &effectiveOrgPolicyOptions{
+		rootOptions:       rootOptions,
+		readPlannedAssets: tfgcv.ReadPlannedAssetsWithOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "effective-org-policy TFPLAN_JSON",
+		Short: "Compute the org policies in effect for the resources of a Terraform plan",
+		Long:  effectiveOrgPolicyDesc,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return o.validateArgs(args)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if o.dryRun {
+				return nil
+			}
+			return o.run(args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&o.project, "project", "", "Provider project override (override the default project configuration assigned to the google terraform provider when converting resources)")
+	cmd.Flags().StringVar(&o.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
+	cmd.Flags().StringVar(&o.ancestryFile, "ancestry-file", "", "Path to a YAML or JSON file mapping projects, folders and project numbers to ancestry paths")
+	cmd.Flags().StringVar(&o.bucketProjectFile, "bucket-project-file", "", "Path to a YAML or JSON file mapping storage bucket names to project IDs or numbers, used for buckets that are not in the plan")
+	cmd.Flags().StringVar(&o.projectNumberFile, "project-number-file", "", "Path to a YAML or JSON file mapping project IDs to project numbers, used to merge the assets of a project addressed by ID and by number")
+	cmd.Flags().BoolVar(&o.mergeProjectAliases, "merge-project-aliases", false, "Name project assets addressed by project ID by their project number, so that they merge with the assets of the project addressed by number. Online, the number is looked up with Google API once per project. Implied by --project-number-file")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", google.DefaultConcurrency, "Maximum number of resources converted, fetched or looked up at the same time")
+	o.converters.addFlags(cmd)
+	cmd.Flags().StringVar(&o.caiExport, "cai-export", "", "Path to a CAI export of the org policies of existing projects, folders and organizations, as newline-delimited JSON or a JSON array")
+	o.ancestryCache.addFlags(cmd)
+	o.endpoints.addFlags(cmd)
+	o.cassettes.addFlags(cmd)
+	cmd.Flags().BoolVar(&o.offline, "offline", false, "Do not make network requests")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only parse & validate args")
+	cmd.Flags().MarkHidden("dry-run")
+
+	return cmd
+}
+
+func (o *effectiveOrgPolicyOptions) validateArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("missing required argument TFPLAN_JSON")
+	}
+	if o.offline && o.ancestry == "" && o.ancestryFile == "" {
+		return errors.New("please set ancestry via --ancestry or --ancestry-file in offline mode")
+	}
+	if err := o.cassettes.validate(o.offline); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *effectiveOrgPolicyOptions) run(plan string) error {
+	ctx := context.Background()
+	ancestryCache, err := ancestryEntries(o.project, o.ancestry, o.ancestryFile)
+	if err != nil {
+		return err
+	}
+	readOpts, err := readOptions(o.ancestryCache, o.bucketProjectFile, o.projectNumberFile, o.converters, o.endpoints, o.cassettes)
+	if err != nil {
+		return err
+	}
+	if o.mergeProjectAliases {
+		readOpts.MergeProjectAliases = true
+	}
+	readOpts.Concurrency = o.concurrency
+	readOpts.NormalizeOrgPolicies = true
+	var existing []google.Asset
+	if o.caiExport != "" {
+		existing, err = tfgcv.ReadCAIExport(o.caiExport)
+		if err != nil {
+			return err
+		}
+	}
+	zone := multiEnvSearch([]string{
+		"GOOGLE_ZONE",
+		"GCLOUD_ZONE",
+		"CLOUDSDK_COMPUTE_ZONE",
+	})
+
+	region := multiEnvSearch([]string{
+		"GOOGLE_REGION",
+		"GCLOUD_REGION",
+		"CLOUDSDK_COMPUTE_REGION",
+	})
+	userAgent := fmt.Sprintf("config-validator-tf/%s", version.BuildVersion())
+	assets, err := o.readPlannedAssets(ctx, plan, o.project, zone, region, ancestryCache, o.offline, false, o.rootOptions.errorLogger, userAgent, readOpts)
+	if err != nil {
+		return err
+	}
+
+	effective := tfgcv.EffectiveOrgPolicies(assets, existing)
+	result := effectiveOrgPolicyResult{
+		EffectivePolicies: effective,
+		Violations:        tfgcv.CheckListConstraints(assets, effective),
+	}
+
+	if o.rootOptions.useStructuredLogging {
+		o.rootOptions.outputLogger.Info(
+			"effective org policies",
+			zap.Any("resource_body", result),
+		)
+	} else if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		return fmt.Errorf("encoding json: %w", err)
+	}
+	if len(result.Violations) > 0 {
+		return errViolations
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestEffectiveOrgPolicyRun(t *testing.T) {
+	a := assert.New(t)
+	caiExport := filepath.Join(t.TempDir(), "export.json")
+	a.Nil(ioutil.WriteFile(caiExport, []byte(`{"name":"//cloudresourcemanager.googleapis.com/organizations/123","assetType":"cloudresourcemanager.googleapis.com/Organization","orgPolicy":[{"constraint":"constraints/gcp.resourceLocations","listPolicy":{"allowedValues":["in:us-locations"]}}]}`), 0644))
+
+	var readOpts tfgcv.ReadOptions
+	readPlannedAssets := func(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts tfgcv.ReadOptions) ([]google.Asset, error) {
+		readOpts = opts
+		return []google.Asset{{
+			Name:      "//storage.googleapis.com/my-bucket",
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: []string{"projects/my-project", "organizations/123"},
+			Resource:  &google.AssetResource{Data: map[string]interface{}{"location": "EUROPE-WEST1"}},
+		}}, nil
+	}
+
+	errorLogger, _ := newTestErrorLogger("debug", true)
+	outputLogger, outputBuf := newTestOutputLogger()
+	o := effectiveOrgPolicyOptions{
+		caiExport:           caiExport,
+		mergeProjectAliases: true,
+		concurrency:         4,
+		rootOptions: &rootOptions{
+			verbosity:            "debug",
+			useStructuredLogging: true,
+			errorLogger:          errorLogger,
+			outputLogger:         outputLogger,
+		},
+		readPlannedAssets: readPlannedAssets,
+	}
+
+	err := o.run("/path/to/plan")
+	a.ErrorIs(err, errViolations)
+	a.True(readOpts.NormalizeOrgPolicies)
+	a.True(readOpts.MergeProjectAliases)
+	a.Equal(4, readOpts.Concurrency)
+
+	var output struct {
+		ResourceBody effectiveOrgPolicyResult `json:"resource_body"`
+	}
+	a.Nil(json.Unmarshal(outputBuf.Bytes(), &output))
+	a.Len(output.ResourceBody.EffectivePolicies, 1)
+	a.Equal("constraints/gcp.resourceLocations", output.ResourceBody.EffectivePolicies[0].Constraint)
+	a.Equal([]string{"organizations/123"}, output.ResourceBody.EffectivePolicies[0].Sources)
+	a.Len(output.ResourceBody.Violations, 1)
+	a.Equal("europe-west1", output.ResourceBody.Violations[0].Value)
+}
+
+func TestEffectiveOrgPolicyValidateArgs(t *testing.T) {
+	a := assert.New(t)
+	o := &effectiveOrgPolicyOptions{}
+	a.NotNil(o.validateArgs(nil))
+	a.Nil(o.validateArgs([]string{"plan.json"}))
+
+	o.offline = true
+	a.NotNil(o.validateArgs([]string{"plan.json"}))
+	o.ancestry = "organizations/123"
+	a.Nil(o.validateArgs([]string{"plan.json"}))
+}
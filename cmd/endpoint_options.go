@@ -0,0 +1,42 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/spf13/cobra"
+)
+
+// endpointOptions are the flags overriding the endpoints of Google APIs.
+// Endpoints can also be overridden with the environment variables of the
+// provider, like GOOGLE_RESOURCE_MANAGER_CUSTOM_ENDPOINT.
+type endpointOptions struct {
+	endpointsFile string
+}
+
+func (o *endpointOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.endpointsFile, "endpoints-file", "", "Path to a YAML or JSON file mapping Google API services to base paths, like resource_manager: https://crm-myendpoint.p.googleapis.com/v1/, overriding the GOOGLE_*_CUSTOM_ENDPOINT environment variables")
+}
+
+// apply reads the endpoints file into opts.
+func (o *endpointOptions) apply(opts *tfgcv.ReadOptions) error {
+	if o.endpointsFile == "" {
+		return nil
+	}
+	var err error
+	opts.BasePaths, err = resources.ReadBasePathsFile(o.endpointsFile)
+	return err
+}
@@ -41,7 +41,7 @@ func newListSupportedResourcesCmd() *cobra.Command {
 }
 
 func (o *listSupportedResourcesOptions) run() error {
-	converters := resources.ResourceConverters()
+	converters := resources.AllResourceConverters()
 
 	// Get a sorted list of terraform resources
 	list := make([]string, 0, len(converters))
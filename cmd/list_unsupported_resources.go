@@ -41,7 +41,7 @@ func newListUnsupportedResourcesCmd() *cobra.Command {
 
 func (o *listUnsupportedResourcesOptions) run() error {
 	// Get a map of supported terraform resources
-	converters := resources.ResourceConverters()
+	converters := resources.AllResourceConverters()
 
 	// Get a sorted list of unsupported resources
 	schema := provider.Provider()
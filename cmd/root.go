@@ -79,11 +79,15 @@ func newRootCmd() (*cobra.Command, *rootOptions, error) {
 
 	cmd.PersistentFlags().StringVar(&o.verbosity, "verbosity", "info", "Set verbosity level. One of: debug, info, warning, error, critical, none.")
 
+	cmd.AddCommand(newClearAncestryCacheCmd())
 	cmd.AddCommand(newConvertCmd(o))
+	cmd.AddCommand(newEffectiveOrgPolicyCmd(o))
 	cmd.AddCommand(newListSupportedResourcesCmd())
 	cmd.AddCommand(newListUnsupportedResourcesCmd())
+	cmd.AddCommand(newServeCmd(o))
 	cmd.AddCommand(newValidateCmd(o))
 	cmd.AddCommand(newVersionCmd())
+	cmd.AddCommand(newWhoCanCmd(o))
 
 	return cmd, o, nil
 }
@@ -0,0 +1,270 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/server"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/GoogleCloudPlatform/terraform-validator/version"
+)
+
+const serveDesc = `
+Serve the conversion and validation of terraform plans over HTTP, and
+optionally gRPC. The policy library and CEL rules are loaded once, and
+reloaded when their files change.
+
+HTTP endpoints:
+  POST /v1/convert          convert a terraform plan JSON to CAI assets
+  POST /v1/validate         validate a terraform plan JSON
+  POST /v1/validate-assets  validate a JSON array of CAI assets
+  GET  /healthz             liveness
+  GET  /readyz              readiness
+  POST /v1/run-task         Terraform Cloud run task, with --run-task-hmac-key-file
+
+The responses are the JSON output of the convert and validate commands with
+--output-json. The gRPC server implements the Review method of the Config
+Validator service and the gRPC health service.
+
+The Terraform Cloud run task endpoint verifies the signatures of requests with
+the HMAC key of the run task, then downloads, converts and validates the plan
+of the run in the background, and sends one outcome per violation to the run.
+It must be configured for the post-plan stage.
+
+Example:
+  terraform-validator serve \
+    --project my-project \
+    --ancestry organization/my-org/folder/my-folder \
+    --policy-path ./path/to/my/gcv/policies \
+    --http-address :8080
+`
+
+// shutdownTimeout is how long requests may take to finish on shutdown.
+const shutdownTimeout = 30 * time.Second
+
+type serveOptions struct {
+	project              string
+	ancestry             string
+	ancestryFile         string
+	bucketProjectFile    string
+	projectNumberFile    string
+	mergeProjectAliases  bool
+	concurrency          int
+	converters           converterOptions
+	ancestryCache        ancestryCacheOptions
+	endpoints            endpointOptions
+	cassettes            cassetteOptions
+	normalizeOrgPolicies bool
+	offline              bool
+	policyPath           string
+	celRules             string
+	httpAddress          string
+	grpcAddress          string
+	reloadInterval       time.Duration
+	runTaskHMACKeyFile   string
+	dryRun               bool
+	rootOptions          *rootOptions
+}
+
+func newServeCmd(rootOptions *rootOptions) *cobra.Command {
+	o := &serveOptions{
+		rootOptions: rootOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "serve --policy-path=/path/to/policy/library",
+		Short: "Serve the conversion and validation of terraform plans",
+		Long:  serveDesc,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return o.validateArgs(args)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if o.dryRun {
+				return nil
+			}
+			return o.run()
+		},
+	}
+
+	cmd.Flags().StringVar(&o.policyPath, "policy-path", "", "Path to directory containing validation policies")
+	cmd.Flags().StringVar(&o.celRules, "cel-rules", "", "Path to a YAML file, or a directory of YAML files, containing CEL validation rules")
+	cmd.Flags().StringVar(&o.project, "project", "", "Provider project override (override the default project configuration assigned to the google terraform provider when validating resources)")
+	cmd.Flags().StringVar(&o.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
+	cmd.Flags().StringVar(&o.ancestryFile, "ancestry-file", "", "Path to a YAML or JSON file mapping projects, folders and project numbers to ancestry paths")
+	cmd.Flags().StringVar(&o.bucketProjectFile, "bucket-project-file", "", "Path to a YAML or JSON file mapping storage bucket names to project IDs or numbers, used for buckets that are not in the plan")
+	cmd.Flags().StringVar(&o.projectNumberFile, "project-number-file", "", "Path to a YAML or JSON file mapping project IDs to project numbers, used to merge the assets of a project addressed by ID and by number")
+	cmd.Flags().BoolVar(&o.mergeProjectAliases, "merge-project-aliases", false, "Name project assets addressed by project ID by their project number, so that they merge with the assets of the project addressed by number. Online, the number is looked up with Google API once per project. Implied by --project-number-file")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", google.DefaultConcurrency, "Maximum number of resources converted, fetched or looked up at the same time")
+	o.converters.addFlags(cmd)
+	o.ancestryCache.addFlags(cmd)
+	o.endpoints.addFlags(cmd)
+	o.cassettes.addFlags(cmd)
+	cmd.Flags().BoolVar(&o.offline, "offline", false, "Do not make network requests")
+	cmd.Flags().BoolVar(&o.normalizeOrgPolicies, "normalize-org-policies", false, "Give organization policies in both the v1 (org_policy) and v2 (v2_org_policies) formats")
+	cmd.Flags().StringVar(&o.httpAddress, "http-address", ":8080", "Address to serve HTTP on")
+	cmd.Flags().StringVar(&o.grpcAddress, "grpc-address", "", "If specified, address to serve gRPC on")
+	cmd.Flags().DurationVar(&o.reloadInterval, "reload-interval", 10*time.Second, "How often to check the policy library and CEL rules for changes, or 0 to never reload them")
+	cmd.Flags().StringVar(&o.runTaskHMACKeyFile, "run-task-hmac-key-file", "", "If specified, path to a file with the HMAC key of a Terraform Cloud run task, to serve the run task endpoint")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only parse & validate args")
+	cmd.Flags().MarkHidden("dry-run")
+
+	return cmd
+}
+
+func (o *serveOptions) validateArgs(args []string) error {
+	if len(args) != 0 {
+		return errors.New("serve does not take arguments")
+	}
+	if o.policyPath == "" && o.celRules == "" {
+		return errors.New("please set policies via --policy-path or --cel-rules")
+	}
+	if o.offline && o.ancestry == "" && o.ancestryFile == "" {
+		return errors.New("please set ancestry via --ancestry or --ancestry-file in offline mode")
+	}
+	if err := o.cassettes.validate(o.offline); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *serveOptions) serverOptions() (server.Options, error) {
+	ancestryCache, err := ancestryEntries(o.project, o.ancestry, o.ancestryFile)
+	if err != nil {
+		return server.Options{}, err
+	}
+	readOpts, err := readOptions(o.ancestryCache, o.bucketProjectFile, o.projectNumberFile, o.converters, o.endpoints, o.cassettes)
+	if err != nil {
+		return server.Options{}, err
+	}
+	if o.mergeProjectAliases {
+		readOpts.MergeProjectAliases = true
+	}
+	readOpts.Concurrency = o.concurrency
+	readOpts.NormalizeOrgPolicies = o.normalizeOrgPolicies
+	var hmacKey []byte
+	if o.runTaskHMACKeyFile != "" {
+		b, err := ioutil.ReadFile(o.runTaskHMACKeyFile)
+		if err != nil {
+			return server.Options{}, fmt.Errorf("reading run task HMAC key: %w", err)
+		}
+		hmacKey = bytes.TrimSpace(b)
+		if len(hmacKey) == 0 {
+			return server.Options{}, fmt.Errorf("run task HMAC key file %s is empty", o.runTaskHMACKeyFile)
+		}
+	}
+	return server.Options{
+		Validator: tfgcv.Options{
+			Project: o.project,
+			Zone: multiEnvSearch([]string{
+				"GOOGLE_ZONE",
+				"GCLOUD_ZONE",
+				"CLOUDSDK_COMPUTE_ZONE",
+			}),
+			Region: multiEnvSearch([]string{
+				"GOOGLE_REGION",
+				"GCLOUD_REGION",
+				"CLOUDSDK_COMPUTE_REGION",
+			}),
+			Ancestry:    ancestryCache,
+			Offline:     o.offline,
+			Logger:      o.rootOptions.errorLogger,
+			UserAgent:   fmt.Sprintf("config-validator-tf/%s", version.BuildVersion()),
+			PolicyPath:  o.policyPath,
+			CELRules:    o.celRules,
+			ReadOptions: readOpts,
+		},
+		ReloadInterval: o.reloadInterval,
+		RunTask:        server.RunTaskOptions{HMACKey: hmacKey},
+	}, nil
+}
+
+func (o *serveOptions) run() error {
+	opts, err := o.serverOptions()
+	if err != nil {
+		return err
+	}
+	s, err := server.New(opts)
+	if err != nil {
+		return err
+	}
+	logger := o.rootOptions.errorLogger
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go s.WatchPolicies(ctx)
+
+	errs := make(chan error, 2)
+	httpServer := &http.Server{Addr: o.httpAddress, Handler: s.Handler()}
+	go func() {
+		logger.Info(fmt.Sprintf("Serving HTTP on %s", o.httpAddress))
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("serving HTTP: %w", err)
+		}
+	}()
+	var grpcServer *grpc.Server
+	if o.grpcAddress != "" {
+		lis, err := net.Listen("tcp", o.grpcAddress)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %w", o.grpcAddress, err)
+		}
+		grpcServer = grpc.NewServer()
+		s.RegisterGRPC(grpcServer)
+		go func() {
+			logger.Info(fmt.Sprintf("Serving gRPC on %s", o.grpcAddress))
+			if err := grpcServer.Serve(lis); err != nil {
+				errs <- fmt.Errorf("serving gRPC: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		logger.Info("Shutting down")
+	}
+	s.SetReady(false)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if grpcServer != nil {
+		go func() {
+			<-shutdownCtx.Done()
+			grpcServer.Stop()
+		}()
+		grpcServer.GracefulStop()
+	}
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+		err = fmt.Errorf("shutting down HTTP: %w", shutdownErr)
+	}
+	// Let the accepted run tasks send their results.
+	if shutdownErr := s.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+		err = fmt.Errorf("waiting for run tasks: %w", shutdownErr)
+	}
+	return err
+}
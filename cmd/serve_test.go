@@ -0,0 +1,79 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeValidateArgs(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		opts    serveOptions
+		wantErr bool
+	}{
+		{name: "policy path", opts: serveOptions{policyPath: "/policies"}},
+		{name: "CEL rules", opts: serveOptions{celRules: "/rules.yaml"}},
+		{name: "no policies", opts: serveOptions{}, wantErr: true},
+		{name: "arguments", args: []string{"plan.json"}, opts: serveOptions{policyPath: "/policies"}, wantErr: true},
+		{name: "offline without ancestry", opts: serveOptions{policyPath: "/policies", offline: true}, wantErr: true},
+		{name: "offline with ancestry", opts: serveOptions{policyPath: "/policies", offline: true, ancestry: "organizations/123"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.validateArgs(c.args)
+			if (err != nil) != c.wantErr {
+				t.Errorf("validateArgs() = %v, want error = %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestServeServerOptions(t *testing.T) {
+	errorLogger, _ := newTestErrorLogger("debug", false)
+	o := serveOptions{
+		project:        "my-project",
+		ancestry:       "organizations/123/folders/456",
+		offline:        true,
+		policyPath:     "/policies",
+		celRules:       "/rules.yaml",
+		reloadInterval: time.Minute,
+		rootOptions:    &rootOptions{errorLogger: errorLogger},
+	}
+	got, err := o.serverOptions()
+	require.NoError(t, err)
+	assert.Equal(t, "my-project", got.Validator.Project)
+	assert.Equal(t, "organizations/123/folders/456", got.Validator.Ancestry["my-project"])
+	assert.True(t, got.Validator.Offline)
+	assert.Equal(t, "/policies", got.Validator.PolicyPath)
+	assert.Equal(t, "/rules.yaml", got.Validator.CELRules)
+	assert.Equal(t, errorLogger, got.Validator.Logger)
+	assert.Equal(t, time.Minute, got.ReloadInterval)
+	assert.Empty(t, got.RunTask.HMACKey)
+
+	o.runTaskHMACKeyFile = createEmptyFile(t, []byte("secret\n"))
+	got, err = o.serverOptions()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got.RunTask.HMACKey)
+
+	o.runTaskHMACKeyFile = createEmptyFile(t, []byte("\n"))
+	_, err = o.serverOptions()
+	assert.Error(t, err)
+}
@@ -33,7 +33,8 @@ import (
 
 const validateDesc = `
 Validate that a terraform plan conforms to a Constraint Framework
-policy library written to expect Google CAI (Cloud Asset Inventory) data.
+policy library written to expect Google CAI (Cloud Asset Inventory) data,
+and/or to CEL (Common Expression Language) rules read from YAML files.
 Unsupported terraform resources (see: "terraform-validate list-supported-resources")
 are skipped.
 
@@ -43,26 +44,41 @@ Example:
   terraform-validator validate ./example/terraform.tfplan \
     --project my-project \
     --ancestry organization/my-org/folder/my-folder \
-    --policy-path ./path/to/my/gcv/policies
+    --policy-path ./path/to/my/gcv/policies \
+    --cel-rules ./path/to/my/cel/rules.yaml
 `
 
 type validateOptions struct {
-	project           string
-	ancestry          string
-	offline           bool
-	policyPath        string
-	outputJSON        bool
-	dryRun            bool
-	rootOptions       *rootOptions
-	readPlannedAssets tfgcv.ReadPlannedAssetsFunc
-	validateAssets    tfgcv.ValidateAssetsFunc
+	project              string
+	ancestry             string
+	ancestryFile         string
+	bucketProjectFile    string
+	projectNumberFile    string
+	mergeProjectAliases  bool
+	concurrency          int
+	converters           converterOptions
+	ancestryCache        ancestryCacheOptions
+	endpoints            endpointOptions
+	cassettes            cassetteOptions
+	normalizeOrgPolicies bool
+	offline              bool
+	policyPath           string
+	celRules             string
+	celRuleSet           []*tfgcv.CELRule
+	outputJSON           bool
+	dryRun               bool
+	rootOptions          *rootOptions
+	readPlannedAssets    tfgcv.ReadPlannedAssetsWithOptionsFunc
+	validateAssets       tfgcv.ValidateAssetsFunc
+	reviewCELAssets      func(context.Context, []google.Asset, []*tfgcv.CELRule) ([]*validator.Violation, error)
 }
 
 func newValidateCmd(rootOptions *rootOptions) *cobra.Command {
 	o := &validateOptions{
 		rootOptions:       rootOptions,
-		readPlannedAssets: tfgcv.ReadPlannedAssets,
+		readPlannedAssets: tfgcv.ReadPlannedAssetsWithOptions,
 		validateAssets:    tfgcv.ValidateAssets,
+		reviewCELAssets:   tfgcv.ReviewAssetsWithCELRules,
 	}
 
 	cmd := &cobra.Command{
@@ -81,10 +97,20 @@ func newValidateCmd(rootOptions *rootOptions) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&o.policyPath, "policy-path", "", "Path to directory containing validation policies")
-	cmd.MarkFlagRequired("policy-path")
+	cmd.Flags().StringVar(&o.celRules, "cel-rules", "", "Path to a YAML file, or a directory of YAML files, containing CEL validation rules")
 	cmd.Flags().StringVar(&o.project, "project", "", "Provider project override (override the default project configuration assigned to the google terraform provider when validating resources)")
 	cmd.Flags().StringVar(&o.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
+	cmd.Flags().StringVar(&o.ancestryFile, "ancestry-file", "", "Path to a YAML or JSON file mapping projects, folders and project numbers to ancestry paths")
+	cmd.Flags().StringVar(&o.bucketProjectFile, "bucket-project-file", "", "Path to a YAML or JSON file mapping storage bucket names to project IDs or numbers, used for buckets that are not in the plan")
+	cmd.Flags().StringVar(&o.projectNumberFile, "project-number-file", "", "Path to a YAML or JSON file mapping project IDs to project numbers, used to merge the assets of a project addressed by ID and by number")
+	cmd.Flags().BoolVar(&o.mergeProjectAliases, "merge-project-aliases", false, "Name project assets addressed by project ID by their project number, so that they merge with the assets of the project addressed by number. Online, the number is looked up with Google API once per project. Implied by --project-number-file")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", google.DefaultConcurrency, "Maximum number of resources converted, fetched or looked up at the same time")
+	o.converters.addFlags(cmd)
+	o.ancestryCache.addFlags(cmd)
+	o.endpoints.addFlags(cmd)
+	o.cassettes.addFlags(cmd)
 	cmd.Flags().BoolVar(&o.offline, "offline", false, "Do not make network requests")
+	cmd.Flags().BoolVar(&o.normalizeOrgPolicies, "normalize-org-policies", false, "Give organization policies in both the v1 (org_policy) and v2 (v2_org_policies) formats")
 	cmd.Flags().BoolVar(&o.outputJSON, "output-json", false, "Print violations as JSON")
 	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Only parse & validate args")
 	cmd.Flags().MarkHidden("dry-run")
@@ -96,8 +122,23 @@ func (o *validateOptions) validateArgs(args []string) error {
 	if len(args) != 1 {
 		return errors.New("missing required argument TFPLAN_JSON")
 	}
-	if o.offline && o.ancestry == "" {
-		return errors.New("please set ancestry via --ancestry in offline mode")
+	if o.policyPath == "" && o.celRules == "" {
+		return errors.New("please set policies via --policy-path or --cel-rules")
+	}
+	if o.offline && o.ancestry == "" && o.ancestryFile == "" {
+		return errors.New("please set ancestry via --ancestry or --ancestry-file in offline mode")
+	}
+	if err := o.cassettes.validate(o.offline); err != nil {
+		return err
+	}
+	if o.celRules != "" {
+		// Compile the rules before converting the plan, which may take a
+		// while online.
+		rules, err := tfgcv.LoadCELRules(o.celRules)
+		if err != nil {
+			return err
+		}
+		o.celRuleSet = rules
 	}
 	return nil
 }
@@ -113,10 +154,19 @@ func (o *validateOptions) run(plan string) error {
 	var assets []google.Asset
 	if err := json.Unmarshal(content, &assets); err != nil {
 		var err error
-		ancestryCache := map[string]string{}
-		if o.project != "" {
-			ancestryCache[o.project] = o.ancestry
+		ancestryCache, err := ancestryEntries(o.project, o.ancestry, o.ancestryFile)
+		if err != nil {
+			return err
+		}
+		readOpts, err := readOptions(o.ancestryCache, o.bucketProjectFile, o.projectNumberFile, o.converters, o.endpoints, o.cassettes)
+		if err != nil {
+			return err
+		}
+		if o.mergeProjectAliases {
+			readOpts.MergeProjectAliases = true
 		}
+		readOpts.Concurrency = o.concurrency
+		readOpts.NormalizeOrgPolicies = o.normalizeOrgPolicies
 		userAgent := fmt.Sprintf("config-validator-tf/%s", version.BuildVersion())
 		zone := multiEnvSearch([]string{
 			"GOOGLE_ZONE",
@@ -129,15 +179,26 @@ func (o *validateOptions) run(plan string) error {
 			"GCLOUD_REGION",
 			"CLOUDSDK_COMPUTE_REGION",
 		})
-		assets, err = o.readPlannedAssets(ctx, plan, o.project, zone, region, ancestryCache, o.offline, false, o.rootOptions.errorLogger, userAgent)
+		assets, err = o.readPlannedAssets(ctx, plan, o.project, zone, region, ancestryCache, o.offline, false, o.rootOptions.errorLogger, userAgent, readOpts)
 		if err != nil {
 			return err
 		}
 	}
 
-	violations, err := o.validateAssets(ctx, assets, o.policyPath)
-	if err != nil {
-		return fmt.Errorf("validating: %w", err)
+	violations := []*validator.Violation{}
+	if o.policyPath != "" {
+		v, err := o.validateAssets(ctx, assets, o.policyPath)
+		if err != nil {
+			return fmt.Errorf("validating: %w", err)
+		}
+		violations = append(violations, v...)
+	}
+	if o.celRules != "" {
+		v, err := o.reviewCELAssets(ctx, assets, o.celRuleSet)
+		if err != nil {
+			return fmt.Errorf("validating with CEL rules: %w", err)
+		}
+		violations = append(violations, v...)
 	}
 
 	if o.rootOptions.useStructuredLogging {
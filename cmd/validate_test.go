@@ -11,6 +11,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
 	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
 	"github.com/GoogleCloudPlatform/terraform-validator/version"
 	"github.com/stretchr/testify/assert"
 )
@@ -54,7 +55,7 @@ func TestValidateRun(t *testing.T) {
 		project:           "",
 		ancestry:          "",
 		offline:           false,
-		policyPath:        "",
+		policyPath:        "/path/to/policies",
 		outputJSON:        false,
 		dryRun:            false,
 		rootOptions:       ro,
@@ -100,7 +101,7 @@ func TestValidateRunNoViolations(t *testing.T) {
 		project:           "",
 		ancestry:          "",
 		offline:           false,
-		policyPath:        "",
+		policyPath:        "/path/to/policies",
 		outputJSON:        false,
 		dryRun:            false,
 		rootOptions:       ro,
@@ -141,7 +142,7 @@ func TestValidateRunLegacy(t *testing.T) {
 		project:           "",
 		ancestry:          "",
 		offline:           false,
-		policyPath:        "",
+		policyPath:        "/path/to/policies",
 		outputJSON:        false,
 		dryRun:            false,
 		rootOptions:       ro,
@@ -177,7 +178,7 @@ func TestValidateRunNoViolationsLegacy(t *testing.T) {
 		project:           "",
 		ancestry:          "",
 		offline:           false,
-		policyPath:        "",
+		policyPath:        "/path/to/policies",
 		outputJSON:        false,
 		dryRun:            false,
 		rootOptions:       ro,
@@ -213,7 +214,7 @@ func TestValidateRunWithConvertedAssets(t *testing.T) {
 		project:        "",
 		ancestry:       "",
 		offline:        false,
-		policyPath:     "",
+		policyPath:     "/path/to/policies",
 		outputJSON:     false,
 		dryRun:         false,
 		rootOptions:    ro,
@@ -240,6 +241,65 @@ func TestValidateRunWithConvertedAssets(t *testing.T) {
 	a.Equal("", outputJSON)
 }
 
+func TestValidateRunWithCELRules(t *testing.T) {
+	a := assert.New(t)
+	errorLogger, _ := newTestErrorLogger("debug", true)
+	outputLogger, outputBuf := newTestOutputLogger()
+	celViolation := &validator.Violation{
+		Constraint: "bucket-location",
+		Resource:   "//storage.googleapis.com/my-bucket",
+		Message:    "Buckets must be located in the EU.",
+	}
+	rules := []*tfgcv.CELRule{{Name: "bucket-location"}}
+	o := validateOptions{
+		policyPath: "/path/to/policies",
+		celRules:   "/path/to/rules.yaml",
+		celRuleSet: rules,
+		rootOptions: &rootOptions{
+			verbosity:            "debug",
+			useStructuredLogging: true,
+			errorLogger:          errorLogger,
+			outputLogger:         outputLogger,
+		},
+		readPlannedAssets: MockReadPlannedAssets,
+		validateAssets:    MockValidateAssetsWithViolations,
+		reviewCELAssets: func(ctx context.Context, assets []google.Asset, celRules []*tfgcv.CELRule) ([]*validator.Violation, error) {
+			a.Equal(rules, celRules)
+			return []*validator.Violation{celViolation}, nil
+		},
+	}
+
+	err := o.run(createEmptyFile(t, []byte{'0'}))
+	a.ErrorIs(err, errViolations)
+
+	var output struct {
+		ResourceBody []map[string]interface{} `json:"resource_body"`
+	}
+	a.Nil(json.Unmarshal(outputBuf.Bytes(), &output))
+	a.Len(output.ResourceBody, 2)
+	a.Equal(testWithViolations()[0].Constraint, output.ResourceBody[0]["constraint"])
+	a.Equal("bucket-location", output.ResourceBody[1]["constraint"])
+
+	// CEL rules alone do not need a policy library.
+	rulesPath := path.Join(t.TempDir(), "rules.yaml")
+	a.Nil(ioutil.WriteFile(rulesPath, []byte("rules:\n- name: bucket-location\n  condition: has(asset.name)\n"), 0644))
+	o.policyPath = ""
+	o.celRules = rulesPath
+	o.validateAssets = nil
+	o.reviewCELAssets = tfgcv.ReviewAssetsWithCELRules
+	a.Nil(o.validateArgs([]string{"plan.json"}))
+	a.Len(o.celRuleSet, 1)
+	a.Nil(o.run(createEmptyFile(t, []byte{'0'})))
+
+	// Rules are compiled before the plan is converted.
+	a.Nil(ioutil.WriteFile(rulesPath, []byte("rules:\n- name: bad\n  condition: asset.name ==\n"), 0644))
+	o.readPlannedAssets = nil
+	a.NotNil(o.validateArgs([]string{"plan.json"}))
+
+	o.celRules = ""
+	a.NotNil(o.validateArgs([]string{"plan.json"}))
+}
+
 func TestValidateRun_passesCorrectArguments(t *testing.T) {
 	cases := []struct {
 		name         string
@@ -351,7 +411,7 @@ func TestValidateRun_passesCorrectArguments(t *testing.T) {
 				project:           c.project,
 				ancestry:          c.ancestry,
 				offline:           false,
-				policyPath:        "",
+				policyPath:        "/path/to/policies",
 				outputJSON:        false,
 				dryRun:            false,
 				rootOptions:       ro,
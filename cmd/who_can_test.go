@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWhoCanRun(t *testing.T) {
+	a := assert.New(t)
+	caiExport := filepath.Join(t.TempDir(), "export.json")
+	a.Nil(ioutil.WriteFile(caiExport, []byte(`{"name":"//cloudresourcemanager.googleapis.com/organizations/123","assetType":"cloudresourcemanager.googleapis.com/Organization","iamPolicy":{"bindings":[{"role":"roles/owner","members":["group:admins@example.com"]}]}}`), 0644))
+
+	readPlannedAssets := func(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts tfgcv.ReadOptions) ([]google.Asset, error) {
+		return []google.Asset{{
+			Name:      "//cloudresourcemanager.googleapis.com/projects/my-project",
+			Type:      "cloudresourcemanager.googleapis.com/Project",
+			Ancestors: []string{"projects/my-project", "organizations/123"},
+			IAMPolicy: &google.IAMPolicy{Bindings: []google.IAMBinding{
+				{Role: "roles/owner", Members: []string{"user:alice@example.com"}},
+				{Role: "roles/viewer", Members: []string{"user:bob@example.com"}},
+			}},
+			GrantAddresses: map[google.IAMGrant][]string{
+				{Role: "roles/owner", Member: "user:alice@example.com"}: {"google_project_iam_member.alice"},
+			},
+		}}, nil
+	}
+
+	errorLogger, _ := newTestErrorLogger("debug", true)
+	outputLogger, outputBuf := newTestOutputLogger()
+	o := whoCanOptions{
+		caiExport: caiExport,
+		resource:  "projects/my-project",
+		role:      "roles/owner",
+		rootOptions: &rootOptions{
+			verbosity:            "debug",
+			useStructuredLogging: true,
+			errorLogger:          errorLogger,
+			outputLogger:         outputLogger,
+		},
+		readPlannedAssets: readPlannedAssets,
+	}
+
+	a.Nil(o.run("/path/to/plan"))
+
+	var output struct {
+		ResourceBody tfgcv.WhoCanResult `json:"resource_body"`
+	}
+	a.Nil(json.Unmarshal(outputBuf.Bytes(), &output))
+	a.Equal(map[string][]string{"roles/owner": {"group:admins@example.com", "user:alice@example.com"}}, output.ResourceBody.MembersByRole)
+	a.Len(output.ResourceBody.Grants, 2)
+	a.Equal("//cloudresourcemanager.googleapis.com/organizations/123", output.ResourceBody.Grants[0].Asset)
+	a.Empty(output.ResourceBody.Grants[0].Addresses)
+	a.Equal([]string{"google_project_iam_member.alice"}, output.ResourceBody.Grants[1].Addresses)
+}
+
+func TestWhoCanValidateArgs(t *testing.T) {
+	a := assert.New(t)
+	o := &whoCanOptions{}
+	a.NotNil(o.validateArgs(nil))
+	a.NotNil(o.validateArgs([]string{"plan.json"}))
+
+	o.resource = "projects/my-project"
+	a.Nil(o.validateArgs([]string{"plan.json"}))
+	o.offline = true
+	a.NotNil(o.validateArgs([]string{"plan.json"}))
+	o.ancestry = "organizations/123"
+	a.Nil(o.validateArgs([]string{"plan.json"}))
+}
@@ -15,14 +15,17 @@
 package google
 
 import (
+	"context"
 	errorssyslib "errors"
 	"fmt"
 	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/plugin"
 	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfdata"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
@@ -36,6 +39,15 @@ import (
 
 var ErrDuplicateAsset = errors.New("duplicate asset")
 
+// DefaultConcurrency is the number of resource changes converted at the same
+// time unless overridden with SetConcurrency.
+const DefaultConcurrency = 16
+
+const (
+	projectAssetType       = "cloudresourcemanager.googleapis.com/Project"
+	projectAssetNamePrefix = "//cloudresourcemanager.googleapis.com/projects/"
+)
+
 // Asset contains the resource data and metadata in the same format as
 // Google CAI (Cloud Asset Inventory).
 type Asset struct {
@@ -45,25 +57,55 @@ type Asset struct {
 	IAMPolicy     *IAMPolicy       `json:"iam_policy,omitempty"`
 	OrgPolicy     []*OrgPolicy     `json:"org_policy,omitempty"`
 	V2OrgPolicies []*V2OrgPolicies `json:"v2_org_policies,omitempty"`
+	// At most one of the access_context_policy payloads is set, for VPC
+	// Service Controls assets.
+	AccessPolicy     AccessContextPolicy `json:"access_policy,omitempty"`
+	AccessLevel      AccessContextPolicy `json:"access_level,omitempty"`
+	ServicePerimeter AccessContextPolicy `json:"service_perimeter,omitempty"`
 
 	// Store the converter's version of the asset to allow for merges which
 	// operate on this type. When matching json tags land in the conversions
 	// library, this could be nested to avoid the duplication of fields.
 	converterAsset resources.Asset
 	Ancestors      []string `json:"ancestors"`
+
+	// GrantAddresses maps the grants of IAMPolicy to the addresses of the
+	// Terraform resources making them. Grants that only come from the remote
+	// policy are missing. It is not part of the CAI format.
+	GrantAddresses map[IAMGrant][]string `json:"-"`
 }
 
 // IAMPolicy is the representation of a Cloud IAM policy set on a cloud resource.
 type IAMPolicy struct {
-	Bindings []IAMBinding `json:"bindings"`
+	Bindings     []IAMBinding     `json:"bindings,omitempty"`
+	AuditConfigs []IAMAuditConfig `json:"audit_configs,omitempty"`
+}
+
+// IAMAuditConfig configures audit logging of a service, or of all services if
+// Service is "allServices".
+type IAMAuditConfig struct {
+	Service         string              `json:"service"`
+	AuditLogConfigs []IAMAuditLogConfig `json:"audit_log_configs,omitempty"`
+}
+
+// IAMAuditLogConfig configures the logging of a type of permission.
+type IAMAuditLogConfig struct {
+	LogType         string   `json:"log_type"`
+	ExemptedMembers []string `json:"exempted_members,omitempty"`
 }
 
 // IAMBinding binds a role to a set of members.
 type IAMBinding struct {
 	Role    string   `json:"role"`
 	Members []string `json:"members"`
+	// Condition is nil for unconditional bindings.
+	Condition *Expr `json:"condition,omitempty"`
 }
 
+// AccessContextPolicy is an access policy, access level or service perimeter
+// in the JSON format of the Access Context Manager API.
+type AccessContextPolicy map[string]interface{}
+
 // AssetResource is nested within the Asset type.
 type AssetResource struct {
 	Version              string                 `json:"version"`
@@ -141,6 +183,13 @@ func (t *Timestamp) UnmarshalJSON(b []byte) error {
 // `denied_values`.
 type ListPolicyAllValues int32
 
+// Values of ListPolicy.AllValues, as in the Cloud Resource Manager v1 API.
+const (
+	ListPolicyAllValuesUnspecified ListPolicyAllValues = 0
+	ListPolicyAllValuesAllow       ListPolicyAllValues = 1
+	ListPolicyAllValuesDeny        ListPolicyAllValues = 2
+)
+
 // ListPolicy can define specific values and subtrees of Cloud Resource
 // Manager resource hierarchy (`Organizations`, `Folders`, `Projects`) that
 // are allowed or denied by setting the `allowed_values` and `denied_values`
@@ -164,17 +213,35 @@ type BooleanPolicy struct {
 type RestoreDefault struct {
 }
 
+var (
+	providerSchemaOnce sync.Once
+	providerSchemaVal  *schema.Provider
+)
+
+// providerSchema returns the schema of the google provider. Building it is
+// expensive, so it is built once and shared by every Converter, which only
+// read it.
+func providerSchema() *schema.Provider {
+	providerSchemaOnce.Do(func() {
+		providerSchemaVal = provider.Provider()
+	})
+	return providerSchemaVal
+}
+
 // NewConverter is a factory function for Converter.
 func NewConverter(cfg *resources.Config, ancestryManager ancestrymanager.AncestryManager, offline bool, convertUnchanged bool, errorLogger *zap.Logger) *Converter {
 	return &Converter{
-		schema:           provider.Provider(),
-		converters:       resources.ResourceConverters(),
+		schema:           providerSchema(),
+		converters:       resources.AllResourceConverters(),
 		offline:          offline,
 		cfg:              cfg,
 		ancestryManager:  ancestryManager,
 		assets:           make(map[string]Asset),
+		grantAddresses:   make(map[string]map[IAMGrant][]string),
 		convertUnchanged: convertUnchanged,
 		errorLogger:      errorLogger,
+		concurrency:      DefaultConcurrency,
+		ctx:              context.Background(),
 	}
 }
 
@@ -196,11 +263,51 @@ type Converter struct {
 	// Map of converted assets (key = asset.Type + asset.Name)
 	assets map[string]Asset
 
+	// Addresses of the Terraform resources making the IAM grants of the
+	// converted assets, keyed like assets.
+	grantAddresses map[string]map[IAMGrant][]string
+
 	// When set, Converter will convert ResourceChanges with no-op "actions".
 	convertUnchanged bool
 
 	// For logging error / status information that doesn't warrant an outright failure
 	errorLogger *zap.Logger
+
+	// Maximum number of concurrent conversions, fetches and ancestry lookups.
+	concurrency int
+
+	// Remote assets fetched during the current call to AddResourceChanges.
+	fetches *fetchCache
+
+	// Context of the current call to AddResourceChanges.
+	ctx context.Context
+
+	// When set, assets carry their organization policies in both the v1 and
+	// v2 formats.
+	normalizeOrgPolicies bool
+
+	// Map terraform resource kinds converted by external plugins to their
+	// plugin.
+	plugins map[string]*plugin.Plugin
+
+	// When set, project assets addressed by project ID are named by project
+	// number (see SetMergeProjectAliases).
+	mergeProjectAliases bool
+}
+
+// SetConcurrency sets the maximum number of resource changes that are
+// converted, fetched or looked up at the same time. Values lower than 1 make
+// conversion sequential.
+func (c *Converter) SetConcurrency(n int) {
+	c.concurrency = n
+}
+
+// SetMergeProjectAliases sets whether project assets addressed by project ID
+// are renamed to use the project number, so that they merge with the assets
+// of the same project addressed by number. The number is found by the
+// ancestry manager, which may call Google API for each project once.
+func (c *Converter) SetMergeProjectAliases(merge bool) {
+	c.mergeProjectAliases = merge
 }
 
 // AddResourceChange processes the resource changes in two stages:
@@ -209,46 +316,61 @@ type Converter struct {
 // This will give us a deterministic end result even in cases where for example
 // an IAM Binding and Member conflict with each other, but one is replacing the
 // other.
+//
+// Conversion, remote fetches and ancestry lookups run concurrently (see
+// SetConcurrency), while merges are applied in plan order so that the result
+// is the same as converting the changes one by one.
 func (c *Converter) AddResourceChanges(changes []*tfjson.ResourceChange) error {
-	var createOrUpdateOrNoops []*tfjson.ResourceChange
+	return c.AddResourceChangesWithContext(context.Background(), changes)
+}
+
+// AddResourceChangesWithContext is like AddResourceChanges, but stops
+// converting and returns the error of ctx once ctx is done. API calls use the
+// context of the Config of the Converter.
+func (c *Converter) AddResourceChangesWithContext(ctx context.Context, changes []*tfjson.ResourceChange) error {
+	var deletes, createOrUpdateOrNoops []*tfjson.ResourceChange
 	for _, rc := range changes {
-		// Silently skip non-google resources
-		if !strings.HasPrefix(rc.Type, "google_") {
+		if _, ok := c.plugins[rc.Type]; !ok && !c.isConvertible(rc) {
 			continue
 		}
 
-		// Warn about google-beta resources
-		if rc.ProviderName == "registry.terraform.io/hashicorp/google-beta" {
-			c.errorLogger.Debug(fmt.Sprintf("%s: resource uses the google-beta provider and may not be convertible", rc.Address))
+		if tfplan.IsCreate(rc) || tfplan.IsUpdate(rc) || tfplan.IsDeleteCreate(rc) || (c.convertUnchanged && tfplan.IsNoOp(rc)) {
+			createOrUpdateOrNoops = append(createOrUpdateOrNoops, rc)
+		} else if tfplan.IsDelete(rc) {
+			deletes = append(deletes, rc)
 		}
+	}
 
-		// Skip resources not found in the google GA provider's schema
-		if _, ok := c.schema.ResourcesMap[rc.Type]; !ok {
-			c.errorLogger.Debug(fmt.Sprintf("%s: resource type not found in google GA provider: %s.", rc.Address, rc.Type))
-			continue
+	c.ctx = ctx
+	c.fetches = newFetchCache()
+	defer func() {
+		if hits, misses := c.fetches.stats(); hits+misses > 0 {
+			c.errorLogger.Debug(fmt.Sprintf("Fetched remote assets: %d cache hits, %d cache misses", hits, misses))
 		}
+	}()
 
-		// Skip unsupported resources
-		if _, ok := c.converters[rc.Type]; !ok {
-			c.errorLogger.Debug(fmt.Sprintf("%s: resource type cannot be converted for CAI-based policies: %s. For details, see https://cloud.google.com/docs/terraform/policy-validation/create-cai-constraints#supported_resources", rc.Address, rc.Type))
-			continue
-		}
+	deleted := c.convertChanges(deletes, true)
+	createdOrUpdatedOrNoops := c.convertChanges(createOrUpdateOrNoops, false)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.prefetch(deleted, createdOrUpdatedOrNoops)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		if tfplan.IsCreate(rc) || tfplan.IsUpdate(rc) || tfplan.IsDeleteCreate(rc) || (c.convertUnchanged && tfplan.IsNoOp(rc)) {
-			createOrUpdateOrNoops = append(createOrUpdateOrNoops, rc)
-		} else if tfplan.IsDelete(rc) {
-			if err := c.addDelete(rc); err != nil {
-				return fmt.Errorf("%s: converting deleted TF resource to CAI: %w", rc.Address, err)
-			}
+	for _, change := range deleted {
+		if err := c.addDelete(change); err != nil {
+			return fmt.Errorf("%s: converting deleted TF resource to CAI: %w", change.rc.Address, err)
 		}
 	}
 
-	for _, rc := range createOrUpdateOrNoops {
-		if err := c.addCreateOrUpdateOrNoop(rc); err != nil {
+	for _, change := range createdOrUpdatedOrNoops {
+		if err := c.addCreateOrUpdateOrNoop(change); err != nil {
 			if errorssyslib.Is(err, ErrDuplicateAsset) {
-				c.errorLogger.Warn(fmt.Sprintf("%s: converting TF resource to CAI: %v", rc.Address, err))
+				c.errorLogger.Warn(fmt.Sprintf("%s: converting TF resource to CAI: %v", change.rc.Address, err))
 			} else {
-				return fmt.Errorf("%s: converting TF resource to CAI: %w", rc.Address, err)
+				return fmt.Errorf("%s: converting TF resource to CAI: %w", change.rc.Address, err)
 			}
 		}
 	}
@@ -256,37 +378,220 @@ func (c *Converter) AddResourceChanges(changes []*tfjson.ResourceChange) error {
 	return nil
 }
 
+// isConvertible reports whether a resource change has a built-in converter,
+// logging why it is skipped otherwise.
+func (c *Converter) isConvertible(rc *tfjson.ResourceChange) bool {
+	// Silently skip non-google resources
+	if !strings.HasPrefix(rc.Type, "google_") {
+		return false
+	}
+
+	// Warn about google-beta resources
+	if rc.ProviderName == "registry.terraform.io/hashicorp/google-beta" {
+		c.errorLogger.Debug(fmt.Sprintf("%s: resource uses the google-beta provider and may not be convertible", rc.Address))
+	}
+
+	// Skip resources not found in the google GA provider's schema
+	if _, ok := c.schema.ResourcesMap[rc.Type]; !ok {
+		c.errorLogger.Debug(fmt.Sprintf("%s: resource type not found in google GA provider: %s.", rc.Address, rc.Type))
+		return false
+	}
+
+	// Skip unsupported resources
+	if _, ok := c.converters[rc.Type]; !ok {
+		c.errorLogger.Debug(fmt.Sprintf("%s: resource type cannot be converted for CAI-based policies: %s. For details, see https://cloud.google.com/docs/terraform/policy-validation/create-cai-constraints#supported_resources", rc.Address, rc.Type))
+		return false
+	}
+	return true
+}
+
+// convertedChange holds a resource change together with the output of each
+// of its ResourceConverters, in converter order.
+type convertedChange struct {
+	rc          *tfjson.ResourceChange
+	rd          resources.TerraformResourceData
+	conversions []*conversion
+}
+
+// conversion is the result of running a single ResourceConverter.
+type conversion struct {
+	converter resources.ResourceConverter
+	assets    []resources.Asset
+	err       error
+}
+
+// convertChanges runs the ResourceConverters for each change concurrently.
+// For deletions, only ResourceConverters that support both fetch and
+// mergeDelete are run.
+func (c *Converter) convertChanges(changes []*tfjson.ResourceChange, deleted bool) []*convertedChange {
+	converted := make([]*convertedChange, len(changes))
+	c.forEach(len(changes), func(i int) {
+		rc := changes[i]
+		values := rc.Change.After
+		if deleted {
+			values = rc.Change.Before
+		}
+		var rd *tfdata.FakeResourceData
+		converters := c.converters[rc.Type]
+		if p, ok := c.plugins[rc.Type]; ok {
+			rd = tfdata.NewFakeResourceData(rc.Type, pluginSchema(values.(map[string]interface{})), values.(map[string]interface{}))
+			converters = []resources.ResourceConverter{c.pluginConverter(p, rc, values.(map[string]interface{}))}
+		} else {
+			rd = tfdata.NewFakeResourceData(
+				rc.Type,
+				c.schema.ResourcesMap[rc.Type].Schema,
+				values.(map[string]interface{}),
+			)
+		}
+		change := &convertedChange{rc: rc, rd: rd}
+		for _, converter := range converters {
+			if deleted && (converter.FetchFullResource == nil || converter.MergeDelete == nil) {
+				continue
+			}
+			assets, err := convertWrapper(converter, rd, c.cfg)
+			for i := range assets {
+				c.canonicalizeProjectAsset(&assets[i])
+			}
+			conv := &conversion{converter: converter, assets: assets, err: err}
+			change.conversions = append(change.conversions, conv)
+		}
+		converted[i] = change
+	})
+	return converted
+}
+
+// prefetch fetches remote assets and warms up the ancestry lookups that the
+// merge of deleted and then createdOrUpdatedOrNoops will need, so that the
+// network calls happen concurrently. Only the assets the merge would fetch
+// are prefetched: assets that an earlier change already added are merged
+// with it instead. Fetched assets are kept in c.fetches for the merge to use.
+func (c *Converter) prefetch(deleted, createdOrUpdatedOrNoops []*convertedChange) {
+	if c.offline {
+		return
+	}
+
+	type task struct {
+		rd        resources.TerraformResourceData
+		converter resources.ResourceConverter
+		asset     resources.Asset
+		// fetch is set if the merge fetches the remote asset.
+		fetch bool
+	}
+	var tasks []task
+	merged := make(map[string]bool, len(c.assets))
+	for key := range c.assets {
+		merged[key] = true
+	}
+	addTasks := func(changes []*convertedChange, isDelete bool) {
+		for _, change := range changes {
+			for _, conv := range change.conversions {
+				if conv.err != nil {
+					continue
+				}
+				for _, asset := range conv.assets {
+					key := asset.Type + asset.Name
+					fetch := !merged[key] && conv.converter.FetchFullResource != nil
+					merged[key] = true
+					// Deleted assets are only merged, and their ancestry
+					// looked up, if they are fetched.
+					if fetch || !isDelete {
+						tasks = append(tasks, task{rd: change.rd, converter: conv.converter, asset: asset, fetch: fetch})
+					}
+				}
+			}
+		}
+	}
+	addTasks(deleted, true)
+	addTasks(createdOrUpdatedOrNoops, false)
+
+	// Errors are ignored here; they are reported when the asset is merged.
+	c.forEach(len(tasks), func(i int) {
+		t := tasks[i]
+		if t.fetch {
+			c.fetches.prefetch(t.asset.Type+t.asset.Name, c.fullResourceFetcher(t.converter, t.rd))
+		}
+		c.ancestryManager.Ancestors(c.cfg, t.rd, &t.asset)
+	})
+}
+
+// forEach calls f for each index in [0, n), running at most c.concurrency
+// calls at a time. Once the context of c is done, f is no longer called.
+func (c *Converter) forEach(n int, f func(i int)) {
+	limit := c.concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n && c.ctx.Err() == nil; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// fetchFullResource fetches the remote version of converted. Remote assets are
+// fetched once per asset type and name, whichever converter asks for them.
+func (c *Converter) fetchFullResource(converter resources.ResourceConverter, rd resources.TerraformResourceData, converted resources.Asset) (resources.Asset, error) {
+	return c.fetches.fetch(converted.Type+converted.Name, c.fullResourceFetcher(converter, rd))
+}
+
+// fullResourceFetcher returns the function fetching the remote version of
+// the assets of rd.
+func (c *Converter) fullResourceFetcher(converter resources.ResourceConverter, rd resources.TerraformResourceData) func() (resources.Asset, error) {
+	return func() (resources.Asset, error) {
+		asset, err := converter.FetchFullResource(rd, c.cfg)
+		if err == nil {
+			c.canonicalizeProjectAsset(&asset)
+		}
+		return asset, err
+	}
+}
+
+// canonicalizeProjectAsset renames a project asset addressed by project ID to
+// use the project number when the ancestry manager can find it, so that the
+// assets of a project merge whether they use its ID or its number.
+func (c *Converter) canonicalizeProjectAsset(asset *resources.Asset) {
+	if !c.mergeProjectAliases || asset.Type != projectAssetType || !strings.HasPrefix(asset.Name, projectAssetNamePrefix) {
+		return
+	}
+	resolver, ok := c.ancestryManager.(ancestrymanager.ProjectAliasResolver)
+	if !ok {
+		return
+	}
+	project := strings.TrimPrefix(asset.Name, projectAssetNamePrefix)
+	asset.Name = projectAssetNamePrefix + strings.TrimPrefix(resolver.CanonicalProject("projects/"+project), "projects/")
+}
+
 // For deletions, we only need to handle ResourceConverters that support
 // both fetch and mergeDelete. Supporting just one doesn't
 // make sense, and supporting neither means that the deletion
 // can just happen without needing to be merged.
-func (c *Converter) addDelete(rc *tfjson.ResourceChange) error {
-	resource := c.schema.ResourcesMap[rc.Type]
-	rd := tfdata.NewFakeResourceData(
-		rc.Type,
-		resource.Schema,
-		rc.Change.Before.(map[string]interface{}),
-	)
-	for _, converter := range c.converters[rd.Kind()] {
-		if converter.FetchFullResource == nil || converter.MergeDelete == nil {
-			continue
-		}
-		convertedItems, err := convertWrapper(converter, rd, c.cfg)
-		if err != nil {
-			if errors.Cause(err) == resources.ErrNoConversion {
+func (c *Converter) addDelete(change *convertedChange) error {
+	rc, rd := change.rc, change.rd
+	for _, conv := range change.conversions {
+		converter := conv.converter
+		if conv.err != nil {
+			if errors.Cause(conv.err) == resources.ErrNoConversion {
 				continue
 			}
-			return err
+			return conv.err
 		}
 
-		for _, converted := range convertedItems {
-
+		for _, converted := range conv.assets {
 			key := converted.Type + converted.Name
 			var existingConverterAsset *resources.Asset
 			if existing, exists := c.assets[key]; exists {
 				existingConverterAsset = &existing.converterAsset
 			} else if !c.offline {
-				asset, err := converter.FetchFullResource(rd, c.cfg)
+				asset, err := c.fetchFullResource(converter, rd, converted)
 				if errors.Cause(err) == resources.ErrEmptyIdentityField {
 					c.errorLogger.Debug(fmt.Sprintf("%s: Unable to fetch and merge remote %s asset due to unset or (known after apply) identity fields on the TF resource.", rc.Address, converted.Type))
 					existingConverterAsset = nil
@@ -316,31 +621,26 @@ func (c *Converter) addDelete(rc *tfjson.ResourceChange) error {
 // For create/update/no-op, we need to handle both the case of no merging,
 // and the case of merging. If merging, we expect both fetch and mergeCreateUpdate
 // to be present.
-func (c *Converter) addCreateOrUpdateOrNoop(rc *tfjson.ResourceChange) error {
-	resource := c.schema.ResourcesMap[rc.Type]
-	rd := tfdata.NewFakeResourceData(
-		rc.Type,
-		resource.Schema,
-		rc.Change.After.(map[string]interface{}),
-	)
-
-	for _, converter := range c.converters[rd.Kind()] {
-		convertedAssets, err := convertWrapper(converter, rd, c.cfg)
-		if err != nil {
-			if errors.Cause(err) == resources.ErrNoConversion {
+func (c *Converter) addCreateOrUpdateOrNoop(change *convertedChange) error {
+	rc, rd := change.rc, change.rd
+	for _, conv := range change.conversions {
+		converter := conv.converter
+		if conv.err != nil {
+			if errors.Cause(conv.err) == resources.ErrNoConversion {
 				continue
 			}
-			return err
+			return conv.err
 		}
 
-		for _, converted := range convertedAssets {
+		for _, converted := range conv.assets {
 			key := converted.Type + converted.Name
+			c.recordGrantAddresses(key, rc.Address, converted)
 
 			var existingConverterAsset *resources.Asset
 			if existing, exists := c.assets[key]; exists {
 				existingConverterAsset = &existing.converterAsset
 			} else if converter.FetchFullResource != nil && !c.offline {
-				asset, err := converter.FetchFullResource(rd, c.cfg)
+				asset, err := c.fetchFullResource(converter, rd, converted)
 				if errors.Cause(err) == resources.ErrEmptyIdentityField {
 					c.errorLogger.Debug(fmt.Sprintf("%s: Unable to fetch and merge remote %s asset due to unset or (known after apply) identity fields on the TF resource.", rc.Address, converted.Type))
 					existingConverterAsset = nil
@@ -374,16 +674,27 @@ func (c *Converter) addCreateOrUpdateOrNoop(rc *tfjson.ResourceChange) error {
 	return nil
 }
 
+// byName sorts assets by name, and by type for assets sharing a name, so
+// that the order does not depend on map iteration.
 type byName []Asset
 
-func (s byName) Len() int           { return len(s) }
-func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
-func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Len() int { return len(s) }
+func (s byName) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Type < s[j].Type
+}
+func (s byName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
 // Assets lists all converted assets previously added by calls to AddResource.
 func (c *Converter) Assets() []Asset {
 	list := make([]Asset, 0, len(c.assets))
-	for _, a := range c.assets {
+	for key, a := range c.assets {
+		if c.normalizeOrgPolicies {
+			normalizeOrgPolicies(&a)
+		}
+		a.GrantAddresses = c.assetGrantAddresses(key, a)
 		list = append(list, a)
 	}
 	sort.Sort(byName(list))
@@ -412,11 +723,31 @@ func (c *Converter) augmentAsset(tfData resources.TerraformResourceData, cfg *re
 	if cai.IAMPolicy != nil {
 		policy = &IAMPolicy{}
 		for _, b := range cai.IAMPolicy.Bindings {
+			var condition *Expr
+			if b.Condition != nil {
+				condition = &Expr{
+					Expression:  b.Condition.Expression,
+					Title:       b.Condition.Title,
+					Description: b.Condition.Description,
+					Location:    b.Condition.Location,
+				}
+			}
 			policy.Bindings = append(policy.Bindings, IAMBinding{
-				Role:    b.Role,
-				Members: b.Members,
+				Role:      b.Role,
+				Members:   b.Members,
+				Condition: condition,
 			})
 		}
+		for _, ac := range cai.IAMPolicy.AuditConfigs {
+			auditConfig := IAMAuditConfig{Service: ac.Service}
+			for _, lc := range ac.AuditLogConfigs {
+				auditConfig.AuditLogConfigs = append(auditConfig.AuditLogConfigs, IAMAuditLogConfig{
+					LogType:         lc.LogType,
+					ExemptedMembers: lc.ExemptedMembers,
+				})
+			}
+			policy.AuditConfigs = append(policy.AuditConfigs, auditConfig)
+		}
 	}
 
 	var orgPolicy []*OrgPolicy
@@ -460,72 +791,77 @@ func (c *Converter) augmentAsset(tfData resources.TerraformResourceData, cfg *re
 	var v2OrgPolicies []*V2OrgPolicies
 	if cai.V2OrgPolicies != nil {
 		for _, o2 := range cai.V2OrgPolicies {
-			var spec *PolicySpec
-			if o2.PolicySpec != nil {
-
-				var rules []*PolicyRule
-				if o2.PolicySpec.PolicyRules != nil {
-					for _, rule := range o2.PolicySpec.PolicyRules {
-						var values *StringValues
-						if rule.Values != nil {
-							values = &StringValues{
-								AllowedValues: rule.Values.AllowedValues,
-								DeniedValues:  rule.Values.DeniedValues,
-							}
-						}
-
-						var condition *Expr
-						if rule.Condition != nil {
-							condition = &Expr{
-								Expression:  rule.Condition.Expression,
-								Title:       rule.Condition.Title,
-								Description: rule.Condition.Description,
-								Location:    rule.Condition.Location,
-							}
-						}
-						rules = append(rules, &PolicyRule{
-							Values:    values,
-							AllowAll:  rule.AllowAll,
-							DenyAll:   rule.DenyAll,
-							Enforce:   rule.Enforce,
-							Condition: condition,
-						})
-					}
-				}
-
-				fixedTime := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
-				spec = &PolicySpec{
-					Etag: o2.PolicySpec.Etag,
-					UpdateTime: &Timestamp{
-						Seconds: int64(fixedTime.Unix()),
-						Nanos:   int64(fixedTime.UnixNano()),
-					},
-					PolicyRules:       rules,
-					InheritFromParent: o2.PolicySpec.InheritFromParent,
-					Reset:             o2.PolicySpec.Reset,
-				}
-
-			}
-
 			v2OrgPolicies = append(v2OrgPolicies, &V2OrgPolicies{
 				Name:       o2.Name,
-				PolicySpec: spec,
+				PolicySpec: convertPolicySpec(o2.PolicySpec),
 			})
 		}
 	}
 
 	return Asset{
-		Name:           cai.Name,
-		Type:           cai.Type,
-		Resource:       resource,
-		IAMPolicy:      policy,
-		OrgPolicy:      orgPolicy,
-		V2OrgPolicies:  v2OrgPolicies,
-		converterAsset: cai,
-		Ancestors:      ancestors,
+		Name:             cai.Name,
+		Type:             cai.Type,
+		Resource:         resource,
+		IAMPolicy:        policy,
+		OrgPolicy:        orgPolicy,
+		V2OrgPolicies:    v2OrgPolicies,
+		AccessPolicy:     AccessContextPolicy(cai.AccessPolicy),
+		AccessLevel:      AccessContextPolicy(cai.AccessLevel),
+		ServicePerimeter: AccessContextPolicy(cai.ServicePerimeter),
+		converterAsset:   cai,
+		Ancestors:        ancestors,
 	}, nil
 }
 
+// convertPolicySpec converts the spec of a v2 org policy.
+func convertPolicySpec(spec *resources.PolicySpec) *PolicySpec {
+	if spec == nil {
+		return nil
+	}
+
+	var rules []*PolicyRule
+	if spec.PolicyRules != nil {
+		for _, rule := range spec.PolicyRules {
+			var values *StringValues
+			if rule.Values != nil {
+				values = &StringValues{
+					AllowedValues: rule.Values.AllowedValues,
+					DeniedValues:  rule.Values.DeniedValues,
+				}
+			}
+
+			var condition *Expr
+			if rule.Condition != nil {
+				condition = &Expr{
+					Expression:  rule.Condition.Expression,
+					Title:       rule.Condition.Title,
+					Description: rule.Condition.Description,
+					Location:    rule.Condition.Location,
+				}
+			}
+			rules = append(rules, &PolicyRule{
+				Values:    values,
+				AllowAll:  rule.AllowAll,
+				DenyAll:   rule.DenyAll,
+				Enforce:   rule.Enforce,
+				Condition: condition,
+			})
+		}
+	}
+
+	fixedTime := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
+	return &PolicySpec{
+		Etag: spec.Etag,
+		UpdateTime: &Timestamp{
+			Seconds: int64(fixedTime.Unix()),
+			Nanos:   int64(fixedTime.UnixNano()),
+		},
+		PolicyRules:       rules,
+		InheritFromParent: spec.InheritFromParent,
+		Reset:             spec.Reset,
+	}
+}
+
 func convertWrapper(conv resources.ResourceConverter, d resources.TerraformResourceData, config *resources.Config) (assets []resources.Asset, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -17,9 +17,14 @@ package google
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,6 +36,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
 )
 
 const testProject = "test-project"
@@ -57,7 +63,7 @@ func newTestErrorLogger() (*zap.Logger, *bytes.Buffer) {
 		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
 		EncodeDuration: zapcore.StringDurationEncoder,
 	}
-	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderCfg), syncer, zap.DebugLevel)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderCfg), zapcore.Lock(syncer), zap.DebugLevel)
 	return zap.New(core), syncer.Buffer
 }
 
@@ -75,6 +81,14 @@ func newTestConverter(convertUnchanged bool) (*Converter, *bytes.Buffer, error)
 	return c, buf, nil
 }
 
+func TestNewConverter_sharesProviderSchema(t *testing.T) {
+	c1, _, err := newTestConverter(false)
+	assert.Nil(t, err)
+	c2, _, err := newTestConverter(false)
+	assert.Nil(t, err)
+	assert.Same(t, c1.schema, c2.schema)
+}
+
 func TestSortByName(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -162,7 +176,8 @@ func TestAddResourceChanges_unsupportedResourceIgnored(t *testing.T) {
 	assert.Nil(t, err)
 
 	// fake that this resource is known to the provider; it will never be "supported" by the
-	// converter.
+	// converter. The schema is shared by converters, so modify a copy.
+	c.schema = provider.Provider()
 	c.schema.ResourcesMap[rc.Type] = c.schema.ResourcesMap["google_compute_disk"]
 
 	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
@@ -360,6 +375,37 @@ func TestAddResourceChanges_createOrUpdateOrDeleteCreateOrNoopProcessed(t *testi
 	}
 }
 
+func TestAddResourceChangesWithContext_canceled(t *testing.T) {
+	rc := tfjson.ResourceChange{
+		Address:      "whatever.google_compute_disk.foo",
+		Mode:         "managed",
+		Type:         "google_compute_disk",
+		Name:         "foo",
+		ProviderName: "google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"project": testProject,
+				"name":    "test-disk",
+				"zone":    "us-central1-a",
+			},
+		},
+	}
+	c, _, err := newTestConverter(false)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.AddResourceChangesWithContext(ctx, []*tfjson.ResourceChange{&rc})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, c.assets)
+
+	// The converter can be used again with another context.
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
+	assert.Nil(t, err)
+	assert.Len(t, c.assets, 1)
+}
+
 func TestAddDuplicatedResources(t *testing.T) {
 	rcb1 := tfjson.ResourceChange{
 		Address:      "google_billing_budget.budget1",
@@ -544,6 +590,19 @@ func TestAddStorageModuleAfterUnknown(t *testing.T) {
 	assert.Equal(t, "", buf.String())
 }
 
+func TestIAMPolicyMarshalJSON_auditConfigsOnly(t *testing.T) {
+	policy := IAMPolicy{
+		AuditConfigs: []IAMAuditConfig{
+			{Service: "allServices", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "ADMIN_READ"}}},
+		},
+	}
+	got, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.JSONEq(t, `{"audit_configs": [{"service": "allServices", "audit_log_configs": [{"log_type": "ADMIN_READ"}]}]}`, string(got))
+}
+
 func TestTimestampMarshalJSON(t *testing.T) {
 	expectedJSON := []byte("\"2021-04-14T15:16:17Z\"")
 	date := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
@@ -635,3 +694,504 @@ func TestConvertWrapper(t *testing.T) {
 	}
 
 }
+
+// TestAddResourceChanges_concurrentMatchesSequential is meant to be run with
+// the race detector (make test-race). It converts IAM resources in online mode
+// against a fake resource manager server and expects the same assets whatever
+// the concurrency.
+const testConditionExpression = `request.time < timestamp("2030-01-01T00:00:00Z")`
+
+// newTestResourceManagerServer returns a fake resource manager API server
+// answering getIamPolicy and getAncestry requests for any project, and the
+// number of getIamPolicy requests it received.
+func newTestResourceManagerServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var getIamPolicyCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload []byte
+		var err error
+		switch name := path.Base(r.URL.Path); {
+		case strings.HasSuffix(name, ":getIamPolicy"):
+			atomic.AddInt32(&getIamPolicyCount, 1)
+			payload, err = (&crmv1.Policy{
+				Bindings: []*crmv1.Binding{
+					{Role: "roles/viewer", Members: []string{"user:existing@example.com"}},
+					{
+						Role:      "roles/viewer",
+						Members:   []string{"user:temporary@example.com"},
+						Condition: &crmv1.Expr{Title: "expires", Expression: testConditionExpression},
+					},
+				},
+				AuditConfigs: []*crmv1.AuditConfig{
+					{
+						Service:         "storage.googleapis.com",
+						AuditLogConfigs: []*crmv1.AuditLogConfig{{LogType: "DATA_READ"}},
+					},
+					{
+						Service:         "allServices",
+						AuditLogConfigs: []*crmv1.AuditLogConfig{{LogType: "ADMIN_READ"}},
+					},
+				},
+			}).MarshalJSON()
+		case strings.HasSuffix(name, ":getAncestry"):
+			payload, err = (&crmv1.GetAncestryResponse{
+				Ancestor: []*crmv1.Ancestor{
+					{ResourceId: &crmv1.ResourceId{Id: strings.TrimSuffix(name, ":getAncestry"), Type: "project"}},
+					{ResourceId: &crmv1.ResourceId{Id: "456", Type: "folder"}},
+					{ResourceId: &crmv1.ResourceId{Id: "123", Type: "organization"}},
+				},
+			}).MarshalJSON()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf("failed to MarshalJSON: %s", err)))
+			return
+		}
+		w.Write(payload)
+	}))
+	return server, &getIamPolicyCount
+}
+
+func newOnlineTestConverter(t *testing.T, server *httptest.Server) (*Converter, *bytes.Buffer) {
+	t.Helper()
+	cfg := resources.NewTestConfig(server)
+	cfg.Project = testProject
+	errorLogger, buf := newTestErrorLogger()
+	ancestryManager, err := ancestrymanager.New(cfg, false, nil, errorLogger)
+	if err != nil {
+		t.Fatalf("building ancestry manager: %s", err)
+	}
+	return NewConverter(cfg, ancestryManager, false, false, errorLogger), buf
+}
+
+func TestAddResourceChanges_concurrentMatchesSequential(t *testing.T) {
+	server, _ := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	newChanges := func() []*tfjson.ResourceChange {
+		var changes []*tfjson.ResourceChange
+		for i := 0; i < 40; i++ {
+			changes = append(changes, &tfjson.ResourceChange{
+				Address:      fmt.Sprintf("google_project_iam_member.member[%d]", i),
+				Mode:         "managed",
+				Type:         "google_project_iam_member",
+				Name:         "member",
+				Index:        i,
+				ProviderName: "google",
+				Change: &tfjson.Change{
+					Actions: tfjson.Actions{"create"},
+					After: map[string]interface{}{
+						"project": fmt.Sprintf("project-%d", i%4),
+						"role":    fmt.Sprintf("roles/role%d", i%3),
+						"member":  fmt.Sprintf("user:user%d@example.com", i),
+					},
+				},
+			})
+		}
+		changes = append(changes, &tfjson.ResourceChange{
+			Address:      "google_project_iam_binding.binding",
+			Mode:         "managed",
+			Type:         "google_project_iam_binding",
+			Name:         "binding",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"delete"},
+				Before: map[string]interface{}{
+					"project": "project-5",
+					"role":    "roles/viewer",
+					"members": []interface{}{"user:existing@example.com"},
+				},
+			},
+		})
+		return changes
+	}
+
+	convert := func(concurrency int) []byte {
+		c, _ := newOnlineTestConverter(t, server)
+		c.SetConcurrency(concurrency)
+		if err := c.AddResourceChanges(newChanges()); err != nil {
+			t.Fatalf("AddResourceChanges() = %s, want = nil", err)
+		}
+		got, err := json.Marshal(c.Assets())
+		if err != nil {
+			t.Fatalf("marshaling assets: %s", err)
+		}
+		return got
+	}
+
+	want := convert(1)
+	for i := 0; i < 5; i++ {
+		if got := convert(8); !bytes.Equal(want, got) {
+			t.Errorf("concurrent conversion differs from sequential conversion:\nwant: %s\ngot:  %s", want, got)
+		}
+	}
+}
+
+func TestAddResourceChanges_fetchesOncePerAsset(t *testing.T) {
+	server, getIamPolicyCount := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "google_project_iam_member.deleted",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "deleted",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"delete"},
+				Before: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/viewer",
+					"member":  "user:existing@example.com",
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_member.member",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "member",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/editor",
+					"member":  "user:member@example.com",
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_binding.binding",
+			Mode:         "managed",
+			Type:         "google_project_iam_binding",
+			Name:         "binding",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/owner",
+					"members": []interface{}{"user:owner@example.com"},
+				},
+			},
+		},
+	}
+
+	c, buf := newOnlineTestConverter(t, server)
+	err := c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(getIamPolicyCount))
+	assert.Contains(t, buf.String(), "cache hits")
+
+	caiKey := "cloudresourcemanager.googleapis.com/Project//cloudresourcemanager.googleapis.com/projects/test-project"
+	assert.Contains(t, c.assets, caiKey)
+	assert.ElementsMatch(t, []IAMBinding{
+		{Role: "roles/editor", Members: []string{"user:member@example.com"}},
+		{Role: "roles/owner", Members: []string{"user:owner@example.com"}},
+		{
+			Role:      "roles/viewer",
+			Members:   []string{"user:temporary@example.com"},
+			Condition: &Expr{Title: "expires", Expression: testConditionExpression},
+		},
+	}, c.assets[caiKey].IAMPolicy.Bindings)
+}
+
+func TestAddResourceChanges_prefetchesOnlyFetchedAssets(t *testing.T) {
+	server, getIamPolicyCount := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	member := func(name, project string, actions tfjson.Actions) *tfjson.ResourceChange {
+		values := map[string]interface{}{
+			"project": project,
+			"role":    "roles/editor",
+			"member":  "user:" + name + "@example.com",
+		}
+		return &tfjson.ResourceChange{
+			Address:      "google_project_iam_member." + name,
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         name,
+			ProviderName: "google",
+			Change:       &tfjson.Change{Actions: actions, Before: values, After: values},
+		}
+	}
+
+	c, _ := newOnlineTestConverter(t, server)
+	err := c.AddResourceChanges([]*tfjson.ResourceChange{member("first", testProject, tfjson.Actions{"create"})})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(getIamPolicyCount))
+
+	// The project was merged by the previous call, and no-op changes are not
+	// merged when convertUnchanged is false, so nothing is fetched.
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		member("second", testProject, tfjson.Actions{"create"}),
+		member("unchanged", "other-project", tfjson.Actions{"no-op"}),
+	})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(getIamPolicyCount))
+	assert.Len(t, c.assets, 1)
+}
+
+func TestAddResourceChanges_conditionalBindings(t *testing.T) {
+	server, _ := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	condition := []interface{}{
+		map[string]interface{}{
+			"title":       "expires",
+			"description": "",
+			"expression":  testConditionExpression,
+		},
+	}
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "google_project_iam_member.conditional",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "conditional",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project":   testProject,
+					"role":      "roles/viewer",
+					"member":    "user:member@example.com",
+					"condition": condition,
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_binding.unconditional",
+			Mode:         "managed",
+			Type:         "google_project_iam_binding",
+			Name:         "unconditional",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"delete"},
+				Before: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/viewer",
+					"members": []interface{}{"user:existing@example.com"},
+				},
+			},
+		},
+	}
+
+	c, _ := newOnlineTestConverter(t, server)
+	err := c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	caiKey := "cloudresourcemanager.googleapis.com/Project//cloudresourcemanager.googleapis.com/projects/test-project"
+	assert.Contains(t, c.assets, caiKey)
+	assert.ElementsMatch(t, []IAMBinding{
+		{
+			Role:      "roles/viewer",
+			Members:   []string{"user:member@example.com", "user:temporary@example.com"},
+			Condition: &Expr{Title: "expires", Expression: testConditionExpression},
+		},
+	}, c.assets[caiKey].IAMPolicy.Bindings)
+}
+
+func TestAddResourceChanges_auditConfigs(t *testing.T) {
+	server, _ := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "google_project_iam_audit_config.all",
+			Mode:         "managed",
+			Type:         "google_project_iam_audit_config",
+			Name:         "all",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"service": "allServices",
+					"audit_log_config": []interface{}{
+						map[string]interface{}{
+							"log_type":         "DATA_WRITE",
+							"exempted_members": []interface{}{"user:exempt@example.com"},
+						},
+					},
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_audit_config.storage",
+			Mode:         "managed",
+			Type:         "google_project_iam_audit_config",
+			Name:         "storage",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"delete"},
+				Before: map[string]interface{}{
+					"project": testProject,
+					"service": "storage.googleapis.com",
+					"audit_log_config": []interface{}{
+						map[string]interface{}{"log_type": "DATA_READ"},
+					},
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_member.member",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "member",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/editor",
+					"member":  "user:member@example.com",
+				},
+			},
+		},
+	}
+
+	c, _ := newOnlineTestConverter(t, server)
+	err := c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	caiKey := "cloudresourcemanager.googleapis.com/Project//cloudresourcemanager.googleapis.com/projects/test-project"
+	assert.Contains(t, c.assets, caiKey)
+	assert.Equal(t, []IAMAuditConfig{
+		{
+			Service: "allServices",
+			AuditLogConfigs: []IAMAuditLogConfig{
+				{LogType: "DATA_WRITE", ExemptedMembers: []string{"user:exempt@example.com"}},
+			},
+		},
+	}, c.assets[caiKey].IAMPolicy.AuditConfigs)
+	assert.Len(t, c.assets[caiKey].IAMPolicy.Bindings, 3)
+}
+
+func TestAddResourceChanges_projectAliases(t *testing.T) {
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "google_project.project",
+			Mode:         "managed",
+			Type:         "google_project",
+			Name:         "project",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"update"},
+				After: map[string]interface{}{
+					"project_id": testProject,
+					"number":     "12345",
+					"name":       "Test Project",
+					"org_id":     "123",
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_member.member",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "member",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/editor",
+					"member":  "user:member@example.com",
+				},
+			},
+		},
+	}
+
+	cfg, err := resources.NewConfig(context.Background(), testProject, "", "", true, "", nil)
+	if err != nil {
+		t.Fatalf("constructing configuration: %s", err)
+	}
+	errorLogger, _ := newTestErrorLogger()
+	ancestryManager, err := ancestrymanager.NewWithOptions(cfg, true, map[string]string{
+		testProject: "organizations/123/projects/" + testProject,
+		"12345":     "organizations/123/projects/12345",
+	}, errorLogger, ancestrymanager.Options{ProjectNumbers: map[string]string{testProject: "12345"}})
+	if err != nil {
+		t.Fatalf("building ancestry manager: %s", err)
+	}
+	c := NewConverter(cfg, ancestryManager, true, false, errorLogger)
+	err = c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	// Project aliases are only merged on request.
+	assets := c.Assets()
+	assert.Len(t, assets, 2)
+
+	c = NewConverter(cfg, ancestryManager, true, false, errorLogger)
+	c.SetMergeProjectAliases(true)
+	err = c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	assets = c.Assets()
+	assert.Len(t, assets, 1)
+	assert.Equal(t, "//cloudresourcemanager.googleapis.com/projects/12345", assets[0].Name)
+	assert.NotNil(t, assets[0].Resource)
+	assert.Equal(t, []IAMBinding{
+		{Role: "roles/editor", Members: []string{"user:member@example.com"}},
+	}, assets[0].IAMPolicy.Bindings)
+}
+
+func TestAddResourceChanges_grantAddresses(t *testing.T) {
+	server, _ := newTestResourceManagerServer(t)
+	defer server.Close()
+
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "google_project_iam_member.owner",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "owner",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/owner",
+					"member":  "user:owner@example.com",
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_binding.owners",
+			Mode:         "managed",
+			Type:         "google_project_iam_binding",
+			Name:         "owners",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/owner",
+					"members": []interface{}{"user:owner@example.com", "group:admins@example.com"},
+				},
+			},
+		},
+	}
+
+	c, _ := newOnlineTestConverter(t, server)
+	err := c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	var project Asset
+	for _, a := range c.Assets() {
+		if a.Type == "cloudresourcemanager.googleapis.com/Project" {
+			project = a
+		}
+	}
+	assert.Equal(t, map[IAMGrant][]string{
+		{Role: "roles/owner", Member: "user:owner@example.com"}:   {"google_project_iam_member.owner", "google_project_iam_binding.owners"},
+		{Role: "roles/owner", Member: "group:admins@example.com"}: {"google_project_iam_binding.owners"},
+	}, project.GrantAddresses)
+}
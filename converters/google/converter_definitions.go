@@ -0,0 +1,24 @@
+package google
+
+import (
+	"fmt"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+)
+
+// SetConverterDefinitions adds declarative converters (see
+// resources.ReadConverterDefinitions). The converter of a definition replaces
+// the built-in converters of its resource type, which must be a resource of
+// the google provider.
+func (c *Converter) SetConverterDefinitions(defs []*resources.ConverterDefinition) error {
+	for _, def := range defs {
+		if _, ok := c.schema.ResourcesMap[def.ResourceType]; !ok {
+			return fmt.Errorf("converter definition: resource type not found in google GA provider: %s", def.ResourceType)
+		}
+		if _, ok := c.converters[def.ResourceType]; ok {
+			c.errorLogger.Debug(fmt.Sprintf("Converter definition replaces the built-in converters of %s", def.ResourceType))
+		}
+		c.converters[def.ResourceType] = []resources.ResourceConverter{def.ResourceConverter()}
+	}
+	return nil
+}
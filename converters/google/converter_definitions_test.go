@@ -0,0 +1,59 @@
+package google
+
+import (
+	"testing"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConverterDefinitions(t *testing.T) {
+	c, _, err := newTestConverter(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetConverterDefinitions([]*resources.ConverterDefinition{{
+		ResourceType: "google_pubsub_topic",
+		AssetType:    "pubsub.googleapis.com/Topic",
+		AssetName:    "//pubsub.googleapis.com/projects/{{project}}/topics/{{name}}",
+		Fields: []resources.FieldDefinition{
+			{Attribute: "labels", Field: "metadata.labels", Transform: resources.TransformLabels},
+		},
+	}})
+	assert.Nil(t, err)
+
+	changes := []*tfjson.ResourceChange{{
+		Address:      "google_pubsub_topic.topic",
+		Mode:         "managed",
+		Type:         "google_pubsub_topic",
+		Name:         "topic",
+		ProviderName: "google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"name":    "my-topic",
+				"project": testProject,
+				"labels":  map[string]interface{}{"env": "prod"},
+			},
+		},
+	}}
+	assert.Nil(t, c.AddResourceChanges(changes))
+
+	assets := c.Assets()
+	if assert.Len(t, assets, 1) {
+		assert.Equal(t, "//pubsub.googleapis.com/projects/"+testProject+"/topics/my-topic", assets[0].Name)
+		assert.Equal(t, map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": map[string]string{"env": "prod"}},
+		}, assets[0].Resource.Data)
+	}
+}
+
+func TestSetConverterDefinitions_unknownType(t *testing.T) {
+	c, _, err := newTestConverter(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetConverterDefinitions([]*resources.ConverterDefinition{{ResourceType: "mycorp_topic", AssetType: "a", AssetName: "a"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// IssueKind is the kind of an Issue.
+type IssueKind string
+
+const (
+	// UnknownField is a field that the schema does not have.
+	UnknownField IssueKind = "unknown field"
+	// WrongType is a value that does not have the type of its schema.
+	WrongType IssueKind = "wrong type"
+	// EnumMismatch is a string that is not one of the values of its enum.
+	EnumMismatch IssueKind = "enum mismatch"
+	// UnknownSchema is a discovery name that the discovery document does not
+	// have.
+	UnknownSchema IssueKind = "unknown schema"
+	// UnknownDocument is a discovery document URI that is not the URI of a
+	// Google API, like the URI of a plugin asset.
+	UnknownDocument IssueKind = "unknown discovery document"
+)
+
+// Issue is a mismatch between the resource data of an asset and its schema.
+type Issue struct {
+	// Asset is the name of the asset.
+	Asset string
+	// Path is the path of the value in the resource data, like
+	// "lifecycle.rule[0].action".
+	Path    string
+	Kind    IssueKind
+	Message string
+	// Warning is set for known deviations of the built-in converters from
+	// their schemas, and for assets that cannot be checked.
+	Warning bool
+}
+
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s: %s: %s", i.Asset, i.Kind, i.Message)
+	if i.Path != "" {
+		s = fmt.Sprintf("%s: %s: %s: %s", i.Asset, i.Path, i.Kind, i.Message)
+	}
+	if i.Warning {
+		return "warning: " + s
+	}
+	return s
+}
+
+// Checker checks the resource data of assets against the schemas of their
+// discovery documents. It is safe for concurrent use.
+type Checker struct {
+	mu sync.Mutex
+	// docs holds the documents by FileName; a nil document means that there
+	// is no document for the API version.
+	docs map[string]*Document
+}
+
+// NewChecker returns a Checker using the bundled discovery documents.
+func NewChecker() *Checker {
+	return &Checker{docs: map[string]*Document{}}
+}
+
+// AddDocument makes c use doc for the API version of doc, instead of the
+// bundled document.
+func (c *Checker) AddDocument(doc *Document) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs[FileName(doc.Name, doc.Version)] = doc
+}
+
+func (c *Checker) document(name, version string) (*Document, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := FileName(name, version)
+	if doc, ok := c.docs[key]; ok {
+		return doc, nil
+	}
+	doc, err := readBundledDocument(name, version)
+	if err != nil {
+		return nil, err
+	}
+	c.docs[key] = doc
+	return doc, nil
+}
+
+// CheckAssets checks the resource data of assets, and returns their issues
+// in the order of assets.
+func (c *Checker) CheckAssets(assets []google.Asset) ([]Issue, error) {
+	var issues []Issue
+	for _, asset := range assets {
+		assetIssues, err := c.Check(asset)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, assetIssues...)
+	}
+	return issues, nil
+}
+
+// Check checks the resource data of asset. Assets without resource data or
+// without a discovery document URI, and assets of APIs without a bundled
+// document, have no issues. Assets with a discovery document URI that is not
+// the URI of a Google API, and known deviations of the built-in converters
+// from their schemas, are reported as warnings.
+func (c *Checker) Check(asset google.Asset) ([]Issue, error) {
+	if asset.Resource == nil || asset.Resource.Data == nil || asset.Resource.DiscoveryDocumentURI == "" {
+		return nil, nil
+	}
+	ch := &check{asset: asset}
+	name, version, err := API(asset.Resource.DiscoveryDocumentURI, asset.Resource.Version)
+	if err != nil {
+		ch.issues = []Issue{{
+			Asset:   asset.Name,
+			Kind:    UnknownDocument,
+			Message: err.Error(),
+			Warning: true,
+		}}
+		return ch.issues, nil
+	}
+	ch.doc, err = c.document(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if ch.doc == nil {
+		return nil, nil
+	}
+
+	schema, ok := ch.doc.Schemas[asset.Resource.DiscoveryName]
+	if !ok {
+		ch.report("", UnknownSchema, "%s %s has no schema %q", name, version, asset.Resource.DiscoveryName)
+		return ch.issues, nil
+	}
+
+	// Converters give data of various Go types, so check its JSON encoding,
+	// as sent to policies.
+	b, err := json.Marshal(asset.Resource.Data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding data of asset %s: %w", asset.Name, err)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var data interface{}
+	if err := d.Decode(&data); err != nil {
+		return nil, fmt.Errorf("decoding data of asset %s: %w", asset.Name, err)
+	}
+
+	ch.value("", data, schema)
+	return ch.issues, nil
+}
+
+// check holds the state of checking the data of an asset.
+type check struct {
+	asset  google.Asset
+	doc    *Document
+	issues []Issue
+}
+
+func (ch *check) report(path string, kind IssueKind, format string, a ...interface{}) {
+	ch.issues = append(ch.issues, Issue{
+		Asset:   ch.asset.Name,
+		Path:    path,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, a...),
+		Warning: knownDeviation(ch.asset.Type, path),
+	})
+}
+
+// value checks v, found at path, against s. Null values match all schemas.
+func (ch *check) value(path string, v interface{}, s *Schema) {
+	// Follow references, without looping on bad documents.
+	for i := 0; s != nil && s.Ref != "" && i < 8; i++ {
+		s = ch.doc.Schemas[s.Ref]
+	}
+	if s == nil || v == nil {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			ch.report(path, WrongType, "got %s, want object", typeName(v))
+			return
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fieldPath := k
+			if path != "" {
+				fieldPath = path + "." + k
+			}
+			if p, ok := s.Properties[k]; ok {
+				ch.value(fieldPath, m[k], p)
+			} else if s.AdditionalProperties != nil {
+				ch.value(fieldPath, m[k], s.AdditionalProperties)
+			} else {
+				ch.report(fieldPath, UnknownField, "no such field%s", suggestion(k, s.Properties))
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			ch.report(path, WrongType, "got %s, want array", typeName(v))
+			return
+		}
+		for i, item := range a {
+			ch.value(fmt.Sprintf("%s[%d]", path, i), item, s.Items)
+		}
+	case "string":
+		switch v := v.(type) {
+		case string:
+			if len(s.Enum) > 0 && !contains(s.Enum, v) {
+				ch.report(path, EnumMismatch, "got %q, want one of %s", v, strings.Join(s.Enum, ", "))
+			}
+		case json.Number:
+			// 64-bit integers are strings in the API, but commonly numbers
+			// in converted data.
+			if s.Format != "int64" && s.Format != "uint64" {
+				ch.report(path, WrongType, "got number, want string")
+			} else if _, err := strconv.ParseInt(v.String(), 10, 64); err != nil {
+				ch.report(path, WrongType, "got %s, want %s", v, s.Format)
+			}
+		default:
+			ch.report(path, WrongType, "got %s, want string", typeName(v))
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			ch.report(path, WrongType, "got %s, want integer", typeName(v))
+		} else if _, err := n.Int64(); err != nil {
+			ch.report(path, WrongType, "got %s, want integer", n)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			ch.report(path, WrongType, "got %s, want number", typeName(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			ch.report(path, WrongType, "got %s, want boolean", typeName(v))
+		}
+	}
+}
+
+// typeName is the JSON type of v.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// suggestion names the property of properties that only differs from field
+// by case or underscores, to point out typos such as snake_case fields.
+func suggestion(field string, properties map[string]*Schema) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+	for p := range properties {
+		if normalize(p) == normalize(field) {
+			return fmt.Sprintf(", did you mean %q?", p)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI(t *testing.T) {
+	cases := []struct {
+		uri         string
+		version     string
+		wantName    string
+		wantVersion string
+		wantErr     bool
+	}{
+		{uri: "https://www.googleapis.com/discovery/v1/apis/storage/v1/rest", version: "v1", wantName: "storage", wantVersion: "v1"},
+		{uri: "https://www.googleapis.com/discovery/v1/apis/{{location}}-dialogflow/v3/rest", version: "v3", wantName: "dialogflow", wantVersion: "v3"},
+		{uri: "https://iam.googleapis.com/$discovery/rest?version=v1", wantName: "iam", wantVersion: "v1"},
+		{uri: "https://bigtableadmin.googleapis.com/$discovery/rest", version: "v2", wantName: "bigtableadmin", wantVersion: "v2"},
+		{uri: "https://www.googleapis.com/discovery/v1/apis/pubsublite/admin/rest", version: "admin", wantName: "pubsublite", wantVersion: "v1"},
+		{uri: "https://bigtableadmin.googleapis.com/$discovery/rest", wantErr: true},
+		{uri: "https://example.com/schema.json", version: "v1", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.uri, func(t *testing.T) {
+			name, version, err := API(c.uri, c.version)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.wantName, name)
+			assert.Equal(t, c.wantVersion, version)
+		})
+	}
+}
+
+func testAsset(discoveryName string, data map[string]interface{}) google.Asset {
+	return google.Asset{
+		Name: "//example.googleapis.com/widgets/test",
+		Type: "example.googleapis.com/Widget",
+		Resource: &google.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://www.googleapis.com/discovery/v1/apis/example/v1/rest",
+			DiscoveryName:        discoveryName,
+			Data:                 data,
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	checker := NewChecker()
+	checker.AddDocument(&Document{
+		Name:    "example",
+		Version: "v1",
+		Schemas: map[string]*Schema{
+			"Widget": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"name":      {Type: "string"},
+					"sizeBytes": {Type: "string", Format: "int64"},
+					"count":     {Type: "integer", Format: "int32"},
+					"ratio":     {Type: "number"},
+					"enabled":   {Type: "boolean"},
+					"state":     {Type: "string", Enum: []string{"STATE_UNSPECIFIED", "ACTIVE"}},
+					"labels":    {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+					"parts":     {Type: "array", Items: &Schema{Ref: "Part"}},
+					"metadata":  {Type: "any"},
+				},
+			},
+			"Part": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"partId": {Type: "string"},
+				},
+			},
+		},
+	})
+
+	cases := []struct {
+		name          string
+		discoveryName string
+		data          map[string]interface{}
+		want          []Issue
+	}{
+		{
+			name:          "valid",
+			discoveryName: "Widget",
+			data: map[string]interface{}{
+				"name":      "test",
+				"sizeBytes": 1024,
+				"count":     3,
+				"ratio":     0.5,
+				"enabled":   true,
+				"state":     "ACTIVE",
+				"labels":    map[string]string{"env": "test"},
+				"parts":     []interface{}{map[string]interface{}{"partId": "a"}, nil},
+				"metadata":  map[string]interface{}{"anything": []int{1}},
+			},
+		},
+		{
+			name:          "unknown fields",
+			discoveryName: "Widget",
+			data: map[string]interface{}{
+				"size_bytes": "1024",
+				"parts":      []interface{}{map[string]interface{}{"partID": "a", "extra": "b"}},
+			},
+			want: []Issue{
+				{Path: "parts[0].extra", Kind: UnknownField, Message: "no such field"},
+				{Path: "parts[0].partID", Kind: UnknownField, Message: `no such field, did you mean "partId"?`},
+				{Path: "size_bytes", Kind: UnknownField, Message: `no such field, did you mean "sizeBytes"?`},
+			},
+		},
+		{
+			name:          "wrong types",
+			discoveryName: "Widget",
+			data: map[string]interface{}{
+				"name":      3,
+				"sizeBytes": 1.5,
+				"count":     "3",
+				"enabled":   "true",
+				"labels":    []string{"env"},
+				"parts":     map[string]interface{}{"partId": "a"},
+			},
+			want: []Issue{
+				{Path: "count", Kind: WrongType, Message: "got string, want integer"},
+				{Path: "enabled", Kind: WrongType, Message: "got string, want boolean"},
+				{Path: "labels", Kind: WrongType, Message: "got array, want object"},
+				{Path: "name", Kind: WrongType, Message: "got number, want string"},
+				{Path: "parts", Kind: WrongType, Message: "got object, want array"},
+				{Path: "sizeBytes", Kind: WrongType, Message: "got 1.5, want int64"},
+			},
+		},
+		{
+			name:          "enum mismatch",
+			discoveryName: "Widget",
+			data:          map[string]interface{}{"state": "active"},
+			want: []Issue{
+				{Path: "state", Kind: EnumMismatch, Message: `got "active", want one of STATE_UNSPECIFIED, ACTIVE`},
+			},
+		},
+		{
+			name:          "unknown schema",
+			discoveryName: "Gadget",
+			data:          map[string]interface{}{"name": "test"},
+			want: []Issue{
+				{Kind: UnknownSchema, Message: `example v1 has no schema "Gadget"`},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			asset := testAsset(c.discoveryName, c.data)
+			for i := range c.want {
+				c.want[i].Asset = asset.Name
+			}
+			got, err := checker.Check(asset)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestCheckBundledDocument(t *testing.T) {
+	asset := google.Asset{
+		Name: "//storage.googleapis.com/test-bucket",
+		Type: "storage.googleapis.com/Bucket",
+		Resource: &google.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://www.googleapis.com/discovery/v1/apis/storage/v1/rest",
+			DiscoveryName:        "Bucket",
+			Data: map[string]interface{}{
+				"name":         "test-bucket",
+				"storageClass": "STANDARD",
+				"versioning":   map[string]interface{}{"enabled": "true"},
+				"website":      map[string]interface{}{"main_page_suffix": "index.html"},
+			},
+		},
+	}
+	got, err := NewChecker().CheckAssets([]google.Asset{asset})
+	require.NoError(t, err)
+	assert.Equal(t, []Issue{
+		{Asset: asset.Name, Path: "versioning.enabled", Kind: WrongType, Message: "got string, want boolean"},
+		{Asset: asset.Name, Path: "website.main_page_suffix", Kind: UnknownField, Message: `no such field, did you mean "mainPageSuffix"?`},
+	}, got)
+
+	// Assets of APIs without a bundled document are not checked.
+	asset.Resource.DiscoveryDocumentURI = "https://www.googleapis.com/discovery/v1/apis/unknown/v1/rest"
+	got, err = NewChecker().Check(asset)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestCheckWarnings(t *testing.T) {
+	bucket := google.Asset{
+		Name: "//storage.googleapis.com/test-bucket",
+		Type: "storage.googleapis.com/Bucket",
+		Resource: &google.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://www.googleapis.com/discovery/v1/apis/storage/v1/rest",
+			DiscoveryName:        "Bucket",
+			Data:                 map[string]interface{}{"name": "test-bucket", "project": "test-project"},
+		},
+	}
+	// Plugin and declarative assets may not name a document, or name one
+	// that is not a Google API.
+	undocumented := google.Asset{
+		Name:     "//example.com/widgets/undocumented",
+		Type:     "example.com/Widget",
+		Resource: &google.AssetResource{Data: map[string]interface{}{"name": "undocumented"}},
+	}
+	external := google.Asset{
+		Name: "//example.com/widgets/external",
+		Type: "example.com/Widget",
+		Resource: &google.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://example.com/schema.json",
+			Data:                 map[string]interface{}{"name": "external"},
+		},
+	}
+	got, err := NewChecker().CheckAssets([]google.Asset{bucket, undocumented, external})
+	require.NoError(t, err)
+	assert.Equal(t, []Issue{
+		{Asset: bucket.Name, Path: "project", Kind: UnknownField, Message: "no such field", Warning: true},
+		{Asset: external.Name, Kind: UnknownDocument, Message: `unexpected discovery document URI "https://example.com/schema.json"`, Warning: true},
+	}, got)
+	assert.Equal(t, "warning: //storage.googleapis.com/test-bucket: project: unknown field: no such field", got[0].String())
+}
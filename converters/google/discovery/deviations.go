@@ -0,0 +1,60 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import "regexp"
+
+// knownDeviations maps asset types to the paths of fields, without array
+// indices, that the built-in converters give although their schema does not
+// have them. Existing policies may rely on these fields, so they are reported
+// as warnings. An empty path means that the data of the asset type does not
+// follow its schema, and that all of its issues are warnings.
+//
+// New converters should not add entries.
+var knownDeviations = map[string][]string{
+	// CAI gives projects and folders with the compute discovery document.
+	"cloudresourcemanager.googleapis.com/Folder":  {""},
+	"cloudresourcemanager.googleapis.com/Project": {""},
+	// The discovery names of these asset types are not schema names.
+	"compute.googleapis.com/GlobalAddress":        {""},
+	"compute.googleapis.com/GlobalForwardingRule": {""},
+	"logging.googleapis.com/Metric":               {""},
+	"serviceusage.googleapis.com/Service":         {""},
+
+	"bigquery.googleapis.com/Table":               {"tableReference.project"},
+	"bigtableadmin.googleapis.com/Cluster":        {"serverNodes"},
+	"cloudfunctions.googleapis.com/CloudFunction": {"location", "timeout", "trigger_http"},
+	"compute.googleapis.com/Snapshot":             {"zone"},
+	"container.googleapis.com/NodePool":           {"cluster", "location"},
+	// Databases are given as CreateDatabaseRequest fields.
+	"spanner.googleapis.com/Database": {"createStatement", "extraStatements"},
+	// Instances are given as CreateInstanceRequest fields.
+	"spanner.googleapis.com/Instance": {"instance", "instanceId"},
+	"storage.googleapis.com/Bucket":   {"project"},
+}
+
+var arrayIndexRE = regexp.MustCompile(`\[\d+\]`)
+
+// knownDeviation reports whether the field at path of assets of assetType is
+// a known deviation from their schema.
+func knownDeviation(assetType, path string) bool {
+	path = arrayIndexRE.ReplaceAllString(path, "")
+	for _, p := range knownDeviations[assetType] {
+		if p == path || p == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery checks the resource data of converted assets against the
+// schemas of the Google API discovery documents they refer to.
+//
+// The schemas of the discovery documents referred to by the built-in
+// converters are bundled with the package, so that checks do not make network
+// requests. They are extracted from the discovery documents of the
+// google.golang.org/api module by "go generate".
+package discovery
+
+//go:generate go run ./internal/gen -resources ../resources -out documents
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+//go:embed documents/*.json
+var documents embed.FS
+
+// apiAliases maps the "api/version" of discovery document URIs used by
+// converters to the discovery document that describes them.
+var apiAliases = map[string]string{
+	"pubsublite/admin": "pubsublite/v1",
+	"www/v2":           "deploymentmanager/v2",
+}
+
+// Document is the part of a discovery document describing its schemas.
+type Document struct {
+	Name    string             `json:"name"`
+	Version string             `json:"version"`
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a JSON schema of a discovery document. A schema either refers
+// to another schema of the document by name, or has a type.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// API returns the API name and version of the discovery document at uri, as
+// used in the file names of bundled documents. version is the version of the
+// asset, used when uri does not give one. Location prefixes of regional
+// endpoints, like "{{location}}-dialogflow", are dropped.
+func API(uri, version string) (string, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing discovery document URI %q: %w", uri, err)
+	}
+	var name string
+	switch {
+	case strings.HasPrefix(u.Path, "/discovery/v1/apis/"):
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/discovery/v1/apis/"), "/")
+		if len(parts) != 3 || parts[2] != "rest" {
+			return "", "", fmt.Errorf("unexpected discovery document URI %q", uri)
+		}
+		name, version = parts[0], parts[1]
+	case u.Path == "/$discovery/rest" && strings.HasSuffix(u.Host, ".googleapis.com"):
+		name = strings.TrimSuffix(u.Host, ".googleapis.com")
+		if v := u.Query().Get("version"); v != "" {
+			version = v
+		}
+	default:
+		return "", "", fmt.Errorf("unexpected discovery document URI %q", uri)
+	}
+	if i := strings.LastIndex(name, "}}-"); i >= 0 {
+		name = name[i+len("}}-"):]
+	}
+	if version == "" {
+		return "", "", fmt.Errorf("no API version in discovery document URI %q", uri)
+	}
+	if alias, ok := apiAliases[name+"/"+version]; ok {
+		name, version, _ = strings.Cut(alias, "/")
+	}
+	return name, version, nil
+}
+
+// FileName is the name of the bundled document of an API version.
+func FileName(name, version string) string {
+	return name + "." + version + ".json"
+}
+
+// readBundledDocument reads the bundled document of an API version. It
+// returns nil if there is none.
+func readBundledDocument(name, version string) (*Document, error) {
+	b, err := documents.ReadFile("documents/" + FileName(name, version))
+	if err != nil {
+		return nil, nil
+	}
+	doc := &Document{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("reading discovery document of %s %s: %w", name, version, err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command gen writes the schemas of the discovery documents referred to by
+// the converters in the resources directory to the output directory. The
+// discovery documents are read from the google.golang.org/api module.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/discovery"
+)
+
+var assetResourceRE = regexp.MustCompile(`Version:\s+"([^"]*)",\s+DiscoveryDocumentURI:\s+"([^"]*)"`)
+
+func main() {
+	resourcesDir := flag.String("resources", "", "directory of the converters")
+	outDir := flag.String("out", "", "directory to write the documents to")
+	flag.Parse()
+
+	apis, err := referencedAPIs(*resourcesDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiDir, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "google.golang.org/api").Output()
+	if err != nil {
+		log.Fatalf("locating google.golang.org/api: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*outDir, "*.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, api := range apis {
+		name, version, _ := strings.Cut(api, "/")
+		src := filepath.Join(strings.TrimSpace(string(apiDir)), name, version, name+"-api.json")
+		b, err := ioutil.ReadFile(src)
+		if os.IsNotExist(err) {
+			log.Printf("skipping %s %s: no discovery document in google.golang.org/api", name, version)
+			continue
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		doc := &discovery.Document{}
+		if err := json.Unmarshal(b, doc); err != nil {
+			log.Fatalf("reading %s: %v", src, err)
+		}
+		// Only the fields of Document are kept.
+		b, err = json.Marshal(doc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(*outDir, discovery.FileName(name, version)), append(b, '\n'), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// referencedAPIs returns the sorted "api/version" of the discovery documents
+// referred to by the Go files of dir.
+func referencedAPIs(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range assetResourceRE.FindAllStringSubmatch(string(b), -1) {
+			name, version, err := discovery.API(m[2], m[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			seen[name+"/"+version] = true
+		}
+	}
+	apis := make([]string, 0, len(seen))
+	for api := range seen {
+		apis = append(apis, api)
+	}
+	sort.Strings(apis)
+	return apis, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// templateData fills in the expected assets of testdata/templates.
+var templateData = map[string]interface{}{
+	"Ancestry": "organizations/12345/folders/67890",
+	"FolderID": "67890",
+	"OrgID":    "12345",
+	"Project": map[string]string{
+		"BillingAccountName": "billingAccounts/000000-000000-000000",
+		"Number":             "1234567890",
+	},
+	"Provider": map[string]string{
+		"credentials": "credentials.json",
+		"project":     "test-project",
+	},
+	"Time": map[string]string{
+		"RFC3339Nano": "2021-04-14T15:16:17Z",
+	},
+}
+
+// placeholderEnums are the paths of fields that the "full" templates set to
+// placeholder values, like "test-disk_type", instead of enum values.
+var placeholderEnums = map[string][]string{
+	"full_compute_instance.json": {"scheduling.onHostMaintenance"},
+	"full_sql_database_instance.json": {
+		"settings.activationPolicy",
+		"settings.dataDiskType",
+		"settings.maintenanceWindow.updateTrack",
+		"settings.pricingPlan",
+	},
+}
+
+// TestTemplates checks the expected assets of the converter tests.
+func TestTemplates(t *testing.T) {
+	files, err := filepath.Glob("../../../testdata/templates/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker()
+	for _, f := range files {
+		if strings.HasSuffix(f, ".tfplan.json") {
+			continue
+		}
+		f := f
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			tmpl, err := template.ParseFiles(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := tmpl.Execute(&b, templateData); err != nil {
+				t.Fatal(err)
+			}
+			var assets []google.Asset
+			if err := json.Unmarshal(b.Bytes(), &assets); err != nil {
+				t.Fatal(err)
+			}
+			issues, err := checker.CheckAssets(assets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, issue := range issues {
+				if issue.Warning {
+					continue
+				}
+				if issue.Kind == EnumMismatch && contains(placeholderEnums[filepath.Base(f)], issue.Path) {
+					continue
+				}
+				t.Error(issue)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+)
+
+// fetchCache deduplicates FetchFullResource calls within a single conversion.
+// Entries are keyed by asset type and name, so IAM member, binding and delete
+// conversions of the same resource share one fetch. Concurrent fetches of the
+// same key wait for the first one to finish instead of calling the API again.
+// Only successful fetches are cached, so a failed fetch is tried again by the
+// next caller. The exception is a failed prefetch, whose error is handed to
+// the next caller once so that the merge does not repeat it.
+type fetchCache struct {
+	group singleflight.Group
+
+	mu      sync.Mutex
+	results map[string]resources.Asset
+	// prefetchErrs are the errors of failed prefetches not yet handed out.
+	prefetchErrs map[string]error
+	calls        int
+	misses       int
+}
+
+func newFetchCache() *fetchCache {
+	return &fetchCache{
+		results:      make(map[string]resources.Asset),
+		prefetchErrs: make(map[string]error),
+	}
+}
+
+// fetch returns the cached result for key, calling fetchFunc if key has not
+// been fetched yet. The returned asset is a copy, as merges modify it.
+func (fc *fetchCache) fetch(key string, fetchFunc func() (resources.Asset, error)) (resources.Asset, error) {
+	fc.mu.Lock()
+	fc.calls++
+	asset, ok := fc.results[key]
+	prefetchErr, prefetchFailed := fc.prefetchErrs[key]
+	delete(fc.prefetchErrs, key)
+	fc.mu.Unlock()
+	if ok {
+		return copyAsset(asset), nil
+	}
+	if prefetchFailed {
+		return resources.Asset{}, prefetchErr
+	}
+	val, err, _ := fc.group.Do(key, func() (interface{}, error) {
+		asset, err := fetchFunc()
+		fc.mu.Lock()
+		fc.misses++
+		if err == nil {
+			fc.results[key] = asset
+		}
+		fc.mu.Unlock()
+		return asset, err
+	})
+	asset, _ = val.(resources.Asset)
+	return copyAsset(asset), err
+}
+
+// stats returns the number of fetches served from the cache or from a
+// concurrent fetch, and the number of fetches that called the API.
+// prefetch fetches key ahead of a later call to fetch. If it fails, that
+// call gets the error instead of fetching again.
+func (fc *fetchCache) prefetch(key string, fetchFunc func() (resources.Asset, error)) {
+	if _, err := fc.fetch(key, fetchFunc); err != nil {
+		fc.mu.Lock()
+		fc.prefetchErrs[key] = err
+		fc.mu.Unlock()
+	}
+}
+
+func (fc *fetchCache) stats() (hits, misses int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.calls - fc.misses, fc.misses
+}
+
+// copyAsset copies the parts of an asset that merge functions modify.
+func copyAsset(a resources.Asset) resources.Asset {
+	if a.IAMPolicy != nil {
+		policy := *a.IAMPolicy
+		policy.Bindings = nil
+		for _, b := range a.IAMPolicy.Bindings {
+			b.Members = append([]string(nil), b.Members...)
+			policy.Bindings = append(policy.Bindings, b)
+		}
+		a.IAMPolicy = &policy
+	}
+	return a
+}
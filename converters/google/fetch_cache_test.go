@@ -0,0 +1,83 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchCache(t *testing.T) {
+	fc := newFetchCache()
+	var calls int32
+	fetchFunc := func() (resources.Asset, error) {
+		atomic.AddInt32(&calls, 1)
+		return resources.Asset{
+			Name: "//cloudresourcemanager.googleapis.com/projects/test-project",
+			Type: "cloudresourcemanager.googleapis.com/Project",
+			IAMPolicy: &resources.IAMPolicy{
+				Bindings: []resources.IAMBinding{
+					{Role: "roles/viewer", Members: []string{"user:a@example.com"}},
+				},
+			},
+		}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			asset, err := fc.fetch("key", fetchFunc)
+			assert.Nil(t, err)
+			// Modifying the returned asset must not affect other callers.
+			asset.IAMPolicy.Bindings[0].Members[0] = "user:changed@example.com"
+		}()
+	}
+	wg.Wait()
+
+	asset, err := fc.fetch("key", fetchFunc)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user:a@example.com"}, asset.IAMPolicy.Bindings[0].Members)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	_, err = fc.fetch("other-key", func() (resources.Asset, error) {
+		return resources.Asset{}, resources.ErrResourceInaccessible
+	})
+	assert.Equal(t, resources.ErrResourceInaccessible, err)
+
+	// Failed fetches are tried again.
+	_, err = fc.fetch("other-key", fetchFunc)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+
+	// The error of a failed prefetch is handed to the next caller only.
+	fc.prefetch("prefetched-key", func() (resources.Asset, error) {
+		return resources.Asset{}, resources.ErrResourceInaccessible
+	})
+	_, err = fc.fetch("prefetched-key", fetchFunc)
+	assert.Equal(t, resources.ErrResourceInaccessible, err)
+	_, err = fc.fetch("prefetched-key", fetchFunc)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	hits, misses := fc.stats()
+	assert.Equal(t, 11, hits)
+	assert.Equal(t, 5, misses)
+}
@@ -0,0 +1,299 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfdata"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	provider "github.com/hashicorp/terraform-provider-google/google"
+)
+
+// fuzzSeeds is the number of seeds of each resource type in the seed corpus
+// of FuzzResourceConverters, which go test runs without -fuzz.
+const fuzzSeeds = 4
+
+// fuzzMaxDepth bounds the nesting of generated blocks.
+const fuzzMaxDepth = 6
+
+var (
+	fuzzOnce    sync.Once
+	fuzzSchemas map[string]*schema.Resource
+	fuzzTypes   []string
+	fuzzConfig  *resources.Config
+)
+
+// fuzzSetup returns the sorted resource types of AllResourceConverters that the
+// provider defines, their schemas, and an offline config.
+func fuzzSetup(t testing.TB) ([]string, map[string]*schema.Resource, *resources.Config) {
+	fuzzOnce.Do(func() {
+		fuzzSchemas = provider.Provider().ResourcesMap
+		for resourceType := range resources.AllResourceConverters() {
+			if _, ok := fuzzSchemas[resourceType]; ok {
+				fuzzTypes = append(fuzzTypes, resourceType)
+			}
+		}
+		sort.Strings(fuzzTypes)
+		cfg, err := resources.NewConfig(context.Background(), testProject, "", "", true, "", nil)
+		if err == nil {
+			fuzzConfig = cfg
+		}
+	})
+	if fuzzConfig == nil {
+		t.Fatal("constructing configuration failed")
+	}
+	return fuzzTypes, fuzzSchemas, fuzzConfig
+}
+
+// FuzzResourceConverters converts random values, valid for the schema of a
+// resource type, with the converters of the type. It fails on panics and on
+// assets without a name or type, or that cannot be encoded to JSON.
+//
+// Run it with go test -fuzz=FuzzResourceConverters ./converters/google.
+func FuzzResourceConverters(f *testing.F) {
+	types, _, _ := fuzzSetup(f)
+	for i := range types {
+		for seed := int64(0); seed < fuzzSeeds; seed++ {
+			f.Add(uint16(i), seed)
+		}
+	}
+	f.Fuzz(func(t *testing.T, typeIndex uint16, seed int64) {
+		types, schemas, cfg := fuzzSetup(t)
+		resourceType := types[int(typeIndex)%len(types)]
+		values := fuzzValues(rand.New(rand.NewSource(seed)), schemas[resourceType].Schema, 0)
+		if err := fuzzConvert(resourceType, schemas[resourceType].Schema, values, cfg); err != nil {
+			encoded, _ := json.Marshal(values)
+			t.Fatalf("%s with seed %d: %s\nvalues: %s", resourceType, seed, err, encoded)
+		}
+	})
+}
+
+// fuzzUpstreamPanics are the panics of converters copied from Magic Modules
+// that need a fix upstream, keyed by resource type. The copied files are not
+// edited here, and convertWrapper turns these panics into conversion errors.
+var fuzzUpstreamPanics = map[string]string{
+	// isFirstGen reads the settings block without checking that it is set,
+	// which it is not for clones.
+	"google_sql_database_instance": "index out of range [0] with length 0",
+}
+
+// fuzzConvert runs the converters of resourceType on values. Errors returned
+// by converters are expected for random values and are not reported.
+func fuzzConvert(resourceType string, s map[string]*schema.Schema, values map[string]interface{}, cfg *resources.Config) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if known, ok := fuzzUpstreamPanics[resourceType]; ok && strings.Contains(fmt.Sprint(r), known) {
+				return
+			}
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	rd := tfdata.NewFakeResourceData(resourceType, s, values)
+	for _, converter := range resources.AllResourceConverters()[resourceType] {
+		assets, convErr := converter.Convert(rd, cfg)
+		if convErr != nil {
+			continue
+		}
+		for _, asset := range assets {
+			if asset.Name == "" || asset.Type == "" {
+				return fmt.Errorf("asset without a name or type: %+v", asset)
+			}
+			if strings.Contains(asset.Name, "<nil>") || strings.Contains(asset.Name, "%!") {
+				return fmt.Errorf("asset with an invalid name: %s", asset.Name)
+			}
+			if _, err := json.Marshal(asset); err != nil {
+				return fmt.Errorf("encoding asset %s: %w", asset.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fuzzStrings are the strings of generated values: empty, names, self links,
+// templates and values that look like other types.
+var fuzzStrings = []string{
+	"",
+	"foo",
+	testProject,
+	"projects/" + testProject,
+	"organizations/123",
+	"folders/456",
+	"us-central1",
+	"us-central1-a",
+	"https://www.googleapis.com/compute/v1/projects/" + testProject + "/global/networks/default",
+	"projects/" + testProject + "/locations/global/keyRings/ring/cryptoKeys/key",
+	"user:alice@example.com",
+	"roles/viewer",
+	"{{project}}",
+	"true",
+	"42",
+	"3600s",
+	"2021-04-14T15:16:17Z",
+	"a/b/c/d/e/f",
+	"ünïcödé",
+}
+
+// fuzzValues generates values for s in the format of the planned values of a
+// plan: optional attributes may be missing or null (unknown), blocks may be
+// empty or have null items, and numbers are float64.
+func fuzzValues(r *rand.Rand, s map[string]*schema.Schema, depth int) map[string]interface{} {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]interface{})
+	for _, k := range keys {
+		switch n := r.Intn(10); {
+		case s[k].Required:
+			values[k] = fuzzValue(r, s[k], depth)
+		case n == 0:
+			// Missing.
+		case n == 1:
+			values[k] = nil
+		default:
+			values[k] = fuzzValue(r, s[k], depth)
+		}
+	}
+	return values
+}
+
+func fuzzValue(r *rand.Rand, s *schema.Schema, depth int) interface{} {
+	switch s.Type {
+	case schema.TypeBool:
+		return r.Intn(2) == 0
+	case schema.TypeInt:
+		return float64(r.Intn(2000) - 1000)
+	case schema.TypeFloat:
+		return r.NormFloat64() * 100
+	case schema.TypeString:
+		return fuzzStrings[r.Intn(len(fuzzStrings))]
+	case schema.TypeMap:
+		m := make(map[string]interface{})
+		for i := r.Intn(3); i > 0; i-- {
+			key := fuzzStrings[r.Intn(len(fuzzStrings))]
+			switch elem, _ := s.Elem.(*schema.Schema); {
+			case elem == nil, elem.Type == schema.TypeString:
+				m[key] = fuzzStrings[r.Intn(len(fuzzStrings))]
+			default:
+				m[key] = fuzzValue(r, elem, depth+1)
+			}
+		}
+		return m
+	case schema.TypeList, schema.TypeSet:
+		if depth >= fuzzMaxDepth {
+			return []interface{}{}
+		}
+		n := r.Intn(4)
+		if s.MaxItems > 0 && n > s.MaxItems {
+			n = s.MaxItems
+		}
+		if n < s.MinItems || (n == 0 && s.Required) {
+			n = s.MinItems
+			if n == 0 {
+				n = 1
+			}
+		}
+		l := make([]interface{}, n)
+		for i := range l {
+			switch elem := s.Elem.(type) {
+			case *schema.Resource:
+				switch n := r.Intn(6); {
+				case n == 0 && !s.Required:
+					// An unknown block, which Terraform only gives for
+					// optional blocks.
+					l[i] = nil
+				case n == 1 && !hasRequired(elem.Schema):
+					l[i] = map[string]interface{}{}
+				default:
+					l[i] = fuzzValues(r, elem.Schema, depth+1)
+				}
+			case *schema.Schema:
+				l[i] = fuzzValue(r, elem, depth+1)
+			default:
+				l[i] = fuzzStrings[r.Intn(len(fuzzStrings))]
+			}
+		}
+		return l
+	}
+	return nil
+}
+
+func hasRequired(s map[string]*schema.Schema) bool {
+	for _, attr := range s {
+		if attr.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// TestResourceConverters_unknownBlocks converts the inputs on which
+// FuzzResourceConverters found panics in converters copied from Magic
+// Modules. FakeResourceData drops unknown blocks and offline configs fail API
+// calls, so the converters need no local changes.
+func TestResourceConverters_unknownBlocks(t *testing.T) {
+	cases := []struct {
+		resourceType string
+		values       map[string]interface{}
+	}{
+		{
+			resourceType: "google_storage_bucket",
+			values: map[string]interface{}{
+				"name":             "bucket",
+				"location":         "US",
+				"cors":             []interface{}{nil},
+				"retention_policy": []interface{}{nil},
+				"lifecycle_rule":   []interface{}{nil},
+			},
+		},
+		{
+			resourceType: "google_bigtable_instance",
+			values: map[string]interface{}{
+				"name": "instance",
+				"cluster": []interface{}{
+					map[string]interface{}{"cluster_id": "cluster"},
+					nil,
+				},
+			},
+		},
+		{
+			resourceType: "google_project_organization_policy",
+			values: map[string]interface{}{
+				"project":    testProject,
+				"constraint": "constraints/compute.vmExternalIpAccess",
+				"list_policy": []interface{}{map[string]interface{}{
+					"allow": []interface{}{nil},
+					"deny":  []interface{}{nil},
+				}},
+			},
+		},
+		{
+			// Images are looked up with the compute API, which offline
+			// configs have no client for.
+			resourceType: "google_compute_disk",
+			values: map[string]interface{}{
+				"name":  "disk",
+				"zone":  "us-central1-a",
+				"image": "my-image",
+			},
+		},
+	}
+	_, schemas, cfg := fuzzSetup(t)
+	for _, c := range cases {
+		t.Run(c.resourceType, func(t *testing.T) {
+			if err := fuzzConvert(c.resourceType, schemas[c.resourceType].Schema, c.values, cfg); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
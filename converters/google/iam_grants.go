@@ -0,0 +1,86 @@
+package google
+
+import (
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+)
+
+// IAMGrant is the grant of a role to a member by an IAM policy binding.
+// Condition is the zero Expr for unconditional grants.
+type IAMGrant struct {
+	Role      string
+	Member    string
+	Condition Expr
+}
+
+// iamGrants returns the grants of the bindings of policy.
+func iamGrants(policy *IAMPolicy) []IAMGrant {
+	if policy == nil {
+		return nil
+	}
+	var grants []IAMGrant
+	for _, b := range policy.Bindings {
+		var condition Expr
+		if b.Condition != nil {
+			condition = *b.Condition
+		}
+		for _, m := range b.Members {
+			grants = append(grants, IAMGrant{Role: b.Role, Member: m, Condition: condition})
+		}
+	}
+	return grants
+}
+
+// recordGrantAddresses records that the Terraform resource at address makes
+// the grants of the IAM policy of converted, an asset stored under key.
+func (c *Converter) recordGrantAddresses(key, address string, converted resources.Asset) {
+	if converted.IAMPolicy == nil {
+		return
+	}
+	grants := c.grantAddresses[key]
+	if grants == nil {
+		grants = make(map[IAMGrant][]string)
+		c.grantAddresses[key] = grants
+	}
+	for _, b := range converted.IAMPolicy.Bindings {
+		var condition Expr
+		if b.Condition != nil {
+			condition = Expr(*b.Condition)
+		}
+		for _, m := range b.Members {
+			grant := IAMGrant{Role: b.Role, Member: m, Condition: condition}
+			if !containsString(grants[grant], address) {
+				grants[grant] = append(grants[grant], address)
+			}
+		}
+	}
+}
+
+// assetGrantAddresses returns the addresses recorded for the grants of the
+// IAM policy of asset, which is stored under key. Grants that were recorded
+// but were later removed from the policy, for instance by an authoritative
+// resource, are left out.
+func (c *Converter) assetGrantAddresses(key string, asset Asset) map[IAMGrant][]string {
+	recorded := c.grantAddresses[key]
+	if len(recorded) == 0 {
+		return nil
+	}
+	addresses := make(map[IAMGrant][]string)
+	for _, g := range iamGrants(asset.IAMPolicy) {
+		if a, ok := recorded[g]; ok {
+			addresses[g] = a
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+	return addresses
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package google
+
+import (
+	"strings"
+)
+
+// SetNormalizeOrgPolicies sets whether the organization policies of assets
+// are given in both the v1 (org_policy) and v2 (v2_org_policies) formats,
+// whichever format the Terraform resource uses. See normalizeOrgPolicies for
+// the policies that cannot be translated.
+func (c *Converter) SetNormalizeOrgPolicies(normalize bool) {
+	c.normalizeOrgPolicies = normalize
+}
+
+// normalizeOrgPolicies adds to asset the v2 version of its v1 organization
+// policies, and the v1 version of its v2 policies, for the constraints that
+// are not already set in the other format.
+//
+// Boolean policies are translated to and from a single enforce rule, list
+// policies to and from allow_all, deny_all or values rules, restore_default to
+// and from reset, and the inherit_from_parent of list policies to and from the
+// one of the policy spec.
+//
+// Some policies have no equivalent and are not translated:
+//   - v2 policies with conditional rules, as v1 policies have no conditions;
+//   - v2 policies without a spec;
+//   - v2 policies mixing enforce rules with values rules.
+//
+// The suggested_value of v1 list policies has no v2 equivalent and is dropped.
+func normalizeOrgPolicies(asset *Asset) {
+	v1Constraints := make(map[string]bool)
+	for _, p := range asset.OrgPolicy {
+		v1Constraints[p.Constraint] = true
+	}
+	v2Constraints := make(map[string]bool)
+	for _, p := range asset.V2OrgPolicies {
+		v2Constraints[V2OrgPolicyConstraint(p.Name)] = true
+	}
+
+	parent := strings.TrimPrefix(asset.Name, "//cloudresourcemanager.googleapis.com/")
+	var v2Policies []*V2OrgPolicies
+	for _, p := range asset.OrgPolicy {
+		if v2Constraints[p.Constraint] {
+			continue
+		}
+		v2Policies = append(v2Policies, v1ToV2OrgPolicy(parent, p))
+	}
+	var v1Policies []*OrgPolicy
+	for _, p := range asset.V2OrgPolicies {
+		if v1Constraints[V2OrgPolicyConstraint(p.Name)] {
+			continue
+		}
+		if policy, ok := v2ToV1OrgPolicy(p); ok {
+			v1Policies = append(v1Policies, policy)
+		}
+	}
+	// The slices are copied as they may be shared with the converted assets.
+	if len(v2Policies) > 0 {
+		asset.V2OrgPolicies = append(append([]*V2OrgPolicies(nil), asset.V2OrgPolicies...), v2Policies...)
+	}
+	if len(v1Policies) > 0 {
+		asset.OrgPolicy = append(append([]*OrgPolicy(nil), asset.OrgPolicy...), v1Policies...)
+	}
+}
+
+// V2OrgPolicyConstraint returns the v1 constraint name of a v2 policy, which
+// is named like "projects/my-project/policies/compute.skipDefaultNetworkCreation".
+func V2OrgPolicyConstraint(name string) string {
+	if i := strings.LastIndex(name, "/policies/"); i >= 0 {
+		name = name[i+len("/policies/"):]
+	}
+	if strings.HasPrefix(name, "constraints/") {
+		return name
+	}
+	return "constraints/" + name
+}
+
+func v1ToV2OrgPolicy(parent string, p *OrgPolicy) *V2OrgPolicies {
+	spec := &PolicySpec{UpdateTime: p.UpdateTime}
+	switch {
+	case p.RestoreDefault != nil:
+		spec.Reset = true
+	case p.BooleanPolicy != nil:
+		spec.PolicyRules = []*PolicyRule{{Enforce: p.BooleanPolicy.Enforced}}
+	case p.ListPolicy != nil:
+		spec.InheritFromParent = p.ListPolicy.InheritFromParent
+		switch p.ListPolicy.AllValues {
+		case ListPolicyAllValuesAllow:
+			spec.PolicyRules = []*PolicyRule{{AllowAll: true}}
+		case ListPolicyAllValuesDeny:
+			spec.PolicyRules = []*PolicyRule{{DenyAll: true}}
+		default:
+			if len(p.ListPolicy.AllowedValues) > 0 || len(p.ListPolicy.DeniedValues) > 0 {
+				spec.PolicyRules = []*PolicyRule{{Values: &StringValues{
+					AllowedValues: p.ListPolicy.AllowedValues,
+					DeniedValues:  p.ListPolicy.DeniedValues,
+				}}}
+			}
+		}
+	}
+	return &V2OrgPolicies{
+		Name:       parent + "/policies/" + strings.TrimPrefix(p.Constraint, "constraints/"),
+		PolicySpec: spec,
+	}
+}
+
+func v2ToV1OrgPolicy(p *V2OrgPolicies) (*OrgPolicy, bool) {
+	spec := p.PolicySpec
+	if spec == nil {
+		return nil, false
+	}
+	policy := &OrgPolicy{
+		Constraint: V2OrgPolicyConstraint(p.Name),
+		UpdateTime: spec.UpdateTime,
+	}
+	if spec.Reset {
+		policy.RestoreDefault = &RestoreDefault{}
+		return policy, true
+	}
+
+	var enforce, list bool
+	for _, rule := range spec.PolicyRules {
+		if rule.Condition != nil {
+			return nil, false
+		}
+		if rule.Values != nil || rule.AllowAll || rule.DenyAll {
+			list = true
+		} else {
+			enforce = true
+		}
+	}
+	switch {
+	case enforce && list:
+		return nil, false
+	case enforce:
+		policy.BooleanPolicy = &BooleanPolicy{}
+		for _, rule := range spec.PolicyRules {
+			policy.BooleanPolicy.Enforced = policy.BooleanPolicy.Enforced || rule.Enforce
+		}
+	default:
+		listPolicy := &ListPolicy{InheritFromParent: spec.InheritFromParent}
+		for _, rule := range spec.PolicyRules {
+			switch {
+			case rule.DenyAll:
+				listPolicy.AllValues = ListPolicyAllValuesDeny
+			case rule.AllowAll && listPolicy.AllValues == ListPolicyAllValuesUnspecified:
+				listPolicy.AllValues = ListPolicyAllValuesAllow
+			case rule.Values != nil:
+				listPolicy.AllowedValues = append(listPolicy.AllowedValues, rule.Values.AllowedValues...)
+				listPolicy.DeniedValues = append(listPolicy.DeniedValues, rule.Values.DeniedValues...)
+			}
+		}
+		if listPolicy.AllValues != ListPolicyAllValuesUnspecified {
+			listPolicy.AllowedValues = nil
+			listPolicy.DeniedValues = nil
+		}
+		policy.ListPolicy = listPolicy
+	}
+	return policy, true
+}
@@ -0,0 +1,167 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeOrgPolicies(t *testing.T) {
+	const assetName = "//cloudresourcemanager.googleapis.com/projects/my-project"
+	updateTime := &Timestamp{Seconds: 1618413377}
+	cases := []struct {
+		name   string
+		v1     []*OrgPolicy
+		v2     []*V2OrgPolicies
+		wantV1 []*OrgPolicy
+		wantV2 []*V2OrgPolicies
+	}{
+		{
+			name: "v1 boolean policy",
+			v1: []*OrgPolicy{
+				{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{Enforced: true}, UpdateTime: updateTime},
+			},
+			wantV2: []*V2OrgPolicies{
+				{
+					Name: "projects/my-project/policies/compute.disableSerialPortAccess",
+					PolicySpec: &PolicySpec{
+						UpdateTime:  updateTime,
+						PolicyRules: []*PolicyRule{{Enforce: true}},
+					},
+				},
+			},
+		},
+		{
+			name: "v1 list policies",
+			v1: []*OrgPolicy{
+				{Constraint: "constraints/compute.trustedImageProjects", ListPolicy: &ListPolicy{AllowedValues: []string{"projects/images"}, InheritFromParent: true}},
+				{Constraint: "constraints/serviceuser.services", ListPolicy: &ListPolicy{AllValues: ListPolicyAllValuesDeny}},
+				{Constraint: "constraints/compute.vmExternalIpAccess", ListPolicy: &ListPolicy{AllValues: ListPolicyAllValuesAllow}},
+			},
+			wantV2: []*V2OrgPolicies{
+				{
+					Name: "projects/my-project/policies/compute.trustedImageProjects",
+					PolicySpec: &PolicySpec{
+						InheritFromParent: true,
+						PolicyRules:       []*PolicyRule{{Values: &StringValues{AllowedValues: []string{"projects/images"}}}},
+					},
+				},
+				{
+					Name:       "projects/my-project/policies/serviceuser.services",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{{DenyAll: true}}},
+				},
+				{
+					Name:       "projects/my-project/policies/compute.vmExternalIpAccess",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{{AllowAll: true}}},
+				},
+			},
+		},
+		{
+			name: "v1 restore default",
+			v1: []*OrgPolicy{
+				{Constraint: "constraints/compute.disableSerialPortAccess", RestoreDefault: &RestoreDefault{}},
+			},
+			wantV2: []*V2OrgPolicies{
+				{
+					Name:       "projects/my-project/policies/compute.disableSerialPortAccess",
+					PolicySpec: &PolicySpec{Reset: true},
+				},
+			},
+		},
+		{
+			name: "v2 enforce rule",
+			v2: []*V2OrgPolicies{
+				{
+					Name:       "projects/my-project/policies/compute.disableSerialPortAccess",
+					PolicySpec: &PolicySpec{UpdateTime: updateTime, PolicyRules: []*PolicyRule{{Enforce: true}}},
+				},
+			},
+			wantV1: []*OrgPolicy{
+				{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{Enforced: true}, UpdateTime: updateTime},
+			},
+		},
+		{
+			name: "v2 values rules",
+			v2: []*V2OrgPolicies{
+				{
+					Name: "projects/my-project/policies/compute.trustedImageProjects",
+					PolicySpec: &PolicySpec{
+						InheritFromParent: true,
+						PolicyRules: []*PolicyRule{
+							{Values: &StringValues{AllowedValues: []string{"projects/images"}}},
+							{Values: &StringValues{AllowedValues: []string{"projects/other-images"}, DeniedValues: []string{"projects/bad-images"}}},
+						},
+					},
+				},
+				{
+					Name:       "projects/my-project/policies/serviceuser.services",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{{DenyAll: true}}},
+				},
+			},
+			wantV1: []*OrgPolicy{
+				{
+					Constraint: "constraints/compute.trustedImageProjects",
+					ListPolicy: &ListPolicy{
+						AllowedValues:     []string{"projects/images", "projects/other-images"},
+						DeniedValues:      []string{"projects/bad-images"},
+						InheritFromParent: true,
+					},
+				},
+				{Constraint: "constraints/serviceuser.services", ListPolicy: &ListPolicy{AllValues: ListPolicyAllValuesDeny}},
+			},
+		},
+		{
+			name: "v2 reset",
+			v2: []*V2OrgPolicies{
+				{Name: "projects/my-project/policies/compute.disableSerialPortAccess", PolicySpec: &PolicySpec{Reset: true}},
+			},
+			wantV1: []*OrgPolicy{
+				{Constraint: "constraints/compute.disableSerialPortAccess", RestoreDefault: &RestoreDefault{}},
+			},
+		},
+		{
+			name: "untranslatable v2 policies",
+			v2: []*V2OrgPolicies{
+				{
+					Name: "projects/my-project/policies/compute.disableSerialPortAccess",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{
+						{Enforce: true, Condition: &Expr{Expression: "resource.matchTag('123/env', 'prod')"}},
+						{Enforce: false},
+					}},
+				},
+				{Name: "projects/my-project/policies/compute.vmExternalIpAccess"},
+				{
+					Name: "projects/my-project/policies/serviceuser.services",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{
+						{Enforce: true},
+						{DenyAll: true},
+					}},
+				},
+			},
+		},
+		{
+			name: "constraint in both formats",
+			v1: []*OrgPolicy{
+				{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{Enforced: true}},
+			},
+			v2: []*V2OrgPolicies{
+				{
+					Name:       "projects/my-project/policies/compute.disableSerialPortAccess",
+					PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{{Enforce: false}}},
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			asset := Asset{Name: assetName, OrgPolicy: c.v1, V2OrgPolicies: c.v2}
+			normalizeOrgPolicies(&asset)
+			if diff := cmp.Diff(append(c.v1, c.wantV1...), asset.OrgPolicy); diff != "" {
+				t.Errorf("normalizeOrgPolicies() org_policy returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(append(c.v2, c.wantV2...), asset.V2OrgPolicies); diff != "" {
+				t.Errorf("normalizeOrgPolicies() v2_org_policies returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin runs external converters, which convert Terraform resources
+// that the built-in converters do not support, such as the resources of
+// internal providers, to CAI assets.
+//
+// A plugin is an executable described by a manifest, a YAML or JSON file in
+// the plugin directory:
+//
+//	name: project-factory
+//	command: ["./project-factory-plugin", "--verbose"]
+//	resource_types: ["mycorp_gcp_project_factory"]
+//	timeout: 30s
+//
+// A relative command path is relative to the plugin directory. For each
+// resource to convert, the command is run with a Request as JSON on its
+// standard input, and writes a Response as JSON to its standard output. A
+// plugin that fails writes a Response with an error, or exits with a non-zero
+// status; its standard error is then reported.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"sigs.k8s.io/yaml"
+)
+
+// ProtocolVersion is the version of the plugin protocol, sent in each
+// Request. Responses may omit it, or must give the same version.
+const ProtocolVersion = 1
+
+const defaultTimeout = 30 * time.Second
+
+// Request is sent to a plugin to convert a resource.
+type Request struct {
+	ProtocolVersion int `json:"protocol_version"`
+	// ResourceType is the Terraform resource type, like
+	// "mycorp_gcp_project_factory".
+	ResourceType string `json:"resource_type"`
+	// Address is the Terraform address of the resource.
+	Address string `json:"address"`
+	// PlannedValues are the values of the resource after the planned change.
+	PlannedValues map[string]interface{} `json:"planned_values"`
+	Provider      ProviderDefaults       `json:"provider"`
+}
+
+// ProviderDefaults are the defaults of the google provider, for resources
+// that do not set their project, region or zone.
+type ProviderDefaults struct {
+	Project string `json:"project,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+}
+
+// Response is returned by a plugin. Assets are in the CAI format of the
+// convert command, without ancestors, which are added like for the assets of
+// built-in converters.
+type Response struct {
+	ProtocolVersion int               `json:"protocol_version,omitempty"`
+	Assets          []resources.Asset `json:"assets"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// Plugin is an external converter.
+type Plugin struct {
+	Name          string   `json:"name"`
+	Command       []string `json:"command"`
+	ResourceTypes []string `json:"resource_types"`
+	// Timeout is a duration like "30s", 30 seconds by default.
+	Timeout string `json:"timeout,omitempty"`
+
+	dir     string
+	timeout time.Duration
+}
+
+// LoadDir reads the manifests of the plugins of dir, that is its .yaml, .yml
+// and .json files.
+func LoadDir(dir string) ([]*Plugin, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("listing plugins in %s: %w", dir, err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var plugins []*Plugin
+	types := make(map[string]string)
+	for _, file := range files {
+		p, err := readManifest(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range p.ResourceTypes {
+			if other, ok := types[t]; ok {
+				return nil, fmt.Errorf("resource type %s is converted by both plugins %s and %s", t, other, p.Name)
+			}
+			types[t] = p.Name
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+func readManifest(path string) (*Plugin, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plugin manifest: %w", err)
+	}
+	p := &Plugin{}
+	if err := yaml.UnmarshalStrict(content, p); err != nil {
+		return nil, fmt.Errorf("parsing plugin manifest %s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(p.Command) == 0 {
+		return nil, fmt.Errorf("plugin %s in %s: missing command", p.Name, path)
+	}
+	if len(p.ResourceTypes) == 0 {
+		return nil, fmt.Errorf("plugin %s in %s: missing resource_types", p.Name, path)
+	}
+	p.timeout = defaultTimeout
+	if p.Timeout != "" {
+		p.timeout, err = time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s in %s: timeout: %w", p.Name, path, err)
+		}
+	}
+	// Commands are run from the manifest's directory, so relative commands
+	// must not be resolved against a relative directory twice.
+	p.dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s in %s: %w", p.Name, path, err)
+	}
+	return p, nil
+}
+
+// Convert runs the plugin to convert a resource.
+func (p *Plugin) Convert(ctx context.Context, req Request) ([]resources.Asset, error) {
+	req.ProtocolVersion = ProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: encoding request: %w", p.Name, err)
+	}
+
+	timeout := p.timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := p.Command[0]
+	if !filepath.IsAbs(command) && strings.ContainsRune(command, filepath.Separator) {
+		command = filepath.Join(p.dir, command)
+	}
+	cmd := exec.CommandContext(ctx, command, p.Command[1:]...)
+	cmd.Dir = p.dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s: timed out after %s", p.Name, timeout)
+		}
+		return nil, fmt.Errorf("plugin %s: %w: %s", p.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: decoding response: %w", p.Name, err)
+	}
+	if resp.ProtocolVersion != 0 && resp.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("plugin %s: unsupported protocol version %d, want %d", p.Name, resp.ProtocolVersion, ProtocolVersion)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", p.Name, resp.Error)
+	}
+	for _, asset := range resp.Assets {
+		if asset.Name == "" || asset.Type == "" {
+			return nil, fmt.Errorf("plugin %s: asset without a name or asset_type", p.Name)
+		}
+	}
+	return resp.Assets, nil
+}
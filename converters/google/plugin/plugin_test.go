@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestHelperPlugin is not a test: it is run as a plugin by the other tests,
+// behaving according to TFV_TEST_PLUGIN.
+func TestHelperPlugin(t *testing.T) {
+	mode := os.Getenv("TFV_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "decoding request: %v", err)
+		os.Exit(1)
+	}
+	var resp Response
+	switch mode {
+	case "echo":
+		resp.Assets = []resources.Asset{{
+			Name: "//cloudresourcemanager.googleapis.com/projects/" + req.PlannedValues["project_id"].(string),
+			Type: "cloudresourcemanager.googleapis.com/Project",
+			Resource: &resources.AssetResource{
+				Data: map[string]interface{}{
+					"address":  req.Address,
+					"type":     req.ResourceType,
+					"project":  req.Provider.Project,
+					"protocol": req.ProtocolVersion,
+				},
+			},
+		}}
+	case "error":
+		resp.Error = "unsupported template"
+	case "version":
+		resp.ProtocolVersion = ProtocolVersion + 1
+	case "crash":
+		fmt.Fprint(os.Stderr, "segmentation fault")
+		os.Exit(3)
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
+
+// writeHelperManifest writes the manifest of a plugin running TestHelperPlugin.
+func writeHelperManifest(t *testing.T, dir, name, extra string) {
+	command, err := filepath.Abs(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := fmt.Sprintf("name: %s\ncommand: [%q, \"-test.run=TestHelperPlugin\"]\nresource_types: [mycorp_%s]\n%s", name, command, name, extra)
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Setenv("TFV_TEST_PLUGIN", "echo")
+	dir := t.TempDir()
+	writeHelperManifest(t, dir, "project_factory", "")
+
+	plugins, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() = %v", err)
+	}
+	if len(plugins) != 1 {
+		t.Fatalf("LoadDir() = %d plugins, want 1", len(plugins))
+	}
+	got, err := plugins[0].Convert(context.Background(), Request{
+		ResourceType:  "mycorp_project_factory",
+		Address:       "mycorp_project_factory.prod",
+		PlannedValues: map[string]interface{}{"project_id": "prod"},
+		Provider:      ProviderDefaults{Project: "default-project"},
+	})
+	if err != nil {
+		t.Fatalf("Convert() = %v", err)
+	}
+	want := []resources.Asset{{
+		Name: "//cloudresourcemanager.googleapis.com/projects/prod",
+		Type: "cloudresourcemanager.googleapis.com/Project",
+		Resource: &resources.AssetResource{
+			Data: map[string]interface{}{
+				"address":  "mycorp_project_factory.prod",
+				"type":     "mycorp_project_factory",
+				"project":  "default-project",
+				"protocol": float64(ProtocolVersion),
+			},
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvert_relativeDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	t.Setenv("TFV_TEST_PLUGIN", "echo")
+	command, err := filepath.Abs(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	dir := filepath.Join(root, "plugins")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := fmt.Sprintf("#!/bin/sh\nexec %q -test.run=TestHelperPlugin\n", command)
+	if err := ioutil.WriteFile(filepath.Join(dir, "p.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "name: p\ncommand: [\"./p.sh\"]\nresource_types: [mycorp_p]\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "p.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	plugins, err := LoadDir("plugins")
+	if err != nil {
+		t.Fatalf("LoadDir() = %v", err)
+	}
+	if _, err := plugins[0].Convert(context.Background(), Request{
+		ResourceType:  "mycorp_p",
+		PlannedValues: map[string]interface{}{"project_id": "prod"},
+	}); err != nil {
+		t.Errorf("Convert() = %v", err)
+	}
+}
+
+func TestConvert_errors(t *testing.T) {
+	cases := []struct {
+		mode    string
+		extra   string
+		wantErr string
+	}{
+		{mode: "error", wantErr: "unsupported template"},
+		{mode: "version", wantErr: "unsupported protocol version"},
+		{mode: "crash", wantErr: "segmentation fault"},
+		{mode: "sleep", extra: "timeout: 100ms", wantErr: "timed out"},
+	}
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			t.Setenv("TFV_TEST_PLUGIN", c.mode)
+			dir := t.TempDir()
+			writeHelperManifest(t, dir, "factory", c.extra)
+			plugins, err := LoadDir(dir)
+			if err != nil {
+				t.Fatalf("LoadDir() = %v", err)
+			}
+			_, err = plugins[0].Convert(context.Background(), Request{ResourceType: "mycorp_factory"})
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("Convert() = %v, want error containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadDir_errors(t *testing.T) {
+	cases := []struct {
+		name      string
+		manifests map[string]string
+	}{
+		{name: "missing command", manifests: map[string]string{"a.yaml": "resource_types: [mycorp_a]\n"}},
+		{name: "missing resource types", manifests: map[string]string{"a.yaml": "command: [a]\n"}},
+		{name: "bad timeout", manifests: map[string]string{"a.yaml": "command: [a]\nresource_types: [mycorp_a]\ntimeout: soon\n"}},
+		{name: "unknown field", manifests: map[string]string{"a.yaml": "command: [a]\nresource_type: [mycorp_a]\n"}},
+		{
+			name: "duplicate resource type",
+			manifests: map[string]string{
+				"a.yaml": "command: [a]\nresource_types: [mycorp_a]\n",
+				"b.json": `{"command": ["b"], "resource_types": ["mycorp_a"]}`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range c.manifests {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := LoadDir(dir); err == nil {
+				t.Error("LoadDir() = nil error, want error")
+			}
+		})
+	}
+}
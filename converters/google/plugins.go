@@ -0,0 +1,69 @@
+package google
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/plugin"
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// SetPlugins sets the external plugins converting the resource types that
+// have no built-in converter. Their assets get ancestors and are merged with
+// other assets like those of built-in converters.
+func (c *Converter) SetPlugins(plugins []*plugin.Plugin) error {
+	c.plugins = make(map[string]*plugin.Plugin)
+	for _, p := range plugins {
+		for _, t := range p.ResourceTypes {
+			if _, ok := c.converters[t]; ok {
+				return fmt.Errorf("plugin %s: resource type %s has a built-in converter", p.Name, t)
+			}
+			if other, ok := c.plugins[t]; ok {
+				return fmt.Errorf("resource type %s is converted by both plugins %s and %s", t, other.Name, p.Name)
+			}
+			c.plugins[t] = p
+		}
+	}
+	return nil
+}
+
+// pluginConverter returns a ResourceConverter running p for a resource
+// change with the given values.
+func (c *Converter) pluginConverter(p *plugin.Plugin, rc *tfjson.ResourceChange, values map[string]interface{}) resources.ResourceConverter {
+	return resources.ResourceConverter{
+		Convert: func(d resources.TerraformResourceData, cfg *resources.Config) ([]resources.Asset, error) {
+			req := plugin.Request{
+				ResourceType:  rc.Type,
+				Address:       rc.Address,
+				PlannedValues: values,
+			}
+			if cfg != nil {
+				req.Provider = plugin.ProviderDefaults{Project: cfg.Project, Region: cfg.Region, Zone: cfg.Zone}
+			}
+			assets, err := p.Convert(c.ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			if len(assets) == 0 {
+				return nil, resources.ErrNoConversion
+			}
+			return assets, nil
+		},
+		MergeCreateUpdate: resources.MergeExternalAsset,
+	}
+}
+
+// pluginSchema returns a schema for the top-level string values of a resource
+// converted by a plugin, so that its project, folder or organization can be
+// read for its ancestry.
+func pluginSchema(values map[string]interface{}) map[string]*schema.Schema {
+	s := make(map[string]*schema.Schema)
+	for k, v := range values {
+		if _, ok := v.(string); ok {
+			s[k] = &schema.Schema{Type: schema.TypeString, Optional: true}
+		}
+	}
+	return s
+}
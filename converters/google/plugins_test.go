@@ -0,0 +1,116 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/plugin"
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHelperProjectFactoryPlugin is not a test: it is run as a plugin by
+// TestAddResourceChanges_plugins when TFV_TEST_PLUGIN is set.
+func TestHelperProjectFactoryPlugin(t *testing.T) {
+	if os.Getenv("TFV_TEST_PLUGIN") == "" {
+		return
+	}
+	var req plugin.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(1)
+	}
+	project := req.PlannedValues["project"].(string)
+	json.NewEncoder(os.Stdout).Encode(plugin.Response{Assets: []resources.Asset{{
+		Name: "//cloudresourcemanager.googleapis.com/projects/" + project,
+		Type: "cloudresourcemanager.googleapis.com/Project",
+		Resource: &resources.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://www.googleapis.com/discovery/v1/apis/cloudresourcemanager/v1/rest",
+			DiscoveryName:        "Project",
+			Data:                 map[string]interface{}{"projectId": project},
+		},
+		IAMPolicy: &resources.IAMPolicy{Bindings: []resources.IAMBinding{
+			{Role: "roles/owner", Members: []string{"group:factory@example.com"}},
+		}},
+	}}})
+	os.Exit(0)
+}
+
+func TestAddResourceChanges_plugins(t *testing.T) {
+	t.Setenv("TFV_TEST_PLUGIN", "1")
+
+	cfg, err := resources.NewConfig(context.Background(), testProject, "", "", true, "", nil)
+	if err != nil {
+		t.Fatalf("constructing configuration: %s", err)
+	}
+	errorLogger, _ := newTestErrorLogger()
+	ancestryManager, err := ancestrymanager.New(cfg, true, map[string]string{testProject: "organizations/123/folders/456"}, errorLogger)
+	if err != nil {
+		t.Fatalf("building ancestry manager: %s", err)
+	}
+	c := NewConverter(cfg, ancestryManager, true, false, errorLogger)
+	err = c.SetPlugins([]*plugin.Plugin{{
+		Name:          "project-factory",
+		Command:       []string{os.Args[0], "-test.run=TestHelperProjectFactoryPlugin"},
+		ResourceTypes: []string{"mycorp_gcp_project_factory"},
+	}})
+	assert.Nil(t, err)
+
+	changes := []*tfjson.ResourceChange{
+		{
+			Address:      "mycorp_gcp_project_factory.project",
+			Mode:         "managed",
+			Type:         "mycorp_gcp_project_factory",
+			Name:         "project",
+			ProviderName: "registry.terraform.io/mycorp/mycorp",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"labels":  map[string]interface{}{"team": "platform"},
+				},
+			},
+		},
+		{
+			Address:      "google_project_iam_member.owner",
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "owner",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/owner",
+					"member":  "user:owner@example.com",
+				},
+			},
+		},
+	}
+	assert.Nil(t, c.AddResourceChanges(changes))
+
+	assets := c.Assets()
+	if assert.Len(t, assets, 1) {
+		project := assets[0]
+		assert.Equal(t, "//cloudresourcemanager.googleapis.com/projects/"+testProject, project.Name)
+		assert.Equal(t, []string{"projects/" + testProject, "folders/456", "organizations/123"}, project.Ancestors)
+		assert.Equal(t, map[string]interface{}{"projectId": testProject}, project.Resource.Data)
+		assert.Equal(t, []IAMBinding{
+			{Role: "roles/owner", Members: []string{"group:factory@example.com", "user:owner@example.com"}},
+		}, project.IAMPolicy.Bindings)
+		assert.Equal(t, []string{"mycorp_gcp_project_factory.project"}, project.GrantAddresses[IAMGrant{Role: "roles/owner", Member: "group:factory@example.com"}])
+	}
+}
+
+func TestSetPlugins_builtInType(t *testing.T) {
+	c, _, err := newTestConverter(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetPlugins([]*plugin.Plugin{{Name: "bucket", Command: []string{"bucket"}, ResourceTypes: []string{"google_storage_bucket"}}})
+	assert.NotNil(t, err)
+}
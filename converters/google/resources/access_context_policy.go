@@ -0,0 +1,52 @@
+package google
+
+import "strings"
+
+const accessContextManagerAssetNamePrefix = "//accesscontextmanager.googleapis.com/"
+
+// AccessContextPolicy is an access policy, access level or service perimeter
+// in the JSON format of the Access Context Manager API, which CAI uses for the
+// access_context_policy of VPC Service Controls assets.
+type AccessContextPolicy map[string]interface{}
+
+// withAccessContextPolicy wraps the converter of an Access Context Manager
+// resource so that, besides the generic resource data, its assets carry the
+// access policy, access level or service perimeter payload used by CAI.
+func withAccessContextPolicy(c ResourceConverter) ResourceConverter {
+	convert := c.Convert
+	c.Convert = func(d TerraformResourceData, config *Config) ([]Asset, error) {
+		assets, err := convert(d, config)
+		if err != nil {
+			return assets, err
+		}
+		for i := range assets {
+			setAccessContextPolicy(&assets[i])
+		}
+		return assets, nil
+	}
+	return c
+}
+
+// setAccessContextPolicy fills the access_context_policy of an Access Context
+// Manager asset from its resource data.
+func setAccessContextPolicy(asset *Asset) {
+	if asset.Resource == nil {
+		return
+	}
+	policy := AccessContextPolicy{}
+	for k, v := range asset.Resource.Data {
+		policy[k] = v
+	}
+	// The resource data of access policies has no name, as it is assigned by
+	// the server.
+	policy["name"] = strings.TrimPrefix(asset.Name, accessContextManagerAssetNamePrefix)
+
+	switch asset.Type {
+	case AccessContextManagerAccessPolicyAssetType:
+		asset.AccessPolicy = policy
+	case AccessContextManagerAccessLevelAssetType:
+		asset.AccessLevel = policy
+	case AccessContextManagerServicePerimeterAssetType:
+		asset.ServicePerimeter = policy
+	}
+}
@@ -36,6 +36,11 @@ type Asset struct {
 	IAMPolicy     *IAMPolicy       `json:"iam_policy,omitempty"`
 	OrgPolicy     []*OrgPolicy     `json:"org_policy,omitempty"`
 	V2OrgPolicies []*V2OrgPolicies `json:"v2_org_policies,omitempty"`
+	// At most one of the access_context_policy payloads is set, for VPC
+	// Service Controls assets.
+	AccessPolicy     AccessContextPolicy `json:"access_policy,omitempty"`
+	AccessLevel      AccessContextPolicy `json:"access_level,omitempty"`
+	ServicePerimeter AccessContextPolicy `json:"service_perimeter,omitempty"`
 }
 
 // AssetResource is the Asset's Resource field.
@@ -63,12 +68,28 @@ type Folder struct {
 }
 
 type IAMPolicy struct {
-	Bindings []IAMBinding `json:"bindings"`
+	Bindings     []IAMBinding     `json:"bindings"`
+	AuditConfigs []IAMAuditConfig `json:"audit_configs,omitempty"`
+}
+
+// IAMAuditConfig configures audit logging of a service, or of all services if
+// Service is "allServices".
+type IAMAuditConfig struct {
+	Service         string              `json:"service"`
+	AuditLogConfigs []IAMAuditLogConfig `json:"audit_log_configs,omitempty"`
+}
+
+// IAMAuditLogConfig configures the logging of a type of permission.
+type IAMAuditLogConfig struct {
+	LogType         string   `json:"log_type"`
+	ExemptedMembers []string `json:"exempted_members,omitempty"`
 }
 
 type IAMBinding struct {
 	Role    string   `json:"role"`
 	Members []string `json:"members"`
+	// Condition is nil for unconditional bindings.
+	Condition *Expr `json:"condition,omitempty"`
 }
 
 type OrgPolicy struct {
@@ -143,20 +164,18 @@ type RestoreDefault struct {
 func assetName(d TerraformResourceData, config *Config, linkTmpl string) (string, error) {
 	re := regexp.MustCompile("{{([%[:word:]]+)}}")
 
-	// workaround for empty project
-	placeholderSet := false
+	// workaround for empty project. The placeholder is set on a copy so that
+	// concurrent conversions sharing config never observe it.
 	if config.Project == "" {
-		config.Project = fmt.Sprintf("placeholder-%s", RandString(8))
-		placeholderSet = true
+		placeholderConfig := *config
+		placeholderConfig.Project = fmt.Sprintf("placeholder-%s", RandString(8))
+		config = &placeholderConfig
 	}
 
 	f, err := buildReplacementFunc(re, d, config, linkTmpl, false)
 	if err != nil {
 		return "", err
 	}
-	if placeholderSet {
-		config.Project = ""
-	}
 
 	fWithPlaceholder := func(key string) string {
 		val := f(key)
@@ -1808,7 +1808,7 @@ func (c *Config) GetCredentials(clientScopes []string, initialCredentialsOnly bo
 
 // Remove the `/{{version}}/` from a base path if present.
 func RemoveBasePathVersion(url string) string {
-	re := regexp.MustCompile(`(?P<base>http[s]://.*)(?P<version>/[^/]+?/$)`)
+	re := regexp.MustCompile(`(?P<base>https?://.*)(?P<version>/[^/]+?/$)`)
 	return re.ReplaceAllString(url, "$1/")
 }
 
@@ -0,0 +1,270 @@
+package google
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Transforms of the values of ConverterDefinition fields.
+const (
+	// TransformCamelCase converts the keys of the nested objects of a value
+	// to camelCase.
+	TransformCamelCase = "camel_case"
+	// TransformObject converts a block with at most one item to an object
+	// with camelCase keys.
+	TransformObject = "object"
+	// TransformLabels converts a map to a map of strings.
+	TransformLabels = "labels"
+	// TransformSelfLink converts a self link to its v1 version.
+	TransformSelfLink = "self_link"
+	// TransformResourceName keeps the last segment of a self link.
+	TransformResourceName = "resource_name"
+)
+
+// ConverterDefinition declares a converter that copies Terraform attributes
+// to the fields of the API object of a single asset, like most generated
+// converters. Definitions are read from YAML files like:
+//
+//	resource_type: google_pubsub_topic
+//	asset_type: pubsub.googleapis.com/Topic
+//	asset_name: //pubsub.googleapis.com/projects/{{project}}/topics/{{name}}
+//	discovery:
+//	  version: v1
+//	  document_uri: https://www.googleapis.com/discovery/v1/apis/pubsub/v1/rest
+//	  name: Topic
+//	fields:
+//	- attribute: kms_key_name
+//	- attribute: labels
+//	  transform: labels
+//	- attribute: message_storage_policy
+//	  transform: object
+type ConverterDefinition struct {
+	ResourceType string `json:"resource_type"`
+	AssetType    string `json:"asset_type"`
+	// AssetName is a template of the asset name, like the ones of assetName.
+	AssetName string              `json:"asset_name"`
+	Discovery DiscoveryDefinition `json:"discovery"`
+	Fields    []FieldDefinition   `json:"fields"`
+}
+
+// DiscoveryDefinition is the discovery information of the assets of a
+// ConverterDefinition.
+type DiscoveryDefinition struct {
+	Version     string `json:"version"`
+	DocumentURI string `json:"document_uri"`
+	Name        string `json:"name"`
+}
+
+// FieldDefinition maps a Terraform attribute, or a template, to a field of
+// the API object.
+type FieldDefinition struct {
+	// Attribute is the Terraform attribute, which may be nested like
+	// "network_interface.0.network".
+	Attribute string `json:"attribute,omitempty"`
+	// Template builds the value from the attributes of the resource, like
+	// "projects/{{project}}/global/networks/{{network}}", instead of
+	// Attribute.
+	Template string `json:"template,omitempty"`
+	// Field is the API field, which may be nested like "settings.tier". It
+	// defaults to the last segment of Attribute in camelCase.
+	Field string `json:"field,omitempty"`
+	// Transform is one of the Transform constants, or empty to copy the
+	// value as is.
+	Transform string `json:"transform,omitempty"`
+}
+
+var fieldTransforms = map[string]func(interface{}) interface{}{
+	"":                    func(v interface{}) interface{} { return v },
+	TransformCamelCase:    camelCaseKeys,
+	TransformObject:       expandDeclarativeObject,
+	TransformLabels:       expandDeclarativeLabels,
+	TransformSelfLink:     func(v interface{}) interface{} { return ConvertSelfLinkToV1(fmt.Sprint(v)) },
+	TransformResourceName: func(v interface{}) interface{} { return GetResourceNameFromSelfLink(fmt.Sprint(v)) },
+}
+
+// ReadConverterDefinitions reads the converter definitions of the .yaml and
+// .yml files of dir, one definition per file.
+func ReadConverterDefinitions(dir string) ([]*ConverterDefinition, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("listing converter definitions in %s: %w", dir, err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var defs []*ConverterDefinition
+	types := make(map[string]string)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading converter definition: %w", err)
+		}
+		def := &ConverterDefinition{}
+		if err := yaml.UnmarshalStrict(content, def); err != nil {
+			return nil, fmt.Errorf("parsing converter definition %s: %w", file, err)
+		}
+		if err := def.validate(); err != nil {
+			return nil, fmt.Errorf("converter definition %s: %w", file, err)
+		}
+		if other, ok := types[def.ResourceType]; ok {
+			return nil, fmt.Errorf("resource type %s is defined in both %s and %s", def.ResourceType, other, file)
+		}
+		types[def.ResourceType] = file
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (def *ConverterDefinition) validate() error {
+	switch {
+	case def.ResourceType == "":
+		return fmt.Errorf("missing resource_type")
+	case def.AssetType == "":
+		return fmt.Errorf("missing asset_type")
+	case def.AssetName == "":
+		return fmt.Errorf("missing asset_name")
+	}
+	for _, f := range def.Fields {
+		if (f.Attribute == "") == (f.Template == "") {
+			return fmt.Errorf("field %q: exactly one of attribute and template must be set", f.Field)
+		}
+		if f.Template != "" && f.Field == "" {
+			return fmt.Errorf("template field %q: missing field", f.Template)
+		}
+		if _, ok := fieldTransforms[f.Transform]; !ok {
+			return fmt.Errorf("field %q: unknown transform %q", f.Attribute+f.Template, f.Transform)
+		}
+	}
+	return nil
+}
+
+// ResourceConverter returns the converter declared by def.
+func (def *ConverterDefinition) ResourceConverter() ResourceConverter {
+	return ResourceConverter{
+		AssetType: def.AssetType,
+		Convert:   def.convert,
+	}
+}
+
+func (def *ConverterDefinition) convert(d TerraformResourceData, config *Config) ([]Asset, error) {
+	name, err := assetName(d, config, def.AssetName)
+	if err != nil {
+		return []Asset{}, err
+	}
+	obj := make(map[string]interface{})
+	for _, f := range def.Fields {
+		var v interface{}
+		if f.Template != "" {
+			v, err = replaceVars(d, config, f.Template)
+			if err != nil {
+				return []Asset{}, fmt.Errorf("field %s: %w", f.Field, err)
+			}
+		} else {
+			v = d.Get(f.Attribute)
+		}
+		if s, ok := v.(*schema.Set); ok {
+			v = s.List()
+		}
+		if isEmptyValue(reflect.ValueOf(v)) {
+			continue
+		}
+		v = fieldTransforms[f.Transform](v)
+		if isEmptyValue(reflect.ValueOf(v)) {
+			continue
+		}
+		setNestedField(obj, f.apiField(), v)
+	}
+	return []Asset{{
+		Name: name,
+		Type: def.AssetType,
+		Resource: &AssetResource{
+			Version:              def.Discovery.Version,
+			DiscoveryDocumentURI: def.Discovery.DocumentURI,
+			DiscoveryName:        def.Discovery.Name,
+			Data:                 obj,
+		},
+	}}, nil
+}
+
+func (f FieldDefinition) apiField() string {
+	if f.Field != "" {
+		return f.Field
+	}
+	parts := strings.Split(f.Attribute, ".")
+	return camelCase(parts[len(parts)-1])
+}
+
+func setNestedField(obj map[string]interface{}, field string, v interface{}) {
+	parts := strings.Split(field, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := obj[p].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			obj[p] = next
+		}
+		obj = next
+	}
+	obj[parts[len(parts)-1]] = v
+}
+
+// camelCase converts a snake_case Terraform attribute name to camelCase.
+func camelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func camelCaseKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[camelCase(k)] = camelCaseKeys(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = camelCaseKeys(e)
+		}
+		return l
+	case *schema.Set:
+		return camelCaseKeys(v.List())
+	}
+	return v
+}
+
+func expandDeclarativeObject(v interface{}) interface{} {
+	l, ok := v.([]interface{})
+	if !ok {
+		return camelCaseKeys(v)
+	}
+	if len(l) == 0 || l[0] == nil {
+		return nil
+	}
+	return camelCaseKeys(l[0])
+}
+
+func expandDeclarativeLabels(v interface{}) interface{} {
+	m := make(map[string]string)
+	if raw, ok := v.(map[string]interface{}); ok {
+		for k, val := range raw {
+			m[k] = fmt.Sprint(val)
+		}
+	}
+	return m
+}
@@ -0,0 +1,163 @@
+package google
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/tfdata"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const pubsubTopicDefinition = `
+resource_type: google_pubsub_topic
+asset_type: pubsub.googleapis.com/Topic
+asset_name: //pubsub.googleapis.com/projects/{{project}}/topics/{{name}}
+discovery:
+  version: v1
+  document_uri: https://www.googleapis.com/discovery/v1/apis/pubsub/v1/rest
+  name: Topic
+fields:
+- attribute: kms_key_name
+- attribute: labels
+  transform: labels
+- attribute: message_storage_policy
+  transform: object
+- attribute: schema_settings
+  transform: object
+- attribute: message_retention_duration
+`
+
+func writeConverterDefinitions(t *testing.T, defs map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range defs {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConverterDefinition_pubsubTopic(t *testing.T) {
+	defs, err := ReadConverterDefinitions(writeConverterDefinitions(t, map[string]string{"pubsub_topic.yaml": pubsubTopicDefinition}))
+	if err != nil {
+		t.Fatalf("ReadConverterDefinitions() = %s, want = nil", err)
+	}
+	if len(defs) != 1 {
+		t.Fatalf("ReadConverterDefinitions() = %d definitions, want 1", len(defs))
+	}
+
+	// The provider version in go.mod is not a dependency of this package.
+	resourceSchema := map[string]*schema.Schema{
+		"name":         {Type: schema.TypeString, Required: true},
+		"project":      {Type: schema.TypeString, Optional: true},
+		"kms_key_name": {Type: schema.TypeString, Optional: true},
+		"labels":       {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+		"message_storage_policy": {Type: schema.TypeList, Optional: true, MaxItems: 1, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"allowed_persistence_regions": {Type: schema.TypeList, Required: true, Elem: &schema.Schema{Type: schema.TypeString}},
+		}}},
+		"schema_settings": {Type: schema.TypeList, Optional: true, MaxItems: 1, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"schema":   {Type: schema.TypeString, Required: true},
+			"encoding": {Type: schema.TypeString, Optional: true},
+		}}},
+		"message_retention_duration": {Type: schema.TypeString, Optional: true},
+	}
+	d := tfdata.NewFakeResourceData("google_pubsub_topic", resourceSchema, map[string]interface{}{
+		"name":         "my-topic",
+		"project":      "my-project",
+		"kms_key_name": "projects/my-project/locations/global/keyRings/ring/cryptoKeys/key",
+		"labels":       map[string]interface{}{"env": "prod"},
+		"message_storage_policy": []interface{}{map[string]interface{}{
+			"allowed_persistence_regions": []interface{}{"europe-west1"},
+		}},
+	})
+
+	want, err := GetPubsubTopicCaiObject(d, &Config{})
+	if err != nil {
+		t.Fatalf("GetPubsubTopicCaiObject() = %s, want = nil", err)
+	}
+	got, err := defs[0].ResourceConverter().Convert(d, &Config{})
+	if err != nil {
+		t.Fatalf("Convert() = %s, want = nil", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() returned unexpected diff (-generated +declarative):\n%s", diff)
+	}
+}
+
+func TestConverterDefinition_templatesAndTransforms(t *testing.T) {
+	def := &ConverterDefinition{
+		ResourceType: "google_compute_subnetwork",
+		AssetType:    "compute.googleapis.com/Subnetwork",
+		AssetName:    "//compute.googleapis.com/projects/{{project}}/regions/{{region}}/subnetworks/{{name}}",
+		Fields: []FieldDefinition{
+			{Attribute: "network", Transform: TransformSelfLink},
+			{Template: "projects/{{project}}/regions/{{region}}", Field: "location.region"},
+			{Attribute: "log_config", Field: "logConfig", Transform: TransformCamelCase},
+			{Attribute: "description"},
+		},
+	}
+	if err := def.validate(); err != nil {
+		t.Fatalf("validate() = %s, want = nil", err)
+	}
+	resourceSchema := map[string]*schema.Schema{
+		"name":        {Type: schema.TypeString, Required: true},
+		"project":     {Type: schema.TypeString, Optional: true},
+		"region":      {Type: schema.TypeString, Optional: true},
+		"network":     {Type: schema.TypeString, Required: true},
+		"description": {Type: schema.TypeString, Optional: true},
+		"log_config": {Type: schema.TypeList, Optional: true, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"flow_sampling": {Type: schema.TypeFloat, Optional: true},
+		}}},
+	}
+	d := tfdata.NewFakeResourceData("google_compute_subnetwork", resourceSchema, map[string]interface{}{
+		"name":       "subnet",
+		"project":    "my-project",
+		"region":     "us-central1",
+		"network":    "https://www.googleapis.com/compute/beta/projects/my-project/global/networks/default",
+		"log_config": []interface{}{map[string]interface{}{"flow_sampling": 0.5}},
+	})
+
+	got, err := def.ResourceConverter().Convert(d, &Config{})
+	if err != nil {
+		t.Fatalf("Convert() = %s, want = nil", err)
+	}
+	want := []Asset{{
+		Name: "//compute.googleapis.com/projects/my-project/regions/us-central1/subnetworks/subnet",
+		Type: "compute.googleapis.com/Subnetwork",
+		Resource: &AssetResource{
+			Data: map[string]interface{}{
+				"network":   "https://www.googleapis.com/compute/v1/projects/my-project/global/networks/default",
+				"location":  map[string]interface{}{"region": "projects/my-project/regions/us-central1"},
+				"logConfig": []interface{}{map[string]interface{}{"flowSampling": 0.5}},
+			},
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadConverterDefinitions_errors(t *testing.T) {
+	const header = "resource_type: google_pubsub_topic\nasset_type: pubsub.googleapis.com/Topic\nasset_name: //pubsub.googleapis.com/{{name}}\n"
+	cases := []struct {
+		name string
+		defs map[string]string
+	}{
+		{name: "missing resource type", defs: map[string]string{"a.yaml": "asset_type: a\nasset_name: a\n"}},
+		{name: "missing asset name", defs: map[string]string{"a.yaml": "resource_type: google_a\nasset_type: a\n"}},
+		{name: "unknown field", defs: map[string]string{"a.yaml": header + "asset_version: v1\n"}},
+		{name: "attribute and template", defs: map[string]string{"a.yaml": header + "fields:\n- attribute: name\n  template: '{{name}}'\n  field: name\n"}},
+		{name: "template without field", defs: map[string]string{"a.yaml": header + "fields:\n- template: '{{name}}'\n"}},
+		{name: "unknown transform", defs: map[string]string{"a.yaml": header + "fields:\n- attribute: name\n  transform: upper\n"}},
+		{name: "duplicate resource type", defs: map[string]string{"a.yaml": header, "b.yml": header}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := ReadConverterDefinitions(writeConverterDefinitions(t, c.defs)); err == nil {
+				t.Error("ReadConverterDefinitions() = nil error, want error")
+			}
+		})
+	}
+}
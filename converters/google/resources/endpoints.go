@@ -0,0 +1,131 @@
+package google
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// endpointEnvVarExceptions are the environment variables of the provider's
+// custom endpoints that do not follow GOOGLE_{SERVICE}_CUSTOM_ENDPOINT with
+// the service in upper snake case.
+var endpointEnvVarExceptions = map[string]string{
+	ContainerAwsBasePathKey:   "GOOGLE_CONTAINERAWS_CUSTOM_ENDPOINT",
+	ContainerAzureBasePathKey: "GOOGLE_CONTAINERAZURE_CUSTOM_ENDPOINT",
+}
+
+var camelCaseBoundaryRE = regexp.MustCompile(`([a-z0-9])([A-Z])|([A-Z])([A-Z][a-z])`)
+
+// EndpointEnvVar returns the environment variable overriding the base path of
+// a service, given by its key in DefaultBasePaths. It is the one of the
+// provider's custom endpoint, like GOOGLE_RESOURCE_MANAGER_CUSTOM_ENDPOINT.
+func EndpointEnvVar(key string) string {
+	if v, ok := endpointEnvVarExceptions[key]; ok {
+		return v
+	}
+	snake := camelCaseBoundaryRE.ReplaceAllString(key, "${1}${3}_${2}${4}")
+	return "GOOGLE_" + strings.ToUpper(snake) + "_CUSTOM_ENDPOINT"
+}
+
+// BasePathsFromEnv returns the base paths set by the environment variables of
+// EndpointEnvVar, keyed like DefaultBasePaths.
+func BasePathsFromEnv() map[string]string {
+	basePaths := map[string]string{}
+	for key := range DefaultBasePaths {
+		if v := os.Getenv(EndpointEnvVar(key)); v != "" {
+			basePaths[key] = v
+		}
+	}
+	return basePaths
+}
+
+// ReadBasePathsFile reads a YAML or JSON file mapping services to base paths,
+// for example:
+//
+//	resource_manager: https://crm-myendpoint.p.googleapis.com/v1/
+//	ResourceManagerV3: https://crm-myendpoint.p.googleapis.com/v3/
+//	storage_custom_endpoint: http://localhost:8080/storage/v1/
+//
+// Services are given by their keys in DefaultBasePaths, or by the names of
+// the provider's custom endpoint fields, in any case. The result is keyed like
+// DefaultBasePaths.
+func ReadBasePathsFile(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading endpoints file: %w", err)
+	}
+	var entries map[string]string
+	if err := yaml.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("parsing endpoints file %s: %w", path, err)
+	}
+	basePaths := make(map[string]string, len(entries))
+	for name, basePath := range entries {
+		key, ok := BasePathKey(name)
+		if !ok {
+			return nil, fmt.Errorf("endpoints file %s: unknown service %q", path, name)
+		}
+		if _, ok := basePaths[key]; ok {
+			return nil, fmt.Errorf("endpoints file %s: service %q is set more than once", path, key)
+		}
+		basePaths[key] = basePath
+	}
+	return basePaths, nil
+}
+
+// BasePathKey returns the key in DefaultBasePaths of a service name, which is
+// matched ignoring case, underscores and a "_custom_endpoint" suffix.
+func BasePathKey(name string) (string, bool) {
+	normalized := strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	normalized = strings.TrimSuffix(normalized, "customendpoint")
+	for key := range DefaultBasePaths {
+		if strings.ToLower(key) == normalized {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// OverrideBasePaths sets the base paths of c, keyed like DefaultBasePaths.
+// Base paths must be http or https URLs, and are given a trailing slash.
+func OverrideBasePaths(c *Config, basePaths map[string]string) error {
+	keys := make([]string, 0, len(basePaths))
+	for key := range basePaths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	v := reflect.ValueOf(c).Elem()
+	for _, key := range keys {
+		field := v.FieldByName(key + "BasePath")
+		if _, ok := DefaultBasePaths[key]; !ok || !field.IsValid() || field.Kind() != reflect.String {
+			return fmt.Errorf("unknown service %q", key)
+		}
+		basePath, err := normalizeBasePath(basePaths[key])
+		if err != nil {
+			return fmt.Errorf("base path of %s: %w", key, err)
+		}
+		field.SetString(basePath)
+	}
+	return nil
+}
+
+func normalizeBasePath(basePath string) (string, error) {
+	u, err := url.Parse(basePath)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https URL", basePath)
+	}
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+	return basePath, nil
+}
@@ -0,0 +1,133 @@
+package google
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointEnvVar(t *testing.T) {
+	cases := map[string]string{
+		ResourceManagerBasePathKey:   "GOOGLE_RESOURCE_MANAGER_CUSTOM_ENDPOINT",
+		ResourceManagerV3BasePathKey: "GOOGLE_RESOURCE_MANAGER_V3_CUSTOM_ENDPOINT",
+		StorageBasePathKey:           "GOOGLE_STORAGE_CUSTOM_ENDPOINT",
+		IAMBasePathKey:               "GOOGLE_IAM_CUSTOM_ENDPOINT",
+		IAMBetaBasePathKey:           "GOOGLE_IAM_BETA_CUSTOM_ENDPOINT",
+		BigQueryBasePathKey:          "GOOGLE_BIG_QUERY_CUSTOM_ENDPOINT",
+		GKEHubBasePathKey:            "GOOGLE_GKE_HUB_CUSTOM_ENDPOINT",
+		SQLBasePathKey:               "GOOGLE_SQL_CUSTOM_ENDPOINT",
+		ContainerAwsBasePathKey:      "GOOGLE_CONTAINERAWS_CUSTOM_ENDPOINT",
+	}
+	for key, want := range cases {
+		if got := EndpointEnvVar(key); got != want {
+			t.Errorf("EndpointEnvVar(%q) = %q, want = %q", key, got, want)
+		}
+	}
+}
+
+func TestBasePathKey(t *testing.T) {
+	cases := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "ResourceManager", want: ResourceManagerBasePathKey, wantOK: true},
+		{name: "resource_manager_v3", want: ResourceManagerV3BasePathKey, wantOK: true},
+		{name: "storage_custom_endpoint", want: StorageBasePathKey, wantOK: true},
+		{name: "iam", want: IAMBasePathKey, wantOK: true},
+		{name: "unknown", wantOK: false},
+	}
+	for _, c := range cases {
+		got, ok := BasePathKey(c.name)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("BasePathKey(%q) = %q, %t, want = %q, %t", c.name, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestReadBasePathsFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	got, err := ReadBasePathsFile(write("endpoints.yaml", `
+resource_manager: https://crm.p.googleapis.com/v1/
+ResourceManagerV3: https://crm.p.googleapis.com/v3/
+storage_custom_endpoint: http://localhost:8080/storage/v1/
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		ResourceManagerBasePathKey:   "https://crm.p.googleapis.com/v1/",
+		ResourceManagerV3BasePathKey: "https://crm.p.googleapis.com/v3/",
+		StorageBasePathKey:           "http://localhost:8080/storage/v1/",
+	}, got)
+
+	_, err = ReadBasePathsFile(write("unknown.yaml", "unknown: https://example.com/"))
+	assert.Error(t, err)
+	_, err = ReadBasePathsFile(write("twice.yaml", "storage: https://a.com/\nStorage: https://b.com/"))
+	assert.Error(t, err)
+	_, err = ReadBasePathsFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestOverrideBasePaths(t *testing.T) {
+	cfg := &Config{}
+	ConfigureBasePaths(cfg)
+	err := OverrideBasePaths(cfg, map[string]string{
+		ResourceManagerBasePathKey: "http://127.0.0.1:8080/v1",
+		StorageBasePathKey:         "https://storage.p.googleapis.com/storage/v1/",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8080/v1/", cfg.ResourceManagerBasePath)
+	assert.Equal(t, "https://storage.p.googleapis.com/storage/v1/", cfg.StorageBasePath)
+	assert.Equal(t, DefaultBasePaths[ResourceManagerV3BasePathKey], cfg.ResourceManagerV3BasePath)
+
+	assert.Error(t, OverrideBasePaths(cfg, map[string]string{"Unknown": "https://example.com/"}))
+	assert.Error(t, OverrideBasePaths(cfg, map[string]string{StorageBasePathKey: "storage.googleapis.com"}))
+}
+
+// TestOverrideBasePaths_allKeys checks that every service of DefaultBasePaths
+// has a base path in Config.
+func TestOverrideBasePaths_allKeys(t *testing.T) {
+	basePaths := map[string]string{}
+	for key := range DefaultBasePaths {
+		basePaths[key] = "https://example.com/" + key + "/"
+	}
+	assert.NoError(t, OverrideBasePaths(&Config{}, basePaths))
+}
+
+func TestRemoveBasePathVersion(t *testing.T) {
+	cases := map[string]string{
+		"https://cloudresourcemanager.googleapis.com/v1/": "https://cloudresourcemanager.googleapis.com/",
+		"http://127.0.0.1:8080/v3/":                       "http://127.0.0.1:8080/",
+		"http://127.0.0.1:8080/":                          "http://127.0.0.1:8080/",
+	}
+	for basePath, want := range cases {
+		if got := RemoveBasePathVersion(basePath); got != want {
+			t.Errorf("RemoveBasePathVersion(%q) = %q, want = %q", basePath, got, want)
+		}
+	}
+}
+
+func TestNewConfigWithOptions_invalidBasePaths(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewConfigWithOptions(ctx, "project", "", "", false, "", nil, ConfigOptions{
+		BasePaths: map[string]string{StorageBasePathKey: "not a url"},
+	})
+	assert.Error(t, err)
+
+	t.Setenv(EndpointEnvVar(ResourceManagerBasePathKey), "not a url")
+	_, err = NewConfigWithOptions(ctx, "project", "", "", false, "", nil, ConfigOptions{})
+	assert.Error(t, err)
+
+	// Offline configurations make no API calls, and ignore base paths.
+	_, err = NewConfigWithOptions(ctx, "project", "", "", true, "", nil, ConfigOptions{})
+	assert.NoError(t, err)
+}
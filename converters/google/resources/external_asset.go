@@ -0,0 +1,43 @@
+package google
+
+// MergeExternalAsset merges an asset converted outside of this package, such
+// as by a converter plugin, with an existing asset of the same type and name.
+// The resource data and access context policies of incoming replace those of
+// existing when set, IAM members are added to the existing bindings, audit
+// configs replace those of the same service and organization policies those
+// of the same constraint.
+func MergeExternalAsset(existing, incoming Asset) Asset {
+	if incoming.Resource != nil {
+		existing.Resource = incoming.Resource
+	}
+	if incoming.IAMPolicy != nil {
+		existing = mergeIamAssets(existing, incoming, mergeAdditiveBindings)
+		existing = mergeIamAuditConfigAssets(existing, Asset{IAMPolicy: &IAMPolicy{AuditConfigs: incoming.IAMPolicy.AuditConfigs}})
+	}
+	if len(incoming.OrgPolicy) > 0 {
+		policies := make([]*OrgPolicy, 0, len(existing.OrgPolicy)+len(incoming.OrgPolicy))
+		replaced := make(map[string]bool)
+		for _, p := range incoming.OrgPolicy {
+			replaced[p.Constraint] = true
+		}
+		for _, p := range existing.OrgPolicy {
+			if !replaced[p.Constraint] {
+				policies = append(policies, p)
+			}
+		}
+		existing.OrgPolicy = append(policies, incoming.OrgPolicy...)
+	}
+	if len(incoming.V2OrgPolicies) > 0 {
+		existing = MergeV2OrgPolicies(existing, incoming)
+	}
+	if incoming.AccessPolicy != nil {
+		existing.AccessPolicy = incoming.AccessPolicy
+	}
+	if incoming.AccessLevel != nil {
+		existing.AccessLevel = incoming.AccessLevel
+	}
+	if incoming.ServicePerimeter != nil {
+		existing.ServicePerimeter = incoming.ServicePerimeter
+	}
+	return existing
+}
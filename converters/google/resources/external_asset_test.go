@@ -0,0 +1,47 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeExternalAsset(t *testing.T) {
+	existing := Asset{
+		Name:      "//cloudresourcemanager.googleapis.com/projects/my-project",
+		Type:      "cloudresourcemanager.googleapis.com/Project",
+		Resource:  &AssetResource{Data: map[string]interface{}{"projectId": "my-project"}},
+		IAMPolicy: &IAMPolicy{Bindings: []IAMBinding{{Role: "roles/owner", Members: []string{"user:b@example.com"}}}},
+		OrgPolicy: []*OrgPolicy{
+			{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{Enforced: true}},
+			{Constraint: "constraints/iam.disableServiceAccountKeyCreation", BooleanPolicy: &BooleanPolicy{Enforced: true}},
+		},
+	}
+	incoming := Asset{
+		Name: existing.Name,
+		Type: existing.Type,
+		IAMPolicy: &IAMPolicy{
+			Bindings:     []IAMBinding{{Role: "roles/owner", Members: []string{"user:a@example.com"}}},
+			AuditConfigs: []IAMAuditConfig{{Service: "allServices"}},
+		},
+		OrgPolicy: []*OrgPolicy{
+			{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{}},
+		},
+	}
+	want := Asset{
+		Name:     existing.Name,
+		Type:     existing.Type,
+		Resource: existing.Resource,
+		IAMPolicy: &IAMPolicy{
+			Bindings:     []IAMBinding{{Role: "roles/owner", Members: []string{"user:a@example.com", "user:b@example.com"}}},
+			AuditConfigs: []IAMAuditConfig{{Service: "allServices"}},
+		},
+		OrgPolicy: []*OrgPolicy{
+			{Constraint: "constraints/iam.disableServiceAccountKeyCreation", BooleanPolicy: &BooleanPolicy{Enforced: true}},
+			{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &BooleanPolicy{}},
+		},
+	}
+	if diff := cmp.Diff(want, MergeExternalAsset(existing, incoming)); diff != "" {
+		t.Errorf("MergeExternalAsset() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -30,8 +30,22 @@ func resourceConverterFolderIamMember() ResourceConverter {
 	}
 }
 
+func resourceConverterFolderIamAuditConfig() ResourceConverter {
+	return ResourceConverter{
+		AssetType:         "cloudresourcemanager.googleapis.com/Folder",
+		Convert:           GetFolderIamAuditConfigCaiObject,
+		FetchFullResource: FetchFolderIamPolicy,
+		MergeCreateUpdate: MergeFolderIamAuditConfig,
+		MergeDelete:       MergeFolderIamAuditConfigDelete,
+	}
+}
+
 func GetFolderIamPolicyCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
-	return newFolderIamAsset(d, config, expandIamPolicyBindings)
+	assets, err := newFolderIamAsset(d, config, expandIamPolicyBindings)
+	if err != nil {
+		return assets, err
+	}
+	return addIamPolicyAuditConfigs(d, assets)
 }
 
 func GetFolderIamBindingCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
@@ -42,6 +56,10 @@ func GetFolderIamMemberCaiObject(d TerraformResourceData, config *Config) ([]Ass
 	return newFolderIamAsset(d, config, expandIamMemberBindings)
 }
 
+func GetFolderIamAuditConfigCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
+	return newIamAuditConfigAsset(d, config, newFolderIamAsset)
+}
+
 func MergeFolderIamPolicy(existing, incoming Asset) Asset {
 	existing.IAMPolicy = incoming.IAMPolicy
 	return existing
@@ -63,6 +81,14 @@ func MergeFolderIamMemberDelete(existing, incoming Asset) Asset {
 	return mergeDeleteIamAssets(existing, incoming, mergeDeleteAdditiveBindings)
 }
 
+func MergeFolderIamAuditConfig(existing, incoming Asset) Asset {
+	return mergeIamAuditConfigAssets(existing, incoming)
+}
+
+func MergeFolderIamAuditConfigDelete(existing, incoming Asset) Asset {
+	return mergeDeleteIamAuditConfigAssets(existing, incoming)
+}
+
 func newFolderIamAsset(
 	d TerraformResourceData,
 	config *Config,
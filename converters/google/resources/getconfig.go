@@ -3,7 +3,9 @@ package google
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/logging"
 	"github.com/pkg/errors"
 )
 
@@ -17,7 +19,26 @@ func (c *Config) GetClient() *http.Client {
 	return c.Client
 }
 
+// ConfigOptions holds the optional inputs of NewConfigWithOptions.
+type ConfigOptions struct {
+	// BasePaths overrides the base paths of services, keyed like
+	// DefaultBasePaths (see ReadBasePathsFile). It takes precedence over the
+	// environment variables of EndpointEnvVar.
+	BasePaths map[string]string
+}
+
 func NewConfig(ctx context.Context, project, zone, region string, offline bool, userAgent string, client *http.Client) (*Config, error) {
+	return NewConfigWithOptions(ctx, project, zone, region, offline, userAgent, client, ConfigOptions{})
+}
+
+// NewConfigWithOptions is like NewConfig, with the optional inputs given by
+// opts. The base paths of services default to the public endpoints, and can
+// be overridden by environment variables (see EndpointEnvVar) and opts, for
+// example to use Private Service Connect endpoints or local fakes. If client is
+// set, it is used for API calls instead of a client authenticated with the
+// default credentials, with the request logging, retries and headers of the
+// default client.
+func NewConfigWithOptions(ctx context.Context, project, zone, region string, offline bool, userAgent string, client *http.Client, opts ConfigOptions) (*Config, error) {
 	cfg := &Config{
 		Project:   project,
 		Zone:      zone,
@@ -42,13 +63,65 @@ func NewConfig(ctx context.Context, project, zone, region string, offline bool,
 
 	if !offline {
 		ConfigureBasePaths(cfg)
-		if err := cfg.LoadAndValidate(ctx); err != nil {
-			return nil, errors.Wrap(err, "load and validate config")
+		if err := OverrideBasePaths(cfg, BasePathsFromEnv()); err != nil {
+			return nil, errors.Wrap(err, "custom endpoint environment variables")
+		}
+		if err := OverrideBasePaths(cfg, opts.BasePaths); err != nil {
+			return nil, errors.Wrap(err, "custom endpoints")
 		}
 		if client != nil {
-			cfg.Client = client
+			// The client brings its own authentication, so default
+			// credentials are neither required nor loaded.
+			cfg.useClient(ctx, client)
+			return cfg, nil
 		}
+		if err := cfg.LoadAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "load and validate config")
+		}
+	} else {
+		// Converters that look up data, like images, get an error rather
+		// than a nil client.
+		cfg.Client = &http.Client{Transport: offlineTransport{}}
 	}
 
 	return cfg, nil
 }
+
+// offlineTransport fails the API calls of offline configs.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.Errorf("%s %s: API calls are not made in offline mode", req.Method, req.URL)
+}
+
+// useClient sets up c like LoadAndValidate, with client instead of a client
+// authenticated with the default credentials. The transport of client is
+// wrapped like the default transport, and client is not modified.
+func (c *Config) useClient(ctx context.Context, client *http.Client) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	loggingTransport := logging.NewTransport("Google", base)
+	retryTransport := NewTransportWithDefaultRetries(loggingTransport)
+	headerTransport := newTransportWithHeaders(retryTransport)
+	if c.RequestReason != "" {
+		headerTransport.Set("X-Goog-Request-Reason", c.RequestReason)
+	}
+	if c.UserProjectOverride && c.BillingProject != "" {
+		headerTransport.Set("X-Goog-User-Project", c.BillingProject)
+	}
+
+	wrapped := *client
+	wrapped.Transport = headerTransport
+	if wrapped.Timeout == 0 {
+		wrapped.Timeout = c.synchronousTimeout()
+	}
+
+	c.Client = &wrapped
+	c.context = ctx
+	c.Region = GetRegionFromRegionSelfLink(c.Region)
+	c.RequestBatcherServiceUsage = NewRequestBatcher("Service Usage", ctx, c.BatchingConfig)
+	c.requestBatcherIam = NewRequestBatcher("IAM", ctx, c.BatchingConfig)
+	c.PollInterval = 10 * time.Second
+}
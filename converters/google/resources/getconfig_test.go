@@ -3,6 +3,7 @@ package google
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -141,11 +142,32 @@ func TestNewConfigUserAgent_nilClientUsesDefault(t *testing.T) {
 func TestNewConfigUserAgent_usesPassedClient(t *testing.T) {
 	ctx := context.Background()
 	offline := false
-	client := &http.Client{}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	client := server.Client()
+	transport := client.Transport
 	cfg, err := NewConfig(ctx, "project", "", "", offline, "", client)
 	if err != nil {
 		t.Fatalf("error building config: %s", err)
 	}
 
-	assert.Exactly(t, client, cfg.Client)
+	// The client is wrapped to retry temporary errors, without being modified.
+	assert.Exactly(t, transport, client.Transport)
+	resp, err := cfg.Client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("error sending request: %s", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, requests)
+	assert.NotNil(t, cfg.RequestBatcherServiceUsage)
+	assert.NotZero(t, cfg.PollInterval)
 }
@@ -9,30 +9,61 @@ import (
 	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
 )
 
-// expandIamPolicyBindings is used in google_<type>_iam_policy resources.
-func expandIamPolicyBindings(d TerraformResourceData) ([]IAMBinding, error) {
+// expandIamPolicy reads the policy_data of google_<type>_iam_policy resources.
+// It returns nil if the policy is (known after apply).
+func expandIamPolicy(d TerraformResourceData) (*cloudresourcemanager.Policy, error) {
 	ps := d.Get("policy_data").(string)
-	var bindings []IAMBinding
 	// policy_data is (known after apply) in terraform plan, hence an empty string
 	if ps == "" {
-		return bindings, nil
+		return nil, nil
 	}
 	// The policy string is just a marshaled cloudresourcemanager.Policy.
 	policy := &cloudresourcemanager.Policy{}
 	if err := json.Unmarshal([]byte(ps), policy); err != nil {
 		return nil, fmt.Errorf("Could not unmarshal %s: %v", ps, err)
 	}
+	return policy, nil
+}
+
+// expandIamPolicyBindings is used in google_<type>_iam_policy resources.
+func expandIamPolicyBindings(d TerraformResourceData) ([]IAMBinding, error) {
+	var bindings []IAMBinding
+	policy, err := expandIamPolicy(d)
+	if err != nil || policy == nil {
+		return bindings, err
+	}
 
 	for _, b := range policy.Bindings {
 		bindings = append(bindings, IAMBinding{
-			Role:    b.Role,
-			Members: b.Members,
+			Role:      b.Role,
+			Members:   b.Members,
+			Condition: flattenIamBindingCondition(b.Condition),
 		})
 	}
 
 	return bindings, nil
 }
 
+// addIamPolicyAuditConfigs adds the audit configs of the policy_data of
+// google_<type>_iam_policy resources to their assets, for resource types that
+// support audit configs.
+func addIamPolicyAuditConfigs(d TerraformResourceData, assets []Asset) ([]Asset, error) {
+	policy, err := expandIamPolicy(d)
+	if err != nil {
+		return []Asset{}, fmt.Errorf("expanding audit configs: %v", err)
+	}
+	if policy == nil {
+		return assets, nil
+	}
+	auditConfigs := flattenIamAuditConfigs(policy.AuditConfigs)
+	for i := range assets {
+		if assets[i].IAMPolicy != nil {
+			assets[i].IAMPolicy.AuditConfigs = auditConfigs
+		}
+	}
+	return assets, nil
+}
+
 // expandIamRoleBindings is used in google_<type>_iam_binding resources.
 func expandIamRoleBindings(d TerraformResourceData) ([]IAMBinding, error) {
 	var members []string
@@ -41,8 +72,9 @@ func expandIamRoleBindings(d TerraformResourceData) ([]IAMBinding, error) {
 	}
 	return []IAMBinding{
 		{
-			Role:    d.Get("role").(string),
-			Members: members,
+			Role:      d.Get("role").(string),
+			Members:   members,
+			Condition: expandIamBindingCondition(d.Get("condition")),
 		},
 	}, nil
 }
@@ -51,12 +83,131 @@ func expandIamRoleBindings(d TerraformResourceData) ([]IAMBinding, error) {
 func expandIamMemberBindings(d TerraformResourceData) ([]IAMBinding, error) {
 	return []IAMBinding{
 		{
-			Role:    d.Get("role").(string),
-			Members: []string{d.Get("member").(string)},
+			Role:      d.Get("role").(string),
+			Members:   []string{d.Get("member").(string)},
+			Condition: expandIamBindingCondition(d.Get("condition")),
 		},
 	}, nil
 }
 
+// expandIamBindingCondition expands the condition block of
+// google_<type>_iam_binding and google_<type>_iam_member resources. It returns
+// nil if there is no condition, including for resources without the block.
+func expandIamBindingCondition(v interface{}) *Expr {
+	l, ok := v.([]interface{})
+	if !ok || len(l) == 0 || l[0] == nil {
+		return nil
+	}
+	original, ok := l[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	condition := &Expr{}
+	condition.Expression, _ = original["expression"].(string)
+	condition.Title, _ = original["title"].(string)
+	condition.Description, _ = original["description"].(string)
+	if bindingConditionKey(condition).Empty() {
+		return nil
+	}
+	return condition
+}
+
+// flattenIamBindingCondition converts the condition of an API binding.
+func flattenIamBindingCondition(condition *cloudresourcemanager.Expr) *Expr {
+	if conditionKeyFromCondition(condition).Empty() {
+		return nil
+	}
+	return &Expr{
+		Expression:  condition.Expression,
+		Title:       condition.Title,
+		Description: condition.Description,
+		Location:    condition.Location,
+	}
+}
+
+// bindingConditionKey identifies a condition the same way as the provider
+// does when it merges bindings, ignoring its location.
+func bindingConditionKey(condition *Expr) conditionKey {
+	if condition == nil {
+		return conditionKey{}
+	}
+	return conditionKey{condition.Description, condition.Expression, condition.Title}
+}
+
+// bindingKey identifies the binding of a role with a condition, as IAM policies
+// may contain several bindings of the same role with different conditions.
+func bindingKey(binding IAMBinding) iamBindingKey {
+	return iamBindingKey{binding.Role, bindingConditionKey(binding.Condition)}
+}
+
+// expandIamAuditConfig is used in google_<type>_iam_audit_config resources.
+func expandIamAuditConfig(d TerraformResourceData) []IAMAuditConfig {
+	auditConfig := IAMAuditConfig{
+		Service: d.Get("service").(string),
+	}
+	for _, v := range d.Get("audit_log_config").(*schema.Set).List() {
+		raw, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		logConfig := IAMAuditLogConfig{}
+		logConfig.LogType, _ = raw["log_type"].(string)
+		if members, ok := raw["exempted_members"].(*schema.Set); ok {
+			for _, m := range members.List() {
+				logConfig.ExemptedMembers = append(logConfig.ExemptedMembers, m.(string))
+			}
+		}
+		auditConfig.AuditLogConfigs = append(auditConfig.AuditLogConfigs, logConfig)
+	}
+	sortIamAuditLogConfigs(auditConfig.AuditLogConfigs)
+	return []IAMAuditConfig{auditConfig}
+}
+
+// newIamAuditConfigAsset builds the asset of a google_<type>_iam_audit_config
+// resource. newIamAsset builds the IAM policy assets of the resource type.
+func newIamAuditConfigAsset(
+	d TerraformResourceData,
+	config *Config,
+	newIamAsset func(d TerraformResourceData, config *Config, expandBindings func(d TerraformResourceData) ([]IAMBinding, error)) ([]Asset, error),
+) ([]Asset, error) {
+	noBindings := func(d TerraformResourceData) ([]IAMBinding, error) {
+		return nil, nil
+	}
+	assets, err := newIamAsset(d, config, noBindings)
+	if err != nil {
+		return assets, err
+	}
+	for i := range assets {
+		assets[i].IAMPolicy.AuditConfigs = expandIamAuditConfig(d)
+	}
+	return assets, nil
+}
+
+func flattenIamAuditConfigs(auditConfigs []*cloudresourcemanager.AuditConfig) []IAMAuditConfig {
+	var result []IAMAuditConfig
+	for _, ac := range auditConfigs {
+		auditConfig := IAMAuditConfig{Service: ac.Service}
+		for _, lc := range ac.AuditLogConfigs {
+			auditConfig.AuditLogConfigs = append(auditConfig.AuditLogConfigs, IAMAuditLogConfig{
+				LogType:         lc.LogType,
+				ExemptedMembers: lc.ExemptedMembers,
+			})
+		}
+		sortIamAuditLogConfigs(auditConfig.AuditLogConfigs)
+		result = append(result, auditConfig)
+	}
+	return result
+}
+
+func sortIamAuditLogConfigs(logConfigs []IAMAuditLogConfig) {
+	for i := range logConfigs {
+		sort.Strings(logConfigs[i].ExemptedMembers)
+	}
+	sort.Slice(logConfigs, func(i, j int) bool {
+		return logConfigs[i].LogType < logConfigs[j].LogType
+	})
+}
+
 // mergeIamAssets merges an existing asset with the IAM bindings of an incoming
 // Asset.
 func mergeIamAssets(
@@ -82,16 +233,59 @@ func mergeDeleteIamAssets(
 	return existing
 }
 
-// mergeAdditiveBindings adds members to bindings with the same roles and adds new
-// bindings for roles that dont exist.
+// mergeIamAuditConfigAssets merges an existing asset with the audit configs of
+// an incoming asset. Like google_<type>_iam_audit_config resources, the
+// incoming audit config of a service replaces the existing one.
+func mergeIamAuditConfigAssets(existing, incoming Asset) Asset {
+	if existing.IAMPolicy == nil {
+		existing.IAMPolicy = incoming.IAMPolicy
+		return existing
+	}
+	auditConfigs := mergeDeleteAuditConfigs(existing.IAMPolicy.AuditConfigs, incoming.IAMPolicy.AuditConfigs)
+	existing.IAMPolicy.AuditConfigs = append(auditConfigs, incoming.IAMPolicy.AuditConfigs...)
+	sort.Slice(existing.IAMPolicy.AuditConfigs, func(i, j int) bool {
+		return existing.IAMPolicy.AuditConfigs[i].Service < existing.IAMPolicy.AuditConfigs[j].Service
+	})
+	return existing
+}
+
+// mergeDeleteIamAuditConfigAssets removes the audit configs of the services of
+// an incoming asset from an existing asset. incoming is the last known state
+// of the asset prior to deletion.
+func mergeDeleteIamAuditConfigAssets(existing, incoming Asset) Asset {
+	if existing.IAMPolicy != nil {
+		existing.IAMPolicy.AuditConfigs = mergeDeleteAuditConfigs(existing.IAMPolicy.AuditConfigs, incoming.IAMPolicy.AuditConfigs)
+	}
+	return existing
+}
+
+// mergeDeleteAuditConfigs eliminates the audit configs of the services in
+// incoming from the existing list.
+func mergeDeleteAuditConfigs(existing, incoming []IAMAuditConfig) []IAMAuditConfig {
+	toDelete := make(map[string]struct{})
+	for _, auditConfig := range incoming {
+		toDelete[auditConfig.Service] = struct{}{}
+	}
+
+	var newExisting []IAMAuditConfig
+	for _, auditConfig := range existing {
+		if _, delete := toDelete[auditConfig.Service]; !delete {
+			newExisting = append(newExisting, auditConfig)
+		}
+	}
+	return newExisting
+}
+
+// mergeAdditiveBindings adds members to bindings with the same roles and
+// conditions and adds new bindings for roles and conditions that dont exist.
 func mergeAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
-	existingIdxs := make(map[string]int)
+	existingIdxs := make(map[iamBindingKey]int)
 	for i, binding := range existing {
-		existingIdxs[binding.Role] = i
+		existingIdxs[bindingKey(binding)] = i
 	}
 
 	for _, binding := range incoming {
-		if ei, ok := existingIdxs[binding.Role]; ok {
+		if ei, ok := existingIdxs[bindingKey(binding)]; ok {
 			memberExists := make(map[string]bool)
 			for _, m := range existing[ei].Members {
 				memberExists[m] = true
@@ -115,13 +309,18 @@ func mergeAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
 	return existing
 }
 
-// mergeDeleteAdditiveBindings eliminates listed members from roles in the
-// existing list. incoming is the last known state of the bindings being deleted.
+// mergeDeleteAdditiveBindings eliminates listed members from roles and
+// conditions in the existing list. incoming is the last known state of the
+// bindings being deleted.
 func mergeDeleteAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
-	toDelete := make(map[string]struct{})
+	type memberKey struct {
+		binding iamBindingKey
+		member  string
+	}
+	toDelete := make(map[memberKey]struct{})
 	for _, binding := range incoming {
 		for _, m := range binding.Members {
-			key := binding.Role + "-" + m
+			key := memberKey{bindingKey(binding), m}
 			toDelete[key] = struct{}{}
 		}
 	}
@@ -130,7 +329,7 @@ func mergeDeleteAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
 	for _, binding := range existing {
 		var newMembers []string
 		for _, m := range binding.Members {
-			key := binding.Role + "-" + m
+			key := memberKey{bindingKey(binding), m}
 			_, delete := toDelete[key]
 			if !delete {
 				newMembers = append(newMembers, m)
@@ -138,8 +337,9 @@ func mergeDeleteAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
 		}
 		if newMembers != nil {
 			newExisting = append(newExisting, IAMBinding{
-				Role:    binding.Role,
-				Members: newMembers,
+				Role:      binding.Role,
+				Members:   newMembers,
+				Condition: binding.Condition,
 			})
 		}
 	}
@@ -148,15 +348,16 @@ func mergeDeleteAdditiveBindings(existing, incoming []IAMBinding) []IAMBinding {
 }
 
 // mergeAuthoritativeBindings clobbers members to bindings with the same roles
-// and adds new bindings for roles that dont exist.
+// and conditions and adds new bindings for roles and conditions that dont
+// exist.
 func mergeAuthoritativeBindings(existing, incoming []IAMBinding) []IAMBinding {
-	existingIdxs := make(map[string]int)
+	existingIdxs := make(map[iamBindingKey]int)
 	for i, binding := range existing {
-		existingIdxs[binding.Role] = i
+		existingIdxs[bindingKey(binding)] = i
 	}
 
 	for _, binding := range incoming {
-		if ei, ok := existingIdxs[binding.Role]; ok {
+		if ei, ok := existingIdxs[bindingKey(binding)]; ok {
 			existing[ei].Members = binding.Members
 		} else {
 			existing = append(existing, binding)
@@ -172,18 +373,18 @@ func mergeAuthoritativeBindings(existing, incoming []IAMBinding) []IAMBinding {
 }
 
 // mergeDeleteAuthoritativeBindings eliminates any bindings with matching roles
-// in the existing list. incoming is the last known state of the bindings being
-// deleted.
+// and conditions in the existing list. incoming is the last known state of the
+// bindings being deleted.
 func mergeDeleteAuthoritativeBindings(existing, incoming []IAMBinding) []IAMBinding {
-	toDelete := make(map[string]struct{})
+	toDelete := make(map[iamBindingKey]struct{})
 	for _, binding := range incoming {
-		key := binding.Role
+		key := bindingKey(binding)
 		toDelete[key] = struct{}{}
 	}
 
 	var newExisting []IAMBinding
 	for _, binding := range existing {
-		key := binding.Role
+		key := bindingKey(binding)
 		_, delete := toDelete[key]
 		if !delete {
 			newExisting = append(newExisting, binding)
@@ -219,8 +420,9 @@ func fetchIamPolicy(
 		bindings = append(
 			bindings,
 			IAMBinding{
-				Role:    b.Role,
-				Members: b.Members,
+				Role:      b.Role,
+				Members:   b.Members,
+				Condition: flattenIamBindingCondition(b.Condition),
 			},
 		)
 	}
@@ -231,7 +433,8 @@ func fetchIamPolicy(
 		Name: name,
 		Type: assetType,
 		IAMPolicy: &IAMPolicy{
-			Bindings: bindings,
+			Bindings:     bindings,
+			AuditConfigs: flattenIamAuditConfigs(iamPolicy.AuditConfigs),
 		},
 	}, nil
 }
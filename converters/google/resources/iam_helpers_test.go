@@ -149,6 +149,64 @@ func TestMergeBindings(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Conditions",
+			existing: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-b"},
+					Condition: &Expr{Title: "expires", Expression: "request.time < timestamp(\"2030-01-01T00:00:00Z\")"},
+				},
+			},
+			incoming: []IAMBinding{
+				{
+					Role:      "role-a",
+					Members:   []string{"member-c"},
+					Condition: &Expr{Title: "expires", Expression: "request.time < timestamp(\"2030-01-01T00:00:00Z\")"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-d"},
+					Condition: &Expr{Title: "other", Expression: "true"},
+				},
+			},
+			expectedAdditive: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-b", "member-c"},
+					Condition: &Expr{Title: "expires", Expression: "request.time < timestamp(\"2030-01-01T00:00:00Z\")"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-d"},
+					Condition: &Expr{Title: "other", Expression: "true"},
+				},
+			},
+			expectedAuthoritative: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-c"},
+					Condition: &Expr{Title: "expires", Expression: "request.time < timestamp(\"2030-01-01T00:00:00Z\")"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-d"},
+					Condition: &Expr{Title: "other", Expression: "true"},
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name+"/mergeAdditiveBindings", func(t *testing.T) {
@@ -282,6 +340,44 @@ func TestMergeDeleteBindings(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Conditions",
+			existing: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a", "member-b"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-a", "member-b"},
+					Condition: &Expr{Title: "expires", Expression: "true"},
+				},
+			},
+			incoming: []IAMBinding{
+				{
+					Role:      "role-a",
+					Members:   []string{"member-a"},
+					Condition: &Expr{Title: "expires", Expression: "true"},
+				},
+			},
+			expectedDeleteAdditive: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a", "member-b"},
+				},
+				{
+					Role:      "role-a",
+					Members:   []string{"member-b"},
+					Condition: &Expr{Title: "expires", Expression: "true"},
+				},
+			},
+			expectedDeleteAuthoritative: []IAMBinding{
+				{
+					Role:    "role-a",
+					Members: []string{"member-a", "member-b"},
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name+"/mergeDeleteAdditiveBindings", func(t *testing.T) {
@@ -298,3 +394,75 @@ func TestMergeDeleteBindings(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandIamBindingCondition(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    interface{}
+		expected *Expr
+	}{
+		{
+			name:     "Nil",
+			input:    nil,
+			expected: nil,
+		},
+		{
+			name:     "Empty",
+			input:    []interface{}{},
+			expected: nil,
+		},
+		{
+			name: "EmptyCondition",
+			input: []interface{}{
+				map[string]interface{}{"expression": "", "title": "", "description": ""},
+			},
+			expected: nil,
+		},
+		{
+			name: "Condition",
+			input: []interface{}{
+				map[string]interface{}{"expression": "true", "title": "always", "description": "Always true"},
+			},
+			expected: &Expr{Expression: "true", Title: "always", Description: "Always true"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, expandIamBindingCondition(c.input))
+		})
+	}
+}
+
+func TestMergeIamAuditConfigAssets(t *testing.T) {
+	existing := Asset{
+		IAMPolicy: &IAMPolicy{
+			Bindings: []IAMBinding{{Role: "role-a", Members: []string{"member-a"}}},
+			AuditConfigs: []IAMAuditConfig{
+				{Service: "service-b", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "DATA_READ"}}},
+				{Service: "service-a", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "ADMIN_READ"}}},
+			},
+		},
+	}
+	incoming := Asset{
+		IAMPolicy: &IAMPolicy{
+			AuditConfigs: []IAMAuditConfig{
+				{Service: "service-b", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "DATA_WRITE"}}},
+			},
+		},
+	}
+
+	merged := mergeIamAuditConfigAssets(existing, incoming)
+	assert.Equal(t, []IAMBinding{{Role: "role-a", Members: []string{"member-a"}}}, merged.IAMPolicy.Bindings)
+	assert.Equal(t, []IAMAuditConfig{
+		{Service: "service-a", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "ADMIN_READ"}}},
+		{Service: "service-b", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "DATA_WRITE"}}},
+	}, merged.IAMPolicy.AuditConfigs)
+
+	deleted := mergeDeleteIamAuditConfigAssets(merged, incoming)
+	assert.Equal(t, []IAMAuditConfig{
+		{Service: "service-a", AuditLogConfigs: []IAMAuditLogConfig{{LogType: "ADMIN_READ"}}},
+	}, deleted.IAMPolicy.AuditConfigs)
+
+	// An incoming asset without existing state is used as is.
+	assert.Equal(t, incoming, mergeIamAuditConfigAssets(Asset{}, incoming))
+}
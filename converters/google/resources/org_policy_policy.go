@@ -47,8 +47,20 @@ func GetV2OrgPoliciesApiObject(d TerraformResourceData, config *Config) (V2OrgPo
 	}, nil
 }
 
+// MergeV2OrgPolicies adds the incoming policies to the existing asset,
+// replacing existing policies with the same name.
 func MergeV2OrgPolicies(existing, incoming Asset) Asset {
-	existing.Resource = incoming.Resource
+	policies := make([]*V2OrgPolicies, 0, len(existing.V2OrgPolicies)+len(incoming.V2OrgPolicies))
+	replaced := make(map[string]bool)
+	for _, p := range incoming.V2OrgPolicies {
+		replaced[p.Name] = true
+	}
+	for _, p := range existing.V2OrgPolicies {
+		if !replaced[p.Name] {
+			policies = append(policies, p)
+		}
+	}
+	existing.V2OrgPolicies = append(policies, incoming.V2OrgPolicies...)
 	return existing
 }
 
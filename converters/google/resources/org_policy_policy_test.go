@@ -0,0 +1,38 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeV2OrgPolicies(t *testing.T) {
+	policy := func(name string, enforce bool) *V2OrgPolicies {
+		return &V2OrgPolicies{Name: name, PolicySpec: &PolicySpec{PolicyRules: []*PolicyRule{{Enforce: enforce}}}}
+	}
+	resource := &AssetResource{DiscoveryName: "Project"}
+	existing := Asset{
+		Resource: resource,
+		V2OrgPolicies: []*V2OrgPolicies{
+			policy("projects/p/policies/a", true),
+			policy("projects/p/policies/b", true),
+		},
+	}
+	incoming := Asset{
+		V2OrgPolicies: []*V2OrgPolicies{
+			policy("projects/p/policies/b", false),
+			policy("projects/p/policies/c", true),
+		},
+	}
+	want := Asset{
+		Resource: resource,
+		V2OrgPolicies: []*V2OrgPolicies{
+			policy("projects/p/policies/a", true),
+			policy("projects/p/policies/b", false),
+			policy("projects/p/policies/c", true),
+		},
+	}
+	if diff := cmp.Diff(want, MergeV2OrgPolicies(existing, incoming)); diff != "" {
+		t.Errorf("MergeV2OrgPolicies() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
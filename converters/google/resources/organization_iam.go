@@ -30,8 +30,22 @@ func resourceConverterOrganizationIamMember() ResourceConverter {
 	}
 }
 
+func resourceConverterOrganizationIamAuditConfig() ResourceConverter {
+	return ResourceConverter{
+		AssetType:         "cloudresourcemanager.googleapis.com/Organization",
+		Convert:           GetOrganizationIamAuditConfigCaiObject,
+		FetchFullResource: FetchOrganizationIamPolicy,
+		MergeCreateUpdate: MergeOrganizationIamAuditConfig,
+		MergeDelete:       MergeOrganizationIamAuditConfigDelete,
+	}
+}
+
 func GetOrganizationIamPolicyCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
-	return newOrganizationIamAsset(d, config, expandIamPolicyBindings)
+	assets, err := newOrganizationIamAsset(d, config, expandIamPolicyBindings)
+	if err != nil {
+		return assets, err
+	}
+	return addIamPolicyAuditConfigs(d, assets)
 }
 
 func GetOrganizationIamBindingCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
@@ -42,6 +56,10 @@ func GetOrganizationIamMemberCaiObject(d TerraformResourceData, config *Config)
 	return newOrganizationIamAsset(d, config, expandIamMemberBindings)
 }
 
+func GetOrganizationIamAuditConfigCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
+	return newIamAuditConfigAsset(d, config, newOrganizationIamAsset)
+}
+
 func MergeOrganizationIamPolicy(existing, incoming Asset) Asset {
 	existing.IAMPolicy = incoming.IAMPolicy
 	return existing
@@ -63,6 +81,14 @@ func MergeOrganizationIamMemberDelete(existing, incoming Asset) Asset {
 	return mergeDeleteIamAssets(existing, incoming, mergeDeleteAdditiveBindings)
 }
 
+func MergeOrganizationIamAuditConfig(existing, incoming Asset) Asset {
+	return mergeIamAuditConfigAssets(existing, incoming)
+}
+
+func MergeOrganizationIamAuditConfigDelete(existing, incoming Asset) Asset {
+	return mergeDeleteIamAuditConfigAssets(existing, incoming)
+}
+
 func newOrganizationIamAsset(
 	d TerraformResourceData,
 	config *Config,
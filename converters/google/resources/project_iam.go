@@ -30,8 +30,22 @@ func resourceConverterProjectIamMember() ResourceConverter {
 	}
 }
 
+func resourceConverterProjectIamAuditConfig() ResourceConverter {
+	return ResourceConverter{
+		AssetType:         "cloudresourcemanager.googleapis.com/Project",
+		Convert:           GetProjectIamAuditConfigCaiObject,
+		FetchFullResource: FetchProjectIamPolicy,
+		MergeCreateUpdate: MergeProjectIamAuditConfig,
+		MergeDelete:       MergeProjectIamAuditConfigDelete,
+	}
+}
+
 func GetProjectIamPolicyCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
-	return newProjectIamAsset(d, config, expandIamPolicyBindings)
+	assets, err := newProjectIamAsset(d, config, expandIamPolicyBindings)
+	if err != nil {
+		return assets, err
+	}
+	return addIamPolicyAuditConfigs(d, assets)
 }
 
 func GetProjectIamBindingCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
@@ -42,6 +56,10 @@ func GetProjectIamMemberCaiObject(d TerraformResourceData, config *Config) ([]As
 	return newProjectIamAsset(d, config, expandIamMemberBindings)
 }
 
+func GetProjectIamAuditConfigCaiObject(d TerraformResourceData, config *Config) ([]Asset, error) {
+	return newIamAuditConfigAsset(d, config, newProjectIamAsset)
+}
+
 func MergeProjectIamPolicy(existing, incoming Asset) Asset {
 	existing.IAMPolicy = incoming.IAMPolicy
 	return existing
@@ -63,6 +81,14 @@ func MergeProjectIamMemberDelete(existing, incoming Asset) Asset {
 	return mergeDeleteIamAssets(existing, incoming, mergeDeleteAdditiveBindings)
 }
 
+func MergeProjectIamAuditConfig(existing, incoming Asset) Asset {
+	return mergeIamAuditConfigAssets(existing, incoming)
+}
+
+func MergeProjectIamAuditConfigDelete(existing, incoming Asset) Asset {
+	return mergeDeleteIamAuditConfigAssets(existing, incoming)
+}
+
 func newProjectIamAsset(
 	d TerraformResourceData,
 	config *Config,
@@ -77,7 +77,7 @@ func expandListOrganizationPolicy(configured []interface{}) (*ListPolicy, error)
 		values := allowMap["values"].(*schema.Set)
 
 		if all {
-			allValues = 1
+			allValues = 1 // ALLOW
 		} else {
 			allowedValues = convertStringArr(values.List())
 		}
@@ -89,7 +89,7 @@ func expandListOrganizationPolicy(configured []interface{}) (*ListPolicy, error)
 		values := denyMap["values"].(*schema.Set)
 
 		if all {
-			allValues = 0
+			allValues = 2 // DENY
 		} else {
 			deniedValues = convertStringArr(values.List())
 		}
@@ -0,0 +1,56 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestExpandListOrganizationPolicy(t *testing.T) {
+	cases := []struct {
+		name  string
+		allow map[string]interface{}
+		deny  map[string]interface{}
+		want  *ListPolicy
+	}{
+		{
+			name:  "allow all",
+			allow: map[string]interface{}{"all": true, "values": schema.NewSet(schema.HashString, nil)},
+			want:  &ListPolicy{AllValues: 1},
+		},
+		{
+			name: "deny all",
+			deny: map[string]interface{}{"all": true, "values": schema.NewSet(schema.HashString, nil)},
+			want: &ListPolicy{AllValues: 2},
+		},
+		{
+			name: "denied values",
+			deny: map[string]interface{}{"all": false, "values": schema.NewSet(schema.HashString, []interface{}{"cloudresourcemanager.googleapis.com"})},
+			want: &ListPolicy{DeniedValues: []string{"cloudresourcemanager.googleapis.com"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			listPolicy := map[string]interface{}{
+				"allow":               []interface{}{},
+				"deny":                []interface{}{},
+				"suggested_value":     "",
+				"inherit_from_parent": false,
+			}
+			if c.allow != nil {
+				listPolicy["allow"] = []interface{}{c.allow}
+			}
+			if c.deny != nil {
+				listPolicy["deny"] = []interface{}{c.deny}
+			}
+			got, err := expandListOrganizationPolicy([]interface{}{listPolicy})
+			if err != nil {
+				t.Fatalf("expandListOrganizationPolicy() = %s, want = nil", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("expandListOrganizationPolicy() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
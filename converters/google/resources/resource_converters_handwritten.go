@@ -0,0 +1,27 @@
+package google
+
+// ResourceConverters is generated by Magic Modules. Converters that are
+// registered or wrapped by hand live here, so that they survive regeneration.
+
+// AllResourceConverters returns the generated ResourceConverters, with the
+// handwritten converters added to or replacing the generated ones.
+func AllResourceConverters() map[string][]ResourceConverter {
+	converters := ResourceConverters()
+	for resourceType, c := range handwrittenResourceConverters() {
+		converters[resourceType] = c
+	}
+	return converters
+}
+
+func handwrittenResourceConverters() map[string][]ResourceConverter {
+	return map[string][]ResourceConverter{
+		// Access Context Manager assets carry their access_context_policy.
+		"google_access_context_manager_service_perimeter": {withAccessContextPolicy(resourceConverterAccessContextManagerServicePerimeter())},
+		"google_access_context_manager_access_policy":     {withAccessContextPolicy(resourceConverterAccessContextManagerAccessPolicy())},
+		"google_access_context_manager_access_level":      {withAccessContextPolicy(resourceConverterAccessContextManagerAccessLevel())},
+		// IAM audit configs are merged into the IAM policy of their resource.
+		"google_organization_iam_audit_config": {resourceConverterOrganizationIamAuditConfig()},
+		"google_folder_iam_audit_config":       {resourceConverterFolderIamAuditConfig()},
+		"google_project_iam_audit_config":      {resourceConverterProjectIamAuditConfig()},
+	}
+}
@@ -0,0 +1,450 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakegcp is a fake of the Google Cloud APIs that are called to
+// convert plans online: the ancestry and project lookups of Cloud Resource
+// Manager v1 and v3, the bucket lookups of Cloud Storage, and the
+// getIamPolicy methods of all services. It serves seeded projects, folders,
+// buckets and IAM policies, and can make requests fail, so that online
+// conversions are tested without credentials:
+//
+//	fake := fakegcp.NewServer()
+//	defer fake.Close()
+//	fake.AddProject(fakegcp.Project{ID: "my-project", Number: "123", Parent: "organizations/456"})
+//	fake.SetIamPolicy("//cloudresourcemanager.googleapis.com/projects/my-project", policy)
+//	fake.AddFault(fakegcp.Fault{Name: "//storage.googleapis.com/my-bucket", Code: 403})
+//	opts := tfgcv.ReadOptions{HTTPClient: fake.Client(), BasePaths: fake.BasePaths()}
+//
+// Each service is served under the path of its host and base path, for
+// example /cloudresourcemanager.googleapis.com/v1/.
+package fakegcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+)
+
+const (
+	crmHost     = "cloudresourcemanager.googleapis.com"
+	storageHost = "storage.googleapis.com"
+)
+
+// Methods of requests, as recorded in Request and matched by Fault.
+const (
+	MethodGet          = "get"
+	MethodGetAncestry  = "getAncestry"
+	MethodGetIamPolicy = "getIamPolicy"
+)
+
+// Project is a project of the fake.
+type Project struct {
+	ID     string
+	Number string
+	// Parent is the name of the parent folder or organization, like
+	// "folders/123" or "organizations/456".
+	Parent string
+}
+
+// Folder is a folder of the fake.
+type Folder struct {
+	ID string
+	// Parent is the name of the parent folder or organization.
+	Parent string
+}
+
+// Bucket is a storage bucket of the fake.
+type Bucket struct {
+	Name          string
+	ProjectNumber string
+}
+
+// Fault makes the matching requests fail.
+type Fault struct {
+	// Name is the full resource name of the requests, like
+	// "//cloudresourcemanager.googleapis.com/projects/my-project" or
+	// "//storage.googleapis.com/my-bucket". Projects are named by ID. Empty
+	// matches all resources.
+	Name string
+	// Method is the method of the requests, one of MethodGet,
+	// MethodGetAncestry and MethodGetIamPolicy. Empty matches all methods.
+	Method string
+	// Code is the HTTP status code of the error, like 403, 404, 429 or 500.
+	Code int
+	// Count is the number of requests that fail, after which the fault is
+	// removed. Zero fails all requests.
+	Count int
+}
+
+// Request is a request received by the fake.
+type Request struct {
+	// Name is the full resource name of the request, with projects named by
+	// ID when they are known.
+	Name   string
+	Method string
+	// Code is the HTTP status code of the response.
+	Code int
+}
+
+// Server is a fake of Google Cloud APIs. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// prefixes are the paths that services are served under, like
+	// /storage.googleapis.com/storage/v1/.
+	prefixes []string
+
+	mu       sync.Mutex
+	projects map[string]Project
+	folders  map[string]Folder
+	buckets  map[string]Bucket
+	policies map[string]*crmv1.Policy
+	faults   []*Fault
+	requests []Request
+}
+
+// NewServer starts a fake with no resources. It must be closed.
+func NewServer() *Server {
+	s := &Server{
+		projects: map[string]Project{},
+		folders:  map[string]Folder{},
+		buckets:  map[string]Bucket{},
+		policies: map[string]*crmv1.Policy{},
+	}
+	seen := map[string]bool{}
+	for _, basePath := range resources.DefaultBasePaths {
+		prefix := basePathPrefix(basePath)
+		if !seen[prefix] {
+			seen[prefix] = true
+			s.prefixes = append(s.prefixes, prefix)
+		}
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// hostTemplateRE matches the location templates of regional hosts, like
+// "{{region}}-" in "{{region}}-aiplatform.googleapis.com".
+var hostTemplateRE = regexp.MustCompile(`^\{\{\w+\}\}-`)
+
+// basePathPrefix returns the path that the service of a default base path is
+// served under.
+func basePathPrefix(basePath string) string {
+	hostPath := strings.TrimPrefix(basePath, "https://")
+	return "/" + hostTemplateRE.ReplaceAllString(hostPath, "")
+}
+
+// BasePaths returns the base paths of all services on the fake, keyed like
+// resources.DefaultBasePaths, for resources.ConfigOptions and
+// tfgcv.ReadOptions.
+func (s *Server) BasePaths() map[string]string {
+	basePaths := make(map[string]string, len(resources.DefaultBasePaths))
+	for key, basePath := range resources.DefaultBasePaths {
+		basePaths[key] = s.URL + basePathPrefix(basePath)
+	}
+	return basePaths
+}
+
+// Client returns a client of the fake. resources.NewConfig wraps it to retry
+// requests like its default clients, so faults test the production retries.
+func (s *Server) Client() *http.Client {
+	return s.Server.Client()
+}
+
+// AddProject adds or replaces a project.
+func (s *Server) AddProject(p Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects[p.ID] = p
+}
+
+// AddFolder adds or replaces a folder.
+func (s *Server) AddFolder(f Folder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.folders[f.ID] = f
+}
+
+// AddBucket adds or replaces a storage bucket.
+func (s *Server) AddBucket(b Bucket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[b.Name] = b
+}
+
+// SetIamPolicy sets the IAM policy of a resource, given by its full resource
+// name with projects named by ID. Projects, folders and organizations have an
+// empty policy by default, and other resources have none.
+func (s *Server) SetIamPolicy(name string, policy *crmv1.Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[name] = policy
+}
+
+// AddFault makes the requests matching f fail.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// call is a parsed request.
+type call struct {
+	host string
+	// version is the path of the service after its host, like "v1/" or
+	// "storage/v1/".
+	version  string
+	resource string
+	method   string
+	// bucket is set for the storage buckets and their IAM policies.
+	bucket bool
+}
+
+// parse returns the call of r, or false if the fake does not serve it.
+func (s *Server) parse(r *http.Request) (call, bool) {
+	var prefix string
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(r.URL.Path, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return call{}, false
+	}
+	host := strings.SplitN(strings.TrimPrefix(prefix, "/"), "/", 2)[0]
+	c := call{
+		host:     host,
+		version:  strings.TrimPrefix(prefix, "/"+host+"/"),
+		resource: strings.TrimPrefix(r.URL.Path, prefix),
+		method:   MethodGet,
+	}
+	if i := strings.LastIndex(c.resource, ":"); i > strings.LastIndex(c.resource, "/") {
+		c.resource, c.method = c.resource[:i], c.resource[i+1:]
+	}
+	if c.host == storageHost && strings.HasPrefix(c.resource, "b/") {
+		// Buckets are b/{bucket}, and their IAM policies b/{bucket}/iam.
+		c.resource, c.bucket = strings.TrimPrefix(c.resource, "b/"), true
+		if strings.HasSuffix(c.resource, "/iam") {
+			c.resource, c.method = strings.TrimSuffix(c.resource, "/iam"), MethodGetIamPolicy
+		}
+		if strings.Contains(c.resource, "/") {
+			return call{}, false
+		}
+	}
+	switch {
+	case c.method == MethodGetIamPolicy:
+		return c, r.Method == http.MethodGet || r.Method == http.MethodPost
+	case c.method == MethodGetAncestry:
+		return c, r.Method == http.MethodPost && c.host == crmHost && c.version == "v1/"
+	case c.method == MethodGet && r.Method == http.MethodGet:
+		return c, (c.host == crmHost && c.version == "v3/") || c.bucket
+	}
+	return call{}, false
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.parse(r)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Sprintf("fakegcp does not implement %s %s", r.Method, r.URL.Path))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.resource = s.canonicalResource(c.host, c.resource)
+	req := Request{Name: "//" + c.host + "/" + c.resource, Method: c.method}
+	var resp interface{}
+	if f := s.fault(req); f != nil {
+		req.Code = f.Code
+	} else {
+		resp, req.Code = s.serve(c)
+	}
+	s.requests = append(s.requests, req)
+
+	if req.Code != http.StatusOK {
+		writeError(w, req.Code, fmt.Sprintf("%s %s failed", req.Method, req.Name))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// canonicalResource names the projects of resources by ID when they are
+// known.
+func (s *Server) canonicalResource(host, resource string) string {
+	if host != crmHost || !strings.HasPrefix(resource, "projects/") {
+		return resource
+	}
+	if p, ok := s.project(strings.TrimPrefix(resource, "projects/")); ok {
+		return "projects/" + p.ID
+	}
+	return resource
+}
+
+// fault returns the fault matching req, if any, and removes it once it has
+// failed its number of requests.
+func (s *Server) fault(req Request) *Fault {
+	for i, f := range s.faults {
+		if (f.Name != "" && f.Name != req.Name) || (f.Method != "" && f.Method != req.Method) {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// serve returns the response of c and its status code.
+func (s *Server) serve(c call) (interface{}, int) {
+	switch {
+	case c.bucket && c.method == MethodGet:
+		b, ok := s.buckets[c.resource]
+		if !ok {
+			return nil, http.StatusNotFound
+		}
+		return map[string]string{"name": b.Name, "projectNumber": b.ProjectNumber}, http.StatusOK
+	case c.method == MethodGetAncestry:
+		return s.ancestry(strings.TrimPrefix(c.resource, "projects/"))
+	case c.method == MethodGetIamPolicy:
+		if policy, ok := s.policies["//"+c.host+"/"+c.resource]; ok {
+			return policy, http.StatusOK
+		}
+		if c.host != crmHost {
+			return nil, http.StatusNotFound
+		}
+		if _, code := s.get(c.resource); code != http.StatusOK {
+			return nil, code
+		}
+		return &crmv1.Policy{}, http.StatusOK
+	default:
+		return s.get(c.resource)
+	}
+}
+
+// get returns a resource manager project, folder or organization. Like Cloud
+// Resource Manager, it answers 403 for unknown resources.
+func (s *Server) get(name string) (interface{}, int) {
+	kind, id := name, ""
+	if i := strings.Index(name, "/"); i >= 0 {
+		kind, id = name[:i], name[i+1:]
+	}
+	switch kind {
+	case "projects":
+		if p, ok := s.project(id); ok {
+			return map[string]string{"name": "projects/" + p.Number, "projectId": p.ID, "parent": p.Parent}, http.StatusOK
+		}
+	case "folders":
+		if f, ok := s.folders[id]; ok {
+			return map[string]string{"name": "folders/" + f.ID, "parent": f.Parent}, http.StatusOK
+		}
+	case "organizations":
+		if id != "" {
+			return map[string]string{"name": name}, http.StatusOK
+		}
+	}
+	return nil, http.StatusForbidden
+}
+
+// ancestry returns the getAncestry response of a project: the project, its
+// folders and its organization.
+func (s *Server) ancestry(id string) (interface{}, int) {
+	p, ok := s.project(id)
+	if !ok {
+		return nil, http.StatusForbidden
+	}
+	resp := &crmv1.GetAncestryResponse{Ancestor: []*crmv1.Ancestor{
+		{ResourceId: &crmv1.ResourceId{Type: "project", Id: p.ID}},
+	}}
+	for parent := p.Parent; parent != ""; {
+		kind, id := parent, ""
+		if i := strings.Index(parent, "/"); i >= 0 {
+			kind, id = parent[:i], parent[i+1:]
+		}
+		resp.Ancestor = append(resp.Ancestor, &crmv1.Ancestor{
+			ResourceId: &crmv1.ResourceId{Type: strings.TrimSuffix(kind, "s"), Id: id},
+		})
+		if kind != "folders" {
+			break
+		}
+		parent = s.folders[id].Parent
+	}
+	return resp, http.StatusOK
+}
+
+// project returns a project by ID or number.
+func (s *Server) project(id string) (Project, bool) {
+	if p, ok := s.projects[id]; ok {
+		return p, true
+	}
+	for _, p := range s.projects {
+		if p.Number != "" && p.Number == id {
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+// writeError writes an error in the format of Google APIs.
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+			"status":  errorStatus(code),
+		},
+	})
+}
+
+// errorStatus returns the canonical status of an HTTP status code.
+func errorStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusNotImplemented:
+		return "UNIMPLEMENTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegcp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+
+	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
+)
+
+func newTestConfig(t *testing.T, s *Server) *resources.Config {
+	t.Helper()
+	cfg, err := resources.NewConfigWithOptions(context.Background(), "my-project", "", "", false, "", s.Client(), resources.ConfigOptions{
+		BasePaths: s.BasePaths(),
+	})
+	require.NoError(t, err)
+	return cfg
+}
+
+func newSeededServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer()
+	t.Cleanup(s.Close)
+	s.AddFolder(Folder{ID: "1", Parent: "organizations/100"})
+	s.AddFolder(Folder{ID: "2", Parent: "folders/1"})
+	s.AddProject(Project{ID: "my-project", Number: "123", Parent: "folders/2"})
+	s.AddBucket(Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	return s
+}
+
+func errorCode(err error) int {
+	if gerr, ok := err.(*googleapi.Error); ok {
+		return gerr.Code
+	}
+	return 0
+}
+
+func TestResourceManager(t *testing.T) {
+	s := newSeededServer(t)
+	cfg := newTestConfig(t, s)
+	v1 := cfg.NewResourceManagerClient("")
+	v3 := cfg.NewResourceManagerV3Client("")
+
+	ancestry, err := v1.Projects.GetAncestry("my-project", &crmv1.GetAncestryRequest{}).Do()
+	require.NoError(t, err)
+	var got []string
+	for _, a := range ancestry.Ancestor {
+		got = append(got, a.ResourceId.Type+"/"+a.ResourceId.Id)
+	}
+	assert.Equal(t, []string{"project/my-project", "folder/2", "folder/1", "organization/100"}, got)
+
+	project, err := v3.Projects.Get("projects/123").Do()
+	require.NoError(t, err)
+	assert.Equal(t, "projects/123", project.Name)
+	assert.Equal(t, "my-project", project.ProjectId)
+	assert.Equal(t, "folders/2", project.Parent)
+
+	folder, err := v3.Folders.Get("folders/2").Do()
+	require.NoError(t, err)
+	assert.Equal(t, "folders/1", folder.Parent)
+
+	_, err = v1.Projects.GetAncestry("unknown", &crmv1.GetAncestryRequest{}).Do()
+	assert.Equal(t, http.StatusForbidden, errorCode(err))
+	_, err = v3.Folders.Get("folders/3").Do()
+	assert.Equal(t, http.StatusForbidden, errorCode(err))
+}
+
+func TestStorage(t *testing.T) {
+	s := newSeededServer(t)
+	cfg := newTestConfig(t, s)
+	storage := cfg.NewStorageClient("")
+
+	bucket, err := storage.Buckets.Get("my-bucket").Do()
+	require.NoError(t, err)
+	assert.EqualValues(t, 123, bucket.ProjectNumber)
+
+	_, err = storage.Buckets.Get("unknown").Do()
+	assert.Equal(t, http.StatusNotFound, errorCode(err))
+}
+
+func TestGetIamPolicy(t *testing.T) {
+	s := newSeededServer(t)
+	cfg := newTestConfig(t, s)
+	policy := &crmv1.Policy{Bindings: []*crmv1.Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com"}}}}
+	s.SetIamPolicy("//cloudresourcemanager.googleapis.com/projects/my-project", policy)
+	s.SetIamPolicy("//storage.googleapis.com/my-bucket", policy)
+	s.SetIamPolicy("//pubsub.googleapis.com/projects/my-project/topics/topic", policy)
+
+	// Projects are found by number too.
+	got, err := cfg.NewResourceManagerClient("").Projects.GetIamPolicy("123", &crmv1.GetIamPolicyRequest{}).Do()
+	require.NoError(t, err)
+	assert.Equal(t, policy.Bindings, got.Bindings)
+
+	got, err = cfg.NewResourceManagerClient("").Organizations.GetIamPolicy("organizations/100", &crmv1.GetIamPolicyRequest{}).Do()
+	require.NoError(t, err)
+	assert.Empty(t, got.Bindings)
+
+	bucketPolicy, err := cfg.NewStorageClient("").Buckets.GetIamPolicy("my-bucket").Do()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:a@example.com"}, bucketPolicy.Bindings[0].Members)
+
+	res, err := resources.SendRequest(cfg, "POST", "", cfg.PubsubBasePath+"projects/my-project/topics/topic:getIamPolicy", "", nil)
+	require.NoError(t, err)
+	assert.Len(t, res["bindings"], 1)
+
+	_, err = resources.SendRequest(cfg, "POST", "", cfg.PubsubBasePath+"projects/my-project/topics/unknown:getIamPolicy", "", nil)
+	assert.True(t, resources.IsGoogleApiErrorWithCode(err, http.StatusNotFound), "SendRequest() = %v, want = 404", err)
+
+	assert.Equal(t, []Request{
+		{Name: "//cloudresourcemanager.googleapis.com/projects/my-project", Method: MethodGetIamPolicy, Code: http.StatusOK},
+		{Name: "//cloudresourcemanager.googleapis.com/organizations/100", Method: MethodGetIamPolicy, Code: http.StatusOK},
+		{Name: "//storage.googleapis.com/my-bucket", Method: MethodGetIamPolicy, Code: http.StatusOK},
+		{Name: "//pubsub.googleapis.com/projects/my-project/topics/topic", Method: MethodGetIamPolicy, Code: http.StatusOK},
+		{Name: "//pubsub.googleapis.com/projects/my-project/topics/unknown", Method: MethodGetIamPolicy, Code: http.StatusNotFound},
+	}, s.Requests())
+}
+
+func TestFaults(t *testing.T) {
+	s := newSeededServer(t)
+	cfg := newTestConfig(t, s)
+	storage := cfg.NewStorageClient("")
+	bucket := "//storage.googleapis.com/my-bucket"
+
+	// Retryable errors are retried by the client of the config.
+	s.AddFault(Fault{Name: bucket, Method: MethodGet, Code: http.StatusTooManyRequests, Count: 1})
+	_, err := storage.Buckets.Get("my-bucket").Do()
+	require.NoError(t, err)
+
+	s.AddFault(Fault{Name: bucket, Code: http.StatusForbidden})
+	_, err = storage.Buckets.Get("my-bucket").Do()
+	assert.Equal(t, http.StatusForbidden, errorCode(err))
+	_, err = storage.Buckets.GetIamPolicy("my-bucket").Do()
+	assert.Equal(t, http.StatusForbidden, errorCode(err))
+
+	assert.Equal(t, []Request{
+		{Name: bucket, Method: MethodGet, Code: http.StatusTooManyRequests},
+		{Name: bucket, Method: MethodGet, Code: http.StatusOK},
+		{Name: bucket, Method: MethodGet, Code: http.StatusForbidden},
+		{Name: bucket, Method: MethodGetIamPolicy, Code: http.StatusForbidden},
+	}, s.Requests())
+}
+
+func TestNotImplemented(t *testing.T) {
+	s := newSeededServer(t)
+	cases := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/unknown.googleapis.com/v1/projects/my-project"},
+		{method: http.MethodPost, path: "/storage.googleapis.com/storage/v1/b/my-bucket"},
+		{method: http.MethodGet, path: "/storage.googleapis.com/storage/v1/b/my-bucket/o/object"},
+		{method: http.MethodGet, path: "/cloudresourcemanager.googleapis.com/v1/projects/my-project:getAncestry"},
+		{method: http.MethodGet, path: "/pubsub.googleapis.com/v1/projects/my-project/topics/topic"},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, s.URL+c.path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, resp.StatusCode, "%s %s", c.method, c.path)
+	}
+	assert.Empty(t, s.Requests())
+}
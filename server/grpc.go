@@ -0,0 +1,77 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// RegisterGRPC registers the Config Validator service, of which only Review
+// is implemented, and the gRPC health service on g.
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	validator.RegisterValidatorServer(g, &validatorServer{s: s})
+	healthpb.RegisterHealthServer(g, s.health)
+}
+
+type validatorServer struct {
+	validator.UnimplementedValidatorServer
+	s *Server
+}
+
+// Review validates the assets of the request.
+func (v *validatorServer) Review(ctx context.Context, req *validator.ReviewRequest) (*validator.ReviewResponse, error) {
+	assets := make([]google.Asset, 0, len(req.Assets))
+	for _, a := range req.Assets {
+		asset, err := assetFromProto(a)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "asset %s: %s", a.Name, err)
+		}
+		assets = append(assets, asset)
+	}
+	violations, err := v.s.current().Validate(ctx, assets)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &validator.ReviewResponse{Violations: violations}, nil
+}
+
+// assetFromProto is the inverse of the conversion of assets to protos for
+// validation.
+func assetFromProto(a *validator.Asset) (google.Asset, error) {
+	var asset google.Asset
+	marshaller := &jsonpb.Marshaler{OrigName: true}
+	jsn, err := marshaller.MarshalToString(a)
+	if err != nil {
+		return asset, fmt.Errorf("marshaling to json: %w", err)
+	}
+	if err := json.Unmarshal([]byte(jsn), &asset); err != nil {
+		return asset, fmt.Errorf("unmarshaling from json: %w", err)
+	}
+	return asset, nil
+}
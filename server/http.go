@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"github.com/golang/protobuf/jsonpb"
+	"go.uber.org/zap"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// maxRequestBytes limits the size of plans and assets in requests.
+const maxRequestBytes = 64 << 20
+
+// Handler returns the HTTP handler of the endpoints of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/convert", s.post(s.handleConvert))
+	mux.HandleFunc("/v1/validate", s.post(s.handleValidate))
+	mux.HandleFunc("/v1/validate-assets", s.post(s.handleValidateAssets))
+	if len(s.opts.RunTask.HMACKey) > 0 {
+		mux.HandleFunc("/v1/run-task", s.post(s.handleRunTask))
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.isReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// post restricts h to POST requests and gives it the request body.
+func (s *Server) post(h func(w http.ResponseWriter, r *http.Request, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("reading request: %w", err))
+			return
+		}
+		h(w, r, body)
+	}
+}
+
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("request is not a JSON terraform plan"))
+		return
+	}
+	assets, err := s.current().Convert(r.Context(), body)
+	if err != nil {
+		s.logger.Error("Converting plan", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(assets); err != nil {
+		s.logger.Error("Writing response", zap.Error(err))
+	}
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("request is not a JSON terraform plan"))
+		return
+	}
+	// Convert and validate with the same policies, even if they are reloaded
+	// in between.
+	v := s.current()
+	assets, err := v.Convert(r.Context(), body)
+	if err != nil {
+		s.logger.Error("Converting plan", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.validate(w, r, v.Validate, assets)
+}
+
+func (s *Server) handleValidateAssets(w http.ResponseWriter, r *http.Request, body []byte) {
+	var assets []google.Asset
+	if err := json.Unmarshal(body, &assets); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("request is not a JSON array of assets: %w", err))
+		return
+	}
+	s.validate(w, r, s.current().Validate, assets)
+}
+
+type validateFunc func(context.Context, []google.Asset) ([]*validator.Violation, error)
+
+func (s *Server) validate(w http.ResponseWriter, r *http.Request, validate validateFunc, assets []google.Asset) {
+	violations, err := validate(r.Context(), assets)
+	if err != nil {
+		s.logger.Error("Validating assets", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	marshaller := &jsonpb.Marshaler{}
+	if err := marshaller.Marshal(w, &validator.AuditResponse{Violations: violations}); err != nil {
+		s.logger.Error("Writing response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,299 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"go.uber.org/zap"
+)
+
+const (
+	// runTaskSignatureHeader holds the hex HMAC-SHA512 of run task requests.
+	runTaskSignatureHeader = "X-Tfc-Task-Signature"
+	// runTaskTestToken is the access token of the request that Terraform
+	// Cloud sends when a run task is created, which has no callback.
+	runTaskTestToken = "test-token"
+	// runTaskTimeout bounds the processing of a run task, which Terraform
+	// Cloud gives 10 minutes for a callback.
+	runTaskTimeout = 10 * time.Minute
+	// maxOutcomes is the number of outcomes that Terraform Cloud accepts in
+	// a callback.
+	maxOutcomes = 100
+)
+
+// RunTaskOptions configures the Terraform Cloud run task endpoint.
+type RunTaskOptions struct {
+	// HMACKey is the HMAC key of the run task. The endpoint is only served
+	// when it is set.
+	HMACKey []byte
+	// Client downloads plans and makes callbacks. It defaults to a client
+	// with a timeout of a minute.
+	Client *http.Client
+}
+
+// runTaskRequest is the payload of Terraform Cloud run task requests.
+type runTaskRequest struct {
+	PayloadVersion        int    `json:"payload_version"`
+	AccessToken           string `json:"access_token"`
+	Stage                 string `json:"stage"`
+	TaskResultID          string `json:"task_result_id"`
+	TaskResultCallbackURL string `json:"task_result_callback_url"`
+	PlanJSONAPIURL        string `json:"plan_json_api_url"`
+	RunID                 string `json:"run_id"`
+	WorkspaceName         string `json:"workspace_name"`
+	OrganizationName      string `json:"organization_name"`
+}
+
+// runTaskResult is the JSON:API body of run task callbacks.
+type runTaskResult struct {
+	Data runTaskResultData `json:"data"`
+}
+
+type runTaskResultData struct {
+	Type          string                      `json:"type"`
+	Attributes    runTaskResultAttributes     `json:"attributes"`
+	Relationships *runTaskResultRelationships `json:"relationships,omitempty"`
+}
+
+type runTaskResultAttributes struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type runTaskResultRelationships struct {
+	Outcomes runTaskOutcomes `json:"outcomes"`
+}
+
+type runTaskOutcomes struct {
+	Data []runTaskOutcome `json:"data"`
+}
+
+type runTaskOutcome struct {
+	Type       string                   `json:"type"`
+	Attributes runTaskOutcomeAttributes `json:"attributes"`
+}
+
+type runTaskOutcomeAttributes struct {
+	OutcomeID   string                  `json:"outcome-id"`
+	Description string                  `json:"description"`
+	Body        string                  `json:"body"`
+	Tags        map[string][]runTaskTag `json:"tags"`
+}
+
+type runTaskTag struct {
+	Label string `json:"label"`
+	Level string `json:"level"`
+}
+
+// handleRunTask verifies and accepts run task requests, whose plans are
+// validated in the background.
+func (s *Server) handleRunTask(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !validSignature(s.opts.RunTask.HMACKey, body, r.Header.Get(runTaskSignatureHeader)) {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid %s", runTaskSignatureHeader))
+		return
+	}
+	var req runTaskRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("request is not a JSON run task payload: %w", err))
+		return
+	}
+	if req.AccessToken == runTaskTestToken {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if req.TaskResultCallbackURL == "" || req.AccessToken == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("run task payload has no callback"))
+		return
+	}
+
+	s.runTasks.Add(1)
+	go func() {
+		defer s.runTasks.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), runTaskTimeout)
+		defer cancel()
+		s.runTask(ctx, req)
+	}()
+	w.WriteHeader(http.StatusOK)
+}
+
+// runTask validates the plan of req, and gives the result to its callback.
+func (s *Server) runTask(ctx context.Context, req runTaskRequest) {
+	logger := s.logger.With(zap.String("run_id", req.RunID), zap.String("task_result_id", req.TaskResultID))
+	result, err := s.runTaskResult(ctx, req)
+	if err != nil {
+		logger.Error("Running run task", zap.Error(err))
+		result = runTaskResult{Data: runTaskResultData{
+			Type:       "task-results",
+			Attributes: runTaskResultAttributes{Status: "failed", Message: err.Error()},
+		}}
+	}
+	if err := s.runTaskCallback(ctx, req, result); err != nil {
+		logger.Error("Sending run task result", zap.Error(err))
+	}
+}
+
+func (s *Server) runTaskResult(ctx context.Context, req runTaskRequest) (runTaskResult, error) {
+	if req.PlanJSONAPIURL == "" {
+		return runTaskResult{}, fmt.Errorf("run task stage %q has no plan; use the post_plan stage", req.Stage)
+	}
+	plan, err := s.downloadPlan(ctx, req)
+	if err != nil {
+		return runTaskResult{}, err
+	}
+	v := s.current()
+	assets, err := v.Convert(ctx, plan)
+	if err != nil {
+		return runTaskResult{}, fmt.Errorf("converting plan: %w", err)
+	}
+	violations, err := v.Validate(ctx, assets)
+	if err != nil {
+		return runTaskResult{}, err
+	}
+
+	result := runTaskResult{Data: runTaskResultData{
+		Type:       "task-results",
+		Attributes: runTaskResultAttributes{Status: "passed", Message: "No violations found"},
+	}}
+	if len(violations) == 0 {
+		return result, nil
+	}
+	result.Data.Attributes.Status = "failed"
+	result.Data.Attributes.Message = fmt.Sprintf("Found %d violations", len(violations))
+	if len(violations) > maxOutcomes {
+		result.Data.Attributes.Message += fmt.Sprintf(", of which the first %d are listed", maxOutcomes)
+		violations = violations[:maxOutcomes]
+	}
+	outcomes := make([]runTaskOutcome, 0, len(violations))
+	for i, violation := range violations {
+		outcomes = append(outcomes, violationOutcome(i, violation))
+	}
+	result.Data.Relationships = &runTaskResultRelationships{Outcomes: runTaskOutcomes{Data: outcomes}}
+	return result, nil
+}
+
+func (s *Server) downloadPlan(ctx context.Context, req runTaskRequest) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.PlanJSONAPIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("downloading plan: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+req.AccessToken)
+	resp, err := s.runTaskClient().Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("downloading plan: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading plan: %s", resp.Status)
+	}
+	plan, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxRequestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("downloading plan: %w", err)
+	}
+	return plan, nil
+}
+
+func (s *Server) runTaskCallback(ctx context.Context, req runTaskRequest, result runTaskResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshaling result: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPatch, req.TaskResultCallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+req.AccessToken)
+	httpReq.Header.Set("Content-Type", "application/vnd.api+json")
+	resp, err := s.runTaskClient().Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("callback: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Server) runTaskClient() *http.Client {
+	if s.opts.RunTask.Client != nil {
+		return s.opts.RunTask.Client
+	}
+	return &http.Client{Timeout: time.Minute}
+}
+
+// violationOutcome describes the i-th violation of a run.
+func violationOutcome(i int, v *validator.Violation) runTaskOutcome {
+	var body strings.Builder
+	fmt.Fprintf(&body, "**Constraint:** `%s`\n\n", v.Constraint)
+	fmt.Fprintf(&body, "**Resource:** `%s`\n\n", v.Resource)
+	if v.Severity != "" {
+		fmt.Fprintf(&body, "**Severity:** %s\n\n", v.Severity)
+	}
+	body.WriteString(v.Message)
+
+	tags := map[string][]runTaskTag{
+		"Status": {{Label: "Failed", Level: "error"}},
+	}
+	if v.Severity != "" {
+		tags["Severity"] = []runTaskTag{{Label: v.Severity, Level: severityLevel(v.Severity)}}
+	}
+	return runTaskOutcome{
+		Type: "task-result-outcomes",
+		Attributes: runTaskOutcomeAttributes{
+			OutcomeID:   fmt.Sprintf("violation-%d", i+1),
+			Description: fmt.Sprintf("%s on %s", v.Constraint, v.Resource),
+			Body:        body.String(),
+			Tags:        tags,
+		},
+	}
+}
+
+// severityLevel maps the severities of constraints to the levels of outcome
+// tags.
+func severityLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	case "low":
+		return "info"
+	default:
+		return "none"
+	}
+}
+
+// validSignature reports whether signature is the hex HMAC-SHA512 of body.
+func validSignature(key, body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha512.New, key)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
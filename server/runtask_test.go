@@ -0,0 +1,281 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+)
+
+const (
+	testHMACKey     = "secret"
+	testAccessToken = "run-token"
+)
+
+// fakeTFC serves the plan of a run, and receives the callbacks of its run
+// task.
+type fakeTFC struct {
+	*httptest.Server
+	plan      []byte
+	callbacks chan runTaskResult
+}
+
+func newFakeTFC(t *testing.T, plan []byte) *fakeTFC {
+	f := &fakeTFC{plan: plan, callbacks: make(chan runTaskResult, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/plans/plan-1/json-output", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAccessToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		// Like Terraform Cloud, redirect to the archivist URL of the plan.
+		http.Redirect(w, r, "/archivist/plan-1", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/archivist/plan-1", func(w http.ResponseWriter, r *http.Request) {
+		if f.plan == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(f.plan)
+	})
+	mux.HandleFunc("/api/v2/task-results/taskrs-1/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("callback method = %s, want = %s", r.Method, http.MethodPatch)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer "+testAccessToken {
+			t.Errorf("callback Authorization = %q, want = %q", got, "Bearer "+testAccessToken)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/vnd.api+json" {
+			t.Errorf("callback Content-Type = %q, want = application/vnd.api+json", got)
+		}
+		var result runTaskResult
+		if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+			t.Errorf("decoding callback: %s", err)
+		}
+		f.callbacks <- result
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeTFC) payload(stage string) []byte {
+	req := runTaskRequest{
+		PayloadVersion:        1,
+		AccessToken:           testAccessToken,
+		Stage:                 stage,
+		TaskResultID:          "taskrs-1",
+		TaskResultCallbackURL: f.URL + "/api/v2/task-results/taskrs-1/callback",
+		RunID:                 "run-1",
+		WorkspaceName:         "workspace",
+		OrganizationName:      "org",
+	}
+	if stage == "post_plan" {
+		req.PlanJSONAPIURL = f.URL + "/api/v2/plans/plan-1/json-output"
+	}
+	b, _ := json.Marshal(req)
+	return b
+}
+
+func (f *fakeTFC) callback(t *testing.T) runTaskResult {
+	t.Helper()
+	select {
+	case result := <-f.callbacks:
+		return result
+	case <-time.After(30 * time.Second):
+		t.Fatal("no run task callback")
+		return runTaskResult{}
+	}
+}
+
+func sign(body []byte) string {
+	mac := hmac.New(sha512.New, []byte(testHMACKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postRunTask(t *testing.T, url string, body []byte, signature string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/v1/run-task", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(runTaskSignatureHeader, signature)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func runTaskOptions(policyPath string) Options {
+	opts := testOptions(policyPath)
+	opts.RunTask.HMACKey = []byte(testHMACKey)
+	return opts
+}
+
+func TestRunTask(t *testing.T) {
+	tfc := newFakeTFC(t, readFile(t, testPlan))
+	s, ts := newTestServer(t, runTaskOptions(testPolicies))
+
+	body := tfc.payload("post_plan")
+	if got := postRunTask(t, ts.URL, body, sign(body)); got != http.StatusOK {
+		t.Fatalf("POST /v1/run-task = %d, want = 200", got)
+	}
+	result := tfc.callback(t)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %s, want = nil", err)
+	}
+
+	if result.Data.Type != "task-results" {
+		t.Errorf("callback type = %q, want = task-results", result.Data.Type)
+	}
+	if result.Data.Attributes.Status != "failed" {
+		t.Errorf("callback status = %q, want = failed", result.Data.Attributes.Status)
+	}
+	assets, err := s.current().Convert(context.Background(), tfc.plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Data.Relationships == nil {
+		t.Fatal("callback has no outcomes")
+	}
+	outcomes := result.Data.Relationships.Outcomes.Data
+	if len(outcomes) != len(assets) {
+		t.Fatalf("callback = %d outcomes, want one per violation (%d)", len(outcomes), len(assets))
+	}
+	ids := map[string]bool{}
+	for _, o := range outcomes {
+		if o.Type != "task-result-outcomes" {
+			t.Errorf("outcome type = %q, want = task-result-outcomes", o.Type)
+		}
+		if ids[o.Attributes.OutcomeID] {
+			t.Errorf("outcome ID %q is not unique", o.Attributes.OutcomeID)
+		}
+		ids[o.Attributes.OutcomeID] = true
+		if !strings.Contains(o.Attributes.Description, "GCPAlwaysViolatesConstraintV1") {
+			t.Errorf("outcome description = %q, want the constraint", o.Attributes.Description)
+		}
+		if got := o.Attributes.Tags["Status"]; len(got) != 1 || got[0].Level != "error" {
+			t.Errorf("outcome Status tags = %v, want one error", got)
+		}
+	}
+}
+
+func TestRunTask_passed(t *testing.T) {
+	tfc := newFakeTFC(t, readFile(t, testPlan))
+	_, ts := newTestServer(t, runTaskOptions(""))
+
+	body := tfc.payload("post_plan")
+	if got := postRunTask(t, ts.URL, body, sign(body)); got != http.StatusOK {
+		t.Fatalf("POST /v1/run-task = %d, want = 200", got)
+	}
+	result := tfc.callback(t)
+	if result.Data.Attributes.Status != "passed" {
+		t.Errorf("callback status = %q, want = passed", result.Data.Attributes.Status)
+	}
+	if result.Data.Relationships != nil {
+		t.Errorf("callback outcomes = %v, want none", result.Data.Relationships.Outcomes.Data)
+	}
+}
+
+func TestRunTask_errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		plan  []byte
+		stage string
+	}{
+		{name: "download fails", stage: "post_plan"},
+		{name: "invalid plan", plan: []byte(`{"resource_changes": 1}`), stage: "post_plan"},
+		{name: "no plan", plan: []byte(`{}`), stage: "pre_plan"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tfc := newFakeTFC(t, c.plan)
+			_, ts := newTestServer(t, runTaskOptions(testPolicies))
+
+			body := tfc.payload(c.stage)
+			if got := postRunTask(t, ts.URL, body, sign(body)); got != http.StatusOK {
+				t.Fatalf("POST /v1/run-task = %d, want = 200", got)
+			}
+			result := tfc.callback(t)
+			if result.Data.Attributes.Status != "failed" || result.Data.Attributes.Message == "" {
+				t.Errorf("callback = %+v, want failed with a message", result.Data.Attributes)
+			}
+		})
+	}
+}
+
+func TestRunTask_requests(t *testing.T) {
+	tfc := newFakeTFC(t, readFile(t, testPlan))
+	_, ts := newTestServer(t, runTaskOptions(testPolicies))
+
+	body := tfc.payload("post_plan")
+	if got := postRunTask(t, ts.URL, body, ""); got != http.StatusUnauthorized {
+		t.Errorf("POST /v1/run-task without signature = %d, want = 401", got)
+	}
+	if got := postRunTask(t, ts.URL, body, sign([]byte("other"))); got != http.StatusUnauthorized {
+		t.Errorf("POST /v1/run-task with a wrong signature = %d, want = 401", got)
+	}
+
+	// Terraform Cloud verifies run tasks with a test token, and expects no
+	// callback.
+	test := []byte(`{"payload_version": 1, "access_token": "test-token"}`)
+	if got := postRunTask(t, ts.URL, test, sign(test)); got != http.StatusOK {
+		t.Errorf("POST /v1/run-task with the test token = %d, want = 200", got)
+	}
+	select {
+	case result := <-tfc.callbacks:
+		t.Errorf("callback = %+v for the test token, want none", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Without an HMAC key, there is no endpoint.
+	_, ts = newTestServer(t, testOptions(testPolicies))
+	if got := postRunTask(t, ts.URL, body, sign(body)); got != http.StatusNotFound {
+		t.Errorf("POST /v1/run-task without an HMAC key = %d, want = 404", got)
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	cases := map[string]string{
+		"critical": "error",
+		"HIGH":     "error",
+		"medium":   "warning",
+		"low":      "info",
+		"":         "none",
+	}
+	for severity, want := range cases {
+		if got := severityLevel(severity); got != want {
+			t.Errorf("severityLevel(%q) = %q, want = %q", severity, got, want)
+		}
+	}
+	o := violationOutcome(0, &validator.Violation{Constraint: "c", Resource: "r", Message: "m", Severity: "high"})
+	if got := o.Attributes.Tags["Severity"]; len(got) != 1 || got[0] != (runTaskTag{Label: "high", Level: "error"}) {
+		t.Errorf("violationOutcome() Severity tags = %v, want high error", got)
+	}
+}
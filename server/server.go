@@ -0,0 +1,216 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server serves the conversion and validation of terraform plans, so
+// that the provider schema and the policy library are only loaded once.
+//
+// The HTTP handler has the following endpoints:
+//
+//	POST /v1/convert          plan JSON -> JSON array of CAI assets
+//	POST /v1/validate         plan JSON -> {"violations": [...]}
+//	POST /v1/validate-assets  JSON array of CAI assets -> {"violations": [...]}
+//	GET  /healthz             200 while the server runs
+//	GET  /readyz              200 while the server accepts requests
+//	POST /v1/run-task         Terraform Cloud run task, if RunTask.HMACKey is set
+//
+// The responses have the shapes of the output of the convert and validate
+// commands with --output-json. Errors are given as {"error": "..."}.
+//
+// The gRPC services are the Review method of the Config Validator service,
+// and the gRPC health service.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Validator configures the conversion of plans and the policies that
+	// they are validated against.
+	Validator tfgcv.Options
+	// ReloadInterval is how often WatchPolicies checks the policy library
+	// and CEL rules for changes.
+	ReloadInterval time.Duration
+	// RunTask configures the Terraform Cloud run task endpoint.
+	RunTask RunTaskOptions
+}
+
+// Server converts and validates plans for HTTP and gRPC clients. It reloads
+// the policies when they change.
+type Server struct {
+	opts   Options
+	logger *zap.Logger
+	health *health.Server
+
+	// mu guards validator and fingerprint, which are replaced on reloads.
+	mu          sync.RWMutex
+	validator   *tfgcv.Validator
+	fingerprint string
+
+	// ready is 1 while the server accepts requests.
+	ready int32
+	// runTasks counts the run tasks being processed.
+	runTasks sync.WaitGroup
+}
+
+// New returns a ready Server, loading the policies of opts.
+func New(opts Options) (*Server, error) {
+	s := &Server{
+		opts:   opts,
+		logger: opts.Validator.Logger,
+		health: health.NewServer(),
+	}
+	if s.logger == nil {
+		s.logger = zap.NewNop()
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	s.SetReady(true)
+	return s, nil
+}
+
+// SetReady sets whether the server accepts requests, as reported by the
+// readiness endpoint and the gRPC health service. Servers are made not ready
+// before shutting down, so that load balancers stop sending requests.
+func (s *Server) SetReady(ready bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		atomic.StoreInt32(&s.ready, 1)
+		status = healthpb.HealthCheckResponse_SERVING
+	} else {
+		atomic.StoreInt32(&s.ready, 0)
+	}
+	s.health.SetServingStatus("", status)
+}
+
+// Shutdown makes the server not ready, and waits for the run tasks being
+// processed to send their results, or for ctx to be done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.SetReady(false)
+	done := make(chan struct{})
+	go func() {
+		s.runTasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) isReady() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+// Reload loads the policies, and uses them for the following requests. On
+// errors, the previous policies are kept.
+func (s *Server) Reload() error {
+	fingerprint, err := policyFingerprint(s.opts.Validator.PolicyPath, s.opts.Validator.CELRules)
+	if err != nil {
+		return err
+	}
+	v, err := tfgcv.NewValidator(s.opts.Validator)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validator = v
+	s.fingerprint = fingerprint
+	return nil
+}
+
+func (s *Server) current() *tfgcv.Validator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.validator
+}
+
+// WatchPolicies reloads the policies when their files change, until ctx is
+// done. Failed reloads are logged.
+func (s *Server) WatchPolicies(ctx context.Context) {
+	if s.opts.ReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.opts.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		fingerprint, err := policyFingerprint(s.opts.Validator.PolicyPath, s.opts.Validator.CELRules)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Checking policies for changes: %s", err))
+			continue
+		}
+		s.mu.RLock()
+		changed := fingerprint != s.fingerprint
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := s.Reload(); err != nil {
+			s.logger.Error(fmt.Sprintf("Reloading policies: %s", err))
+			continue
+		}
+		s.logger.Info("Reloaded policies")
+	}
+}
+
+// policyFingerprint identifies the names, sizes and modification times of
+// the files under paths. Empty paths are skipped.
+func policyFingerprint(paths ...string) (string, error) {
+	h := sha256.New()
+	for _, root := range paths {
+		if root == "" {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Follow symbolic links, as used by mounted Kubernetes ConfigMaps.
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("reading policies: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
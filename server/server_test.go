@@ -0,0 +1,348 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+)
+
+const (
+	testPlan        = "../test/read_planned_assets/tf0_12plan.json"
+	testPolicies    = "../testdata/sample_policies/always_violate"
+	testProjectName = "gl-akopachevskyy-sql-db"
+)
+
+func testOptions(policyPath string) Options {
+	return Options{
+		Validator: tfgcv.Options{
+			Project: testProjectName,
+			// The always_violate constraint targets organizations/**.
+			Ancestry:   map[string]string{"projects/" + testProjectName: "organizations/123/folders/1234"},
+			Offline:    true,
+			PolicyPath: policyPath,
+		},
+	}
+}
+
+func newTestServer(t *testing.T, opts Options) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() = %s, want = nil", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func post(t *testing.T, url string, body []byte) (int, []byte) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, b
+}
+
+func violations(t *testing.T, body []byte) []*validator.Violation {
+	t.Helper()
+	var resp validator.AuditResponse
+	if err := jsonpb.Unmarshal(bytes.NewReader(body), &resp); err != nil {
+		t.Fatalf("unmarshaling %s: %s", body, err)
+	}
+	return resp.Violations
+}
+
+func TestConvert(t *testing.T) {
+	_, ts := newTestServer(t, testOptions(testPolicies))
+
+	code, body := post(t, ts.URL+"/v1/convert", readFile(t, testPlan))
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/convert = %d %s, want = 200", code, body)
+	}
+	var assets []google.Asset
+	if err := json.Unmarshal(body, &assets); err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) == 0 {
+		t.Error("POST /v1/convert = no assets, want assets")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, ts := newTestServer(t, testOptions(testPolicies))
+
+	code, body := post(t, ts.URL+"/v1/convert", readFile(t, testPlan))
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/convert = %d %s, want = 200", code, body)
+	}
+	var assets []google.Asset
+	if err := json.Unmarshal(body, &assets); err != nil {
+		t.Fatal(err)
+	}
+
+	code, body = post(t, ts.URL+"/v1/validate", readFile(t, testPlan))
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/validate = %d %s, want = 200", code, body)
+	}
+	if got := len(violations(t, body)); got != len(assets) {
+		t.Errorf("POST /v1/validate = %d violations, want %d", got, len(assets))
+	}
+
+	assetsJSON, err := json.Marshal(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, body = post(t, ts.URL+"/v1/validate-assets", assetsJSON)
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/validate-assets = %d %s, want = 200", code, body)
+	}
+	if got := len(violations(t, body)); got != len(assets) {
+		t.Errorf("POST /v1/validate-assets = %d violations, want %d", got, len(assets))
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	_, ts := newTestServer(t, testOptions(testPolicies))
+
+	cases := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{name: "convert not JSON", path: "/v1/convert", body: "plan", want: http.StatusBadRequest},
+		{name: "validate not JSON", path: "/v1/validate", body: "plan", want: http.StatusBadRequest},
+		{name: "validate assets not an array", path: "/v1/validate-assets", body: `{"name": "a"}`, want: http.StatusBadRequest},
+		{name: "convert not a plan", path: "/v1/convert", body: `{"format_version": "0.1", "resource_changes": 1}`, want: http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			code, body := post(t, ts.URL+c.path, []byte(c.body))
+			if code != c.want {
+				t.Errorf("POST %s = %d, want = %d", c.path, code, c.want)
+			}
+			var resp struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil || resp.Error == "" {
+				t.Errorf("POST %s = %s, want an error", c.path, body)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/validate = %d, want = %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s, ts := newTestServer(t, testOptions(testPolicies))
+
+	get := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want = 200", got)
+	}
+	if got := get("/readyz"); got != http.StatusOK {
+		t.Errorf("GET /readyz = %d, want = 200", got)
+	}
+	s.SetReady(false)
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want = 200 when not ready", got)
+	}
+	if got := get("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d, want = 503 when not ready", got)
+	}
+}
+
+// copyDir copies the regular files under src to dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), b, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchPolicies(t *testing.T) {
+	dir := t.TempDir()
+	copyDir(t, testPolicies, dir)
+	constraint := filepath.Join(dir, "policies", "constraints", "always_violates.yaml")
+	constraintYAML := readFile(t, constraint)
+	if err := os.Remove(constraint); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions(dir)
+	opts.ReloadInterval = 10 * time.Millisecond
+	s, ts := newTestServer(t, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.WatchPolicies(ctx)
+
+	plan := readFile(t, testPlan)
+	code, body := post(t, ts.URL+"/v1/validate", plan)
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/validate = %d %s, want = 200", code, body)
+	}
+	if got := len(violations(t, body)); got != 0 {
+		t.Fatalf("POST /v1/validate = %d violations without constraints, want 0", got)
+	}
+
+	// A broken library keeps the previous policies.
+	broken := filepath.Join(dir, "policies", "constraints", "broken.yaml")
+	if err := ioutil.WriteFile(broken, []byte("kind: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if code, body := post(t, ts.URL+"/v1/validate", plan); code != http.StatusOK {
+		t.Fatalf("POST /v1/validate = %d %s with a broken library, want = 200", code, body)
+	}
+	if err := os.Remove(broken); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(constraint, constraintYAML, 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, body := post(t, ts.URL+"/v1/validate", plan)
+		if len(violations(t, body)) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("policies were not reloaded after adding a constraint")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	s, ts := newTestServer(t, testOptions(testPolicies))
+
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	s.RegisterGRPC(g)
+	go g.Serve(lis)
+	defer g.Stop()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() = %s, want = nil", err)
+	}
+	if health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Check() = %s, want = %s", health.Status, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	code, body := post(t, ts.URL+"/v1/convert", readFile(t, testPlan))
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/convert = %d %s, want = 200", code, body)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatal(err)
+	}
+	var assets []*validator.Asset
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	for _, r := range raw {
+		a := &validator.Asset{}
+		if err := u.Unmarshal(bytes.NewReader(r), a); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, a)
+	}
+
+	resp, err := validator.NewValidatorClient(conn).Review(ctx, &validator.ReviewRequest{Assets: assets})
+	if err != nil {
+		t.Fatalf("Review() = %s, want = nil", err)
+	}
+	if len(resp.Violations) != len(assets) {
+		t.Errorf("Review() = %d violations, want %d", len(resp.Violations), len(assets))
+	}
+}
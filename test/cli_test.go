@@ -130,6 +130,7 @@ func TestCLI(t *testing.T) {
 		{name: "example_project_in_folder"},
 		{name: "example_project_in_org"},
 		{name: "example_project_organization_policy"},
+		{name: "example_project_organization_policy_deny_all"},
 		{name: "example_project_service"},
 		{name: "example_pubsub_lite_reservation"},
 		{name: "example_pubsub_lite_subscription"},
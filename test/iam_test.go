@@ -51,7 +51,7 @@ func TestIAMFetchFullResource(t *testing.T) {
 		{name: "example_storage_bucket_iam_member"},
 	}
 
-	converters := resources.ResourceConverters()
+	converters := resources.AllResourceConverters()
 	schema := provider.Provider()
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -164,6 +164,10 @@ func normalizeAssets(t *testing.T, assets []google.Asset, offline bool) []google
 				asset.Resource.Data["name"] = re.ReplaceAllString(name, "/placeholder-foobar")
 			}
 		}
+		if asset.AccessPolicy != nil {
+			name, _ := asset.AccessPolicy["name"].(string)
+			asset.AccessPolicy["name"] = re.ReplaceAllString(name, "/placeholder-foobar")
+		}
 		ret[i] = asset
 	}
 	return ret
@@ -34,6 +34,7 @@ func TestReadPlannedAssetsCoverage(t *testing.T) {
 		// auto inserted tests that are not in list above or manually inserted in cli_test.go
 		{name: "example_access_context_manager_access_policy"},
 		{name: "example_access_context_manager_service_perimeter"},
+		{name: "example_access_context_manager_access_level"},
 		{name: "example_bigquery_dataset"},
 		{name: "example_bigquery_dataset_iam_binding"},
 		{name: "example_bigquery_dataset_iam_member"},
@@ -89,9 +90,11 @@ func TestReadPlannedAssetsCoverage(t *testing.T) {
 		{name: "example_project_iam"},
 		{name: "example_project_iam_custom_role"},
 		{name: "example_project_iam_policy"},
+		{name: "example_project_iam_audit_config"},
 		{name: "example_project_in_folder"},
 		{name: "example_project_in_org"},
 		{name: "example_project_organization_policy"},
+		{name: "example_project_organization_policy_deny_all"},
 		{name: "example_project_service"},
 		{name: "example_pubsub_lite_reservation"},
 		{name: "example_pubsub_lite_subscription"},
@@ -211,3 +214,48 @@ func TestReadPlannedAssetsCoverage_WithoutDefaultProject(t *testing.T) {
 		})
 	}
 }
+
+// Access Context Manager assets carry their resource data in the payloads
+// that are converted to protos for validation.
+func TestValidatePlannedAssets_accessContextManager(t *testing.T) {
+	cases := []struct {
+		name string
+	}{
+		{name: "example_access_context_manager_access_policy"},
+		{name: "example_access_context_manager_service_perimeter"},
+		{name: "example_access_context_manager_access_level"},
+	}
+	for i := range cases {
+		// Allocate a variable to make sure test can run in parallel.
+		c := cases[i]
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			// Create a temporary directory for running terraform.
+			dir, err := ioutil.TempDir(tmpDir, "terraform")
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			generateTestFiles(t, "../testdata/templates", dir, c.name+".tfplan.json")
+
+			planfile := filepath.Join(dir, c.name+".tfplan.json")
+			ctx := context.Background()
+			ancestryCache := map[string]string{
+				data.Provider["project"]: data.Ancestry,
+			}
+			assets, err := tfgcv.ReadPlannedAssets(ctx, planfile, data.Provider["project"], "", "", ancestryCache, true, false, zaptest.NewLogger(t), "")
+			if err != nil {
+				t.Fatalf("ReadPlannedAssets(%s, %s, \"\", \"\", %s, %t): %v", planfile, data.Provider["project"], ancestryCache, true, err)
+			}
+
+			violations, err := tfgcv.ValidateAssets(ctx, assets, "../testdata/sample_policies/always_violate")
+			if err != nil {
+				t.Fatalf("ValidateAssets(%s): %v", c.name, err)
+			}
+			if len(violations) == 0 {
+				t.Errorf("ValidateAssets(%s) = no violations, want always_violate violations", c.name)
+			}
+		})
+	}
+}
@@ -329,6 +329,9 @@ func attributes(value interface{}, address []string, state map[string]string, sc
 		state[addr] = strconv.Itoa(value.(int))
 	case []interface{}:
 		arr := value.([]interface{})
+		if _, ok := sch.Elem.(*schema.Resource); ok {
+			arr = knownBlocks(arr)
+		}
 		countAddr := addr + ".#"
 		state[countAddr] = strconv.Itoa(len(arr))
 		for i, e := range arr {
@@ -341,6 +344,7 @@ func attributes(value interface{}, address []string, state map[string]string, sc
 			addr := append(address, k)
 			attributes(v, addr, state, schemas)
 		}
+
 	case *schema.Set:
 		set := value.(*schema.Set)
 		attributes(set.List(), address, state, schemas)
@@ -348,3 +352,18 @@ func attributes(value interface{}, address []string, state map[string]string, sc
 		panic(fmt.Sprintf("unrecognized type %T", value))
 	}
 }
+
+// knownBlocks drops the unknown blocks of a list or set of blocks: null
+// blocks, and blocks whose attributes are all unknown, which plans give as
+// empty objects. The schema reader would read them as nil items, which
+// converters do not expect.
+func knownBlocks(blocks []interface{}) []interface{} {
+	known := make([]interface{}, 0, len(blocks))
+	for _, b := range blocks {
+		if m, ok := b.(map[string]interface{}); b == nil || (ok && len(m) == 0) {
+			continue
+		}
+		known = append(known, b)
+	}
+	return known
+}
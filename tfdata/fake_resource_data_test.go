@@ -4,7 +4,7 @@
 // you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
 //
-//      http://www.apache.org/licenses/LICENSE-2.0
+//	http://www.apache.org/licenses/LICENSE-2.0
 //
 // Unless required by applicable law or agreed to in writing, software
 // distributed under the License is distributed on an "AS IS" BASIS,
@@ -324,3 +324,26 @@ func TestFakeResourceData_getOknsetTypeObject(t *testing.T) {
 	}, res)
 	assert.False(t, ok)
 }
+
+func TestFakeResourceData_getUnknownBlocks(t *testing.T) {
+	p := provider.Provider()
+
+	values := map[string]interface{}{
+		"name":             "test-bucket",
+		"location":         "US",
+		"retention_policy": []interface{}{nil},
+		"cors": []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"origin": []interface{}{"http://example.com"}},
+		},
+	}
+	d := NewFakeResourceData(
+		"google_storage_bucket",
+		p.ResourcesMap["google_storage_bucket"].Schema,
+		values,
+	)
+	assert.Equal(t, []interface{}{}, d.Get("retention_policy"))
+	cors := d.Get("cors").([]interface{})
+	assert.Len(t, cors, 1)
+	assert.Equal(t, []interface{}{"http://example.com"}, cors[0].(map[string]interface{})["origin"])
+}
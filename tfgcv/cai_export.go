@@ -0,0 +1,75 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/jsonpb"
+	assetpb "google.golang.org/genproto/googleapis/cloud/asset/v1"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// ReadCAIExport reads the assets of a Cloud Asset Inventory export, given as
+// newline-delimited JSON as written by `gcloud asset export`, or as a JSON
+// array.
+func ReadCAIExport(path string) ([]google.Asset, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CAI export: %w", err)
+	}
+
+	var messages []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, fmt.Errorf("reading CAI export %s: %w", path, err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var m json.RawMessage
+			if err := dec.Decode(&m); err != nil {
+				return nil, fmt.Errorf("reading CAI export %s: %w", path, err)
+			}
+			messages = append(messages, m)
+		}
+	}
+
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	marshaler := jsonpb.Marshaler{OrigName: true, EnumsAsInts: true}
+	assets := make([]google.Asset, 0, len(messages))
+	for i, m := range messages {
+		// The export uses the JSON format of the CAI API, which is converted
+		// to the format of converted assets through the proto.
+		var pb assetpb.Asset
+		if err := unmarshaler.Unmarshal(bytes.NewReader(m), &pb); err != nil {
+			return nil, fmt.Errorf("reading CAI export %s: asset %d: %w", path, i, err)
+		}
+		jsn, err := marshaler.MarshalToString(&pb)
+		if err != nil {
+			return nil, fmt.Errorf("reading CAI export %s: asset %d: %w", path, i, err)
+		}
+		var asset google.Asset
+		if err := json.Unmarshal([]byte(jsn), &asset); err != nil {
+			return nil, fmt.Errorf("reading CAI export %s: asset %d: %w", path, i, err)
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+func TestReadCAIExport(t *testing.T) {
+	want := []google.Asset{
+		{
+			Name:      "//cloudresourcemanager.googleapis.com/organizations/123",
+			Type:      "cloudresourcemanager.googleapis.com/Organization",
+			Ancestors: []string{"organizations/123"},
+			OrgPolicy: []*google.OrgPolicy{
+				{
+					Constraint: "constraints/gcp.resourceLocations",
+					ListPolicy: &google.ListPolicy{AllowedValues: []string{"in:us-locations"}, InheritFromParent: true},
+				},
+				{
+					Constraint: "constraints/compute.vmExternalIpAccess",
+					ListPolicy: &google.ListPolicy{AllValues: 2},
+				},
+			},
+		},
+		{
+			Name:      "//cloudresourcemanager.googleapis.com/folders/456",
+			Type:      "cloudresourcemanager.googleapis.com/Folder",
+			Ancestors: []string{"folders/456", "organizations/123"},
+			OrgPolicy: []*google.OrgPolicy{
+				{
+					Constraint:    "constraints/compute.disableSerialPortAccess",
+					BooleanPolicy: &google.BooleanPolicy{Enforced: true},
+				},
+			},
+		},
+	}
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "newline-delimited",
+			content: `{"name":"//cloudresourcemanager.googleapis.com/organizations/123","assetType":"cloudresourcemanager.googleapis.com/Organization","orgPolicy":[{"constraint":"constraints/gcp.resourceLocations","listPolicy":{"allowedValues":["in:us-locations"],"inheritFromParent":true}},{"constraint":"constraints/compute.vmExternalIpAccess","listPolicy":{"allValues":"DENY"}}],"ancestors":["organizations/123"],"updateTime":"2021-04-14T15:16:17Z"}
+{"name":"//cloudresourcemanager.googleapis.com/folders/456","assetType":"cloudresourcemanager.googleapis.com/Folder","orgPolicy":[{"constraint":"constraints/compute.disableSerialPortAccess","booleanPolicy":{"enforced":true}}],"ancestors":["folders/456","organizations/123"]}
+`,
+		},
+		{
+			name: "array",
+			content: `[
+  {"name":"//cloudresourcemanager.googleapis.com/organizations/123","asset_type":"cloudresourcemanager.googleapis.com/Organization","org_policy":[{"constraint":"constraints/gcp.resourceLocations","list_policy":{"allowed_values":["in:us-locations"],"inherit_from_parent":true}},{"constraint":"constraints/compute.vmExternalIpAccess","list_policy":{"all_values":2}}],"ancestors":["organizations/123"]},
+  {"name":"//cloudresourcemanager.googleapis.com/folders/456","asset_type":"cloudresourcemanager.googleapis.com/Folder","org_policy":[{"constraint":"constraints/compute.disableSerialPortAccess","boolean_policy":{"enforced":true}}],"ancestors":["folders/456","organizations/123"]}
+]`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.json")
+			if err := ioutil.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadCAIExport(path)
+			if err != nil {
+				t.Fatalf("ReadCAIExport() = %s, want = nil", err)
+			}
+			if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(google.Asset{})); diff != "" {
+				t.Errorf("ReadCAIExport() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadCAIExport_Fail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := ioutil.WriteFile(path, []byte(`{"name": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCAIExport(path); err == nil {
+		t.Error("ReadCAIExport() = nil, want error")
+	}
+	if _, err := ReadCAIExport(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("ReadCAIExport() = nil, want error for a missing file")
+	}
+}
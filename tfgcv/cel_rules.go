@@ -0,0 +1,301 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sigs.k8s.io/yaml"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// CELRule is a policy rule evaluated with CEL (Common Expression Language)
+// instead of a Rego constraint. Rules are read from YAML files like:
+//
+//	rules:
+//	- name: bucket-location
+//	  asset_types: ["storage.googleapis.com/Bucket"]
+//	  ancestries: ["organizations/123/**"]
+//	  condition: asset.resource.data.location in ["EU", "EUROPE-WEST1"]
+//	  message: Buckets must be located in the EU.
+//	  severity: high
+//
+// The condition is evaluated for each asset that matches one of the asset
+// types (or any asset if none are given) and one of the ancestries (or any
+// ancestry). It sees the asset in its JSON format as the variable asset and
+// its ancestry path, like "organizations/123/folders/456/projects/my-project",
+// as ancestry_path. An asset violates the rule if the condition is false, or
+// if it cannot be evaluated on the asset, like a condition reading a field the
+// asset does not have. Guard optional fields with has(), like
+// !has(asset.resource.data.labels) || asset.resource.data.labels.env == "prod".
+type CELRule struct {
+	Name string `json:"name"`
+	// AssetTypes may contain path.Match patterns, like
+	// "compute.googleapis.com/*".
+	AssetTypes []string `json:"asset_types,omitempty"`
+	// Ancestries are ancestry path patterns, where "*" matches a single path
+	// segment and "**" any number of segments.
+	Ancestries []string `json:"ancestries,omitempty"`
+	Condition  string   `json:"condition"`
+	Message    string   `json:"message,omitempty"`
+	Severity   string   `json:"severity,omitempty"`
+
+	program cel.Program
+}
+
+type celRuleFile struct {
+	Rules []*CELRule `json:"rules"`
+}
+
+// LoadCELRules reads and compiles the CEL rules of a YAML file, or of the
+// .yaml and .yml files of a directory.
+func LoadCELRules(rulesPath string) ([]*CELRule, error) {
+	info, err := os.Stat(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading CEL rules: %w", err)
+	}
+	files := []string{rulesPath}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(rulesPath, pattern))
+			if err != nil {
+				return nil, fmt.Errorf("listing CEL rules in %s: %w", rulesPath, err)
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("asset", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("ancestry_path", decls.String),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL environment: %w", err)
+	}
+
+	var rules []*CELRule
+	names := make(map[string]string)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading CEL rules: %w", err)
+		}
+		var f celRuleFile
+		if err := yaml.UnmarshalStrict(content, &f); err != nil {
+			return nil, fmt.Errorf("parsing CEL rules in %s: %w", file, err)
+		}
+		for _, rule := range f.Rules {
+			if rule.Name == "" {
+				return nil, fmt.Errorf("CEL rule without a name in %s", file)
+			}
+			if other, ok := names[rule.Name]; ok {
+				return nil, fmt.Errorf("CEL rule %s is defined in both %s and %s", rule.Name, other, file)
+			}
+			names[rule.Name] = file
+			if err := rule.compile(env); err != nil {
+				return nil, fmt.Errorf("CEL rule %s in %s: %w", rule.Name, file, err)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *CELRule) compile(env *cel.Env) error {
+	if r.Condition == "" {
+		return fmt.Errorf("missing condition")
+	}
+	for _, t := range r.AssetTypes {
+		if _, err := path.Match(t, ""); err != nil {
+			return fmt.Errorf("asset type %q: %w", t, err)
+		}
+	}
+	ast, issues := env.Compile(r.Condition)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("compiling condition: %w", issues.Err())
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return fmt.Errorf("condition must be a boolean")
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return fmt.Errorf("building condition: %w", err)
+	}
+	r.program = program
+	return nil
+}
+
+// matches reports whether the rule applies to an asset of the given type and
+// ancestry path.
+func (r *CELRule) matches(assetType, ancestryPath string) bool {
+	typeMatch := len(r.AssetTypes) == 0
+	for _, t := range r.AssetTypes {
+		if ok, _ := path.Match(t, assetType); ok {
+			typeMatch = true
+			break
+		}
+	}
+	if !typeMatch {
+		return false
+	}
+	if len(r.Ancestries) == 0 {
+		return true
+	}
+	segments := strings.Split(ancestryPath, "/")
+	for _, a := range r.Ancestries {
+		if matchAncestry(strings.Split(a, "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAncestry matches path segments against pattern segments, where "*"
+// matches one segment and "**" any number of segments.
+func matchAncestry(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchAncestry(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 || (pattern[0] != "*" && pattern[0] != segments[0]) {
+		return false
+	}
+	return matchAncestry(pattern[1:], segments[1:])
+}
+
+// review evaluates the rule on an asset, and returns a violation if the
+// condition is false or cannot be evaluated.
+func (r *CELRule) review(ctx context.Context, asset google.Asset, data map[string]interface{}, ancestryPath string) (*validator.Violation, error) {
+	out, _, err := r.program.ContextEval(ctx, map[string]interface{}{
+		"asset":         data,
+		"ancestry_path": ancestryPath,
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	evalErr := err
+	if err == nil {
+		ok, isBool := out.Value().(bool)
+		if ok {
+			return nil, nil
+		}
+		if !isBool {
+			evalErr = fmt.Errorf("condition returned %v, not a boolean", out.Value())
+		}
+	}
+	message := r.Message
+	if message == "" {
+		message = fmt.Sprintf("%s does not satisfy %s", asset.Name, r.Condition)
+	}
+	fields := map[string]interface{}{
+		"asset_type":    asset.Type,
+		"ancestry_path": ancestryPath,
+		"condition":     r.Condition,
+	}
+	if evalErr != nil {
+		message = fmt.Sprintf("evaluating CEL rule %s on %s: %s", r.Name, asset.Name, evalErr)
+		fields["error"] = evalErr.Error()
+	}
+	metadata, err := structpb.NewValue(fields)
+	if err != nil {
+		return nil, fmt.Errorf("building violation metadata: %w", err)
+	}
+	return &validator.Violation{
+		Constraint: r.Name,
+		Resource:   asset.Name,
+		Message:    message,
+		Metadata:   metadata,
+		Severity:   r.Severity,
+	}, nil
+}
+
+// ancestryPath returns the ancestry path of an asset from the root of the
+// resource hierarchy, like "organizations/123/folders/456/projects/789".
+func ancestryPath(ancestors []string) string {
+	reversed := make([]string, len(ancestors))
+	for i, a := range ancestors {
+		reversed[len(ancestors)-1-i] = a
+	}
+	return strings.Join(reversed, "/")
+}
+
+// ValidateAssetsWithCELRules audits CAI assets with the CEL rules of
+// rulesPath, a YAML file or a directory of YAML files (see CELRule).
+func ValidateAssetsWithCELRules(ctx context.Context, assets []google.Asset, rulesPath string) ([]*validator.Violation, error) {
+	rules, err := LoadCELRules(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	return ReviewAssetsWithCELRules(ctx, assets, rules)
+}
+
+// ReviewAssetsWithCELRules audits CAI assets with compiled CEL rules.
+func ReviewAssetsWithCELRules(ctx context.Context, assets []google.Asset, rules []*CELRule) ([]*validator.Violation, error) {
+	// Make an empty slice, not a nil slice, so that this
+	// can be properly serialized to JSON.
+	violations := []*validator.Violation{}
+	for _, asset := range assets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		path := ancestryPath(asset.Ancestors)
+		var data map[string]interface{}
+		for _, rule := range rules {
+			if !rule.matches(asset.Type, path) {
+				continue
+			}
+			if data == nil {
+				b, err := json.Marshal(asset)
+				if err != nil {
+					return nil, fmt.Errorf("marshaling asset %s: %w", asset.Name, err)
+				}
+				if err := json.Unmarshal(b, &data); err != nil {
+					return nil, fmt.Errorf("unmarshaling asset %s: %w", asset.Name, err)
+				}
+			}
+			v, err := rule.review(ctx, asset, data, path)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				violations = append(violations, v)
+			}
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+const testCELRules = `
+rules:
+- name: bucket-location
+  asset_types: ["storage.googleapis.com/Bucket"]
+  ancestries: ["organizations/123/**"]
+  condition: asset.resource.data.location in ["EU", "EUROPE-WEST1"]
+  message: Buckets must be located in the EU.
+  severity: high
+- name: no-public-members
+  condition: >
+    !has(asset.iam_policy) ||
+    asset.iam_policy.bindings.all(b, !("allUsers" in b.members))
+`
+
+func writeCELRules(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+func TestValidateAssetsWithCELRules(t *testing.T) {
+	dir := t.TempDir()
+	writeCELRules(t, dir, "rules.yaml", testCELRules)
+	writeCELRules(t, dir, "more.yml", `
+rules:
+- name: compute-in-folder
+  asset_types: ["compute.googleapis.com/*"]
+  ancestries: ["organizations/*/folders/456/**"]
+  condition: ancestry_path.endsWith("projects/prod")
+`)
+	writeCELRules(t, dir, "README.md", "not a rule file")
+
+	ancestors := []string{"projects/my-project", "folders/456", "organizations/123"}
+	assets := []google.Asset{
+		{
+			Name:      "//storage.googleapis.com/us-bucket",
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: ancestors,
+			Resource:  &google.AssetResource{Data: map[string]interface{}{"location": "US"}},
+			IAMPolicy: &google.IAMPolicy{Bindings: []google.IAMBinding{{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}}}},
+		},
+		{
+			Name:      "//storage.googleapis.com/eu-bucket",
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: ancestors,
+			Resource:  &google.AssetResource{Data: map[string]interface{}{"location": "EU"}},
+		},
+		{
+			// Outside of the ancestries of bucket-location.
+			Name:      "//storage.googleapis.com/other-org-bucket",
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: []string{"projects/other-project", "organizations/999"},
+			Resource:  &google.AssetResource{Data: map[string]interface{}{"location": "US"}},
+		},
+		{
+			Name:      "//compute.googleapis.com/projects/my-project/global/networks/default",
+			Type:      "compute.googleapis.com/Network",
+			Ancestors: ancestors,
+		},
+	}
+
+	violations, err := ValidateAssetsWithCELRules(context.Background(), assets, dir)
+	if err != nil {
+		t.Fatalf("ValidateAssetsWithCELRules() = %v", err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Constraint+" "+v.Resource)
+	}
+	want := []string{
+		"bucket-location //storage.googleapis.com/us-bucket",
+		"no-public-members //storage.googleapis.com/us-bucket",
+		"compute-in-folder //compute.googleapis.com/projects/my-project/global/networks/default",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidateAssetsWithCELRules() violations = %q, want %q", got, want)
+	}
+	if v := violations[0]; v.Message != "Buckets must be located in the EU." || v.Severity != "high" {
+		t.Errorf("ValidateAssetsWithCELRules() violation = %v, want rule message and severity", v)
+	}
+	if path := violations[0].Metadata.GetStructValue().GetFields()["ancestry_path"].GetStringValue(); path != "organizations/123/folders/456/projects/my-project" {
+		t.Errorf("ValidateAssetsWithCELRules() violation ancestry_path = %q", path)
+	}
+}
+
+func TestValidateAssetsWithCELRules_evaluationError(t *testing.T) {
+	path := writeCELRules(t, t.TempDir(), "rules.yaml", `
+rules:
+- name: env-label
+  condition: asset.resource.data.labels.env == "prod"
+- name: guarded-env-label
+  condition: >
+    !has(asset.resource.data.labels) ||
+    asset.resource.data.labels.env == "prod"
+- name: not-boolean
+  condition: asset.name
+`)
+	assets := []google.Asset{{
+		Name:     "//storage.googleapis.com/my-bucket",
+		Type:     "storage.googleapis.com/Bucket",
+		Resource: &google.AssetResource{Data: map[string]interface{}{"location": "EU"}},
+	}}
+	violations, err := ValidateAssetsWithCELRules(context.Background(), assets, path)
+	if err != nil {
+		t.Fatalf("ValidateAssetsWithCELRules() = %v", err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Constraint)
+		if v.Metadata.GetStructValue().GetFields()["error"].GetStringValue() == "" {
+			t.Errorf("ValidateAssetsWithCELRules() violation of %s has no error in metadata", v.Constraint)
+		}
+	}
+	want := []string{"env-label", "not-boolean"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidateAssetsWithCELRules() violations = %q, want %q", got, want)
+	}
+}
+
+func TestLoadCELRules_errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules string
+	}{
+		{name: "missing name", rules: "rules:\n- condition: 'true'\n"},
+		{name: "missing condition", rules: "rules:\n- name: empty\n"},
+		{name: "syntax error", rules: "rules:\n- name: bad\n  condition: asset.name ==\n"},
+		{name: "not a boolean", rules: "rules:\n- name: string\n  condition: ancestry_path\n"},
+		{name: "duplicate name", rules: "rules:\n- name: dup\n  condition: 'true'\n- name: dup\n  condition: 'false'\n"},
+		{name: "unknown field", rules: "rules:\n- name: typo\n  conditon: 'true'\n"},
+		{name: "bad asset type pattern", rules: "rules:\n- name: pattern\n  asset_types: ['[']\n  condition: 'true'\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := writeCELRules(t, t.TempDir(), "rules.yaml", c.rules)
+			if _, err := LoadCELRules(path); err == nil {
+				t.Error("LoadCELRules() = nil error, want error")
+			}
+		})
+	}
+}
+
+func TestMatchAncestry(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "organizations/123/**", path: "organizations/123/folders/456/projects/p", want: true},
+		{pattern: "organizations/123/**", path: "organizations/123", want: true},
+		{pattern: "organizations/123/**", path: "organizations/1234/projects/p", want: false},
+		{pattern: "**/folders/456/**", path: "organizations/123/folders/456/projects/p", want: true},
+		{pattern: "organizations/*/projects/p", path: "organizations/123/projects/p", want: true},
+		{pattern: "organizations/*/projects/p", path: "organizations/123/folders/456/projects/p", want: false},
+		{pattern: "**", path: "", want: true},
+	}
+	for _, c := range cases {
+		if got := matchAncestry(strings.Split(c.pattern, "/"), strings.Split(c.path, "/")); got != c.want {
+			t.Errorf("matchAncestry(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
@@ -15,4 +15,17 @@
 // Package tfgcv pulls together the other packages in this project to take
 // a terraform plan, extract the planned resources in Google CAI format,
 // and run those CAI assets through the Config Validator.
+//
+// Programs embedding terraform-validator should use a Validator, which loads
+// policies once and can convert and validate plans concurrently:
+//
+//	v, err := tfgcv.NewValidator(tfgcv.Options{
+//		Project:    "my-project",
+//		Ancestry:   map[string]string{"my-project": "organizations/123/folders/456"},
+//		PolicyPath: "/path/to/policy-library",
+//	})
+//	...
+//	assets, err := v.Convert(ctx, planJSON)
+//	...
+//	violations, err := v.Validate(ctx, assets)
 package tfgcv
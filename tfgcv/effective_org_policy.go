@@ -0,0 +1,326 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+const hierarchyAssetNamePrefix = "//cloudresourcemanager.googleapis.com/"
+
+// EffectiveOrgPolicy is the organization policy in effect for a constraint on
+// a resource, after combining the policies set on its ancestors.
+type EffectiveOrgPolicy struct {
+	Resource   string `json:"resource"`
+	AssetType  string `json:"asset_type"`
+	Constraint string `json:"constraint"`
+	// Policy is nil if the default behavior of the constraint applies.
+	Policy *google.OrgPolicy `json:"policy"`
+	// Sources are the ancestors whose policies make up Policy, from the root
+	// of the resource hierarchy.
+	Sources []string `json:"sources,omitempty"`
+	// Untranslated are the ancestors with a v2 policy for the constraint that
+	// has no v1 equivalent, such as a policy with conditional rules. These
+	// policies are not part of Policy.
+	Untranslated []string `json:"untranslated,omitempty"`
+}
+
+// hierarchyPolicies holds the organization policies of the projects, folders
+// and organizations, keyed by resource name and then constraint.
+type hierarchyPolicies struct {
+	v1 map[string]map[string]*google.OrgPolicy
+	// Constraints of v2 policies that have no v1 equivalent.
+	untranslated map[string]map[string]bool
+}
+
+func newHierarchyPolicies() *hierarchyPolicies {
+	return &hierarchyPolicies{
+		v1:           make(map[string]map[string]*google.OrgPolicy),
+		untranslated: make(map[string]map[string]bool),
+	}
+}
+
+// add records the policies of asset if it is a project, folder or
+// organization. Policies added later replace earlier ones for the same
+// resource and constraint.
+func (h *hierarchyPolicies) add(asset google.Asset) {
+	if !strings.HasPrefix(asset.Name, hierarchyAssetNamePrefix) {
+		return
+	}
+	names := []string{strings.TrimPrefix(asset.Name, hierarchyAssetNamePrefix)}
+	// Ancestors may refer to a project by ID while its asset uses the number.
+	if asset.Resource != nil {
+		if id, ok := asset.Resource.Data["projectId"].(string); ok && id != "" {
+			names = append(names, "projects/"+id)
+		}
+	}
+	v1Constraints := make(map[string]bool)
+	for _, name := range names {
+		for _, p := range asset.OrgPolicy {
+			if h.v1[name] == nil {
+				h.v1[name] = make(map[string]*google.OrgPolicy)
+			}
+			h.v1[name][p.Constraint] = p
+			v1Constraints[p.Constraint] = true
+			delete(h.untranslated[name], p.Constraint)
+		}
+		for _, p := range asset.V2OrgPolicies {
+			constraint := google.V2OrgPolicyConstraint(p.Name)
+			if v1Constraints[constraint] {
+				continue
+			}
+			if h.untranslated[name] == nil {
+				h.untranslated[name] = make(map[string]bool)
+			}
+			h.untranslated[name][constraint] = true
+		}
+	}
+}
+
+// constraints returns the sorted constraints set on any of ancestors.
+func (h *hierarchyPolicies) constraints(ancestors []string) []string {
+	seen := make(map[string]bool)
+	for _, a := range ancestors {
+		for c := range h.v1[a] {
+			seen[c] = true
+		}
+		for c := range h.untranslated[a] {
+			seen[c] = true
+		}
+	}
+	constraints := make([]string, 0, len(seen))
+	for c := range seen {
+		constraints = append(constraints, c)
+	}
+	sort.Strings(constraints)
+	return constraints
+}
+
+// EffectiveOrgPolicies returns the effective organization policies of the
+// planned assets, for each constraint set on their ancestors. The policies of
+// projects, folders and organizations come from existing assets, such as a
+// CAI export, overridden by the planned assets. Planned assets should carry
+// their v2 org policies in the v1 format too (see
+// ReadOptions.NormalizeOrgPolicies).
+//
+// The hierarchy is evaluated from the root. A restore_default policy resets
+// the constraint to its default behavior, a boolean policy or a list policy
+// that does not inherit from its parent replaces the parent policy, and a
+// list policy that inherits from its parent merges its allowed and denied
+// values with those of the parent. The allowed or denied values of a list
+// policy are ignored if all values are allowed or denied.
+func EffectiveOrgPolicies(planned, existing []google.Asset) []EffectiveOrgPolicy {
+	policies := newHierarchyPolicies()
+	for _, asset := range existing {
+		policies.add(asset)
+	}
+	for _, asset := range planned {
+		policies.add(asset)
+	}
+
+	var result []EffectiveOrgPolicy
+	for _, asset := range planned {
+		for _, constraint := range policies.constraints(asset.Ancestors) {
+			effective := EffectiveOrgPolicy{
+				Resource:   asset.Name,
+				AssetType:  asset.Type,
+				Constraint: constraint,
+			}
+			for i := len(asset.Ancestors) - 1; i >= 0; i-- {
+				ancestor := asset.Ancestors[i]
+				if policies.untranslated[ancestor][constraint] {
+					effective.Untranslated = append(effective.Untranslated, ancestor)
+				}
+				p, ok := policies.v1[ancestor][constraint]
+				if !ok {
+					continue
+				}
+				if inheritsListPolicy(effective.Policy, p) {
+					effective.Policy = mergeListPolicies(effective.Policy, p)
+					effective.Sources = append(effective.Sources, ancestor)
+					continue
+				}
+				effective.Sources = []string{ancestor}
+				effective.Policy = copyOrgPolicy(p)
+				if p.RestoreDefault != nil {
+					effective.Policy = nil
+				}
+			}
+			result = append(result, effective)
+		}
+	}
+	return result
+}
+
+func inheritsListPolicy(parent, p *google.OrgPolicy) bool {
+	return parent != nil && parent.ListPolicy != nil && p.ListPolicy != nil &&
+		p.ListPolicy.InheritFromParent && p.ListPolicy.AllValues == 0
+}
+
+func mergeListPolicies(parent, p *google.OrgPolicy) *google.OrgPolicy {
+	merged := copyOrgPolicy(parent)
+	merged.ListPolicy.AllowedValues = appendUnique(merged.ListPolicy.AllowedValues, p.ListPolicy.AllowedValues)
+	merged.ListPolicy.DeniedValues = appendUnique(merged.ListPolicy.DeniedValues, p.ListPolicy.DeniedValues)
+	merged.ListPolicy.InheritFromParent = true
+	return merged
+}
+
+func copyOrgPolicy(p *google.OrgPolicy) *google.OrgPolicy {
+	c := &google.OrgPolicy{Constraint: p.Constraint}
+	if p.BooleanPolicy != nil {
+		c.BooleanPolicy = &google.BooleanPolicy{Enforced: p.BooleanPolicy.Enforced}
+	}
+	if p.ListPolicy != nil {
+		l := *p.ListPolicy
+		l.AllowedValues = append([]string(nil), l.AllowedValues...)
+		l.DeniedValues = append([]string(nil), l.DeniedValues...)
+		c.ListPolicy = &l
+	}
+	if p.RestoreDefault != nil {
+		c.RestoreDefault = &google.RestoreDefault{}
+	}
+	return c
+}
+
+func appendUnique(values, more []string) []string {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		seen[v] = true
+	}
+	for _, v := range more {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// OrgPolicyViolation is a planned resource that uses a value denied by the
+// effective policy of a list constraint.
+type OrgPolicyViolation struct {
+	Resource   string `json:"resource"`
+	AssetType  string `json:"asset_type"`
+	Constraint string `json:"constraint"`
+	Value      string `json:"value"`
+	Message    string `json:"message"`
+}
+
+// listConstraintValues returns the values that a planned asset uses for list
+// constraints that can be checked from the plan, keyed by constraint.
+var listConstraintValues = map[string]func(asset google.Asset) []string{
+	"constraints/gcp.resourceLocations":      resourceLocations,
+	"constraints/compute.vmExternalIpAccess": vmExternalIPAccess,
+}
+
+// CheckListConstraints returns the planned resources that use values denied
+// by their effective policies, for the list constraints that can be checked
+// from the plan: gcp.resourceLocations and compute.vmExternalIpAccess.
+//
+// Location value groups such as "in:us-locations" are matched by prefix, so
+// they include "us" and "us-east1" but not the regions of groups whose names
+// differ from their locations, such as "in:eu-locations".
+func CheckListConstraints(planned []google.Asset, effective []EffectiveOrgPolicy) []OrgPolicyViolation {
+	assets := make(map[string]google.Asset, len(planned))
+	for _, asset := range planned {
+		assets[asset.Type+asset.Name] = asset
+	}
+	var violations []OrgPolicyViolation
+	for _, e := range effective {
+		values, ok := listConstraintValues[e.Constraint]
+		if !ok || e.Policy == nil || e.Policy.ListPolicy == nil {
+			continue
+		}
+		for _, value := range values(assets[e.AssetType+e.Resource]) {
+			if reason := deniedBy(e.Policy.ListPolicy, value); reason != "" {
+				violations = append(violations, OrgPolicyViolation{
+					Resource:   e.Resource,
+					AssetType:  e.AssetType,
+					Constraint: e.Constraint,
+					Value:      value,
+					Message:    fmt.Sprintf("%s is %s by the effective policy of %s", value, reason, e.Constraint),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// deniedBy returns why value is denied by p, or "" if it is allowed. Denied
+// values take precedence over allowed values.
+func deniedBy(p *google.ListPolicy, value string) string {
+	switch {
+	case p.AllValues == google.ListPolicyAllValuesDeny:
+		return "denied (all values denied)"
+	case matchesAny(p.DeniedValues, value):
+		return "denied"
+	case p.AllValues == google.ListPolicyAllValuesAllow:
+		return ""
+	case len(p.AllowedValues) > 0 && !matchesAny(p.AllowedValues, value):
+		return "not allowed"
+	}
+	return ""
+}
+
+func matchesAny(policyValues []string, value string) bool {
+	for _, pv := range policyValues {
+		pv = strings.TrimPrefix(pv, "is:")
+		if group := strings.TrimPrefix(pv, "in:"); group != pv {
+			prefix := strings.TrimSuffix(group, "-locations")
+			if value == prefix || strings.HasPrefix(value, prefix+"-") {
+				return true
+			}
+			continue
+		}
+		if pv == value {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceLocations returns the location of a planned resource, in the
+// format of gcp.resourceLocations values such as "us-east1" or "us".
+func resourceLocations(asset google.Asset) []string {
+	if asset.Resource == nil || strings.HasPrefix(asset.Name, hierarchyAssetNamePrefix) {
+		return nil
+	}
+	for _, field := range []string{"location", "region", "zone"} {
+		if location, ok := asset.Resource.Data[field].(string); ok && location != "" {
+			return []string{strings.ToLower(location[strings.LastIndex(location, "/")+1:])}
+		}
+	}
+	return nil
+}
+
+// vmExternalIPAccess returns the name of a planned VM with an external IP, in
+// the format of compute.vmExternalIpAccess values.
+func vmExternalIPAccess(asset google.Asset) []string {
+	if asset.Type != "compute.googleapis.com/Instance" || asset.Resource == nil {
+		return nil
+	}
+	interfaces, _ := asset.Resource.Data["networkInterfaces"].([]interface{})
+	for _, i := range interfaces {
+		networkInterface, _ := i.(map[string]interface{})
+		if accessConfigs, _ := networkInterface["accessConfigs"].([]interface{}); len(accessConfigs) > 0 {
+			return []string{strings.TrimPrefix(asset.Name, "//compute.googleapis.com/")}
+		}
+	}
+	return nil
+}
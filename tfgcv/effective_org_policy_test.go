@@ -0,0 +1,199 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+const (
+	locationsConstraint  = "constraints/gcp.resourceLocations"
+	externalIPConstraint = "constraints/compute.vmExternalIpAccess"
+	serialPortConstraint = "constraints/compute.disableSerialPortAccess"
+)
+
+func hierarchyAsset(name string, policies ...*google.OrgPolicy) google.Asset {
+	return google.Asset{Name: "//cloudresourcemanager.googleapis.com/" + name, OrgPolicy: policies}
+}
+
+func TestEffectiveOrgPolicies(t *testing.T) {
+	existing := []google.Asset{
+		hierarchyAsset("organizations/123",
+			&google.OrgPolicy{Constraint: locationsConstraint, ListPolicy: &google.ListPolicy{AllowedValues: []string{"in:us-locations"}}},
+			&google.OrgPolicy{Constraint: serialPortConstraint, BooleanPolicy: &google.BooleanPolicy{Enforced: true}},
+			&google.OrgPolicy{Constraint: externalIPConstraint, ListPolicy: &google.ListPolicy{AllValues: google.ListPolicyAllValuesDeny}},
+		),
+		hierarchyAsset("folders/456",
+			&google.OrgPolicy{Constraint: locationsConstraint, ListPolicy: &google.ListPolicy{AllowedValues: []string{"europe-west1"}, InheritFromParent: true}},
+		),
+	}
+	planned := []google.Asset{
+		{
+			Name:          "//cloudresourcemanager.googleapis.com/projects/my-project",
+			Type:          "cloudresourcemanager.googleapis.com/Project",
+			Ancestors:     []string{"projects/my-project", "folders/456", "organizations/123"},
+			OrgPolicy:     []*google.OrgPolicy{{Constraint: serialPortConstraint, RestoreDefault: &google.RestoreDefault{}}},
+			V2OrgPolicies: []*google.V2OrgPolicies{{Name: "projects/my-project/policies/compute.requireOsLogin"}},
+		},
+		{
+			Name:      "//storage.googleapis.com/my-bucket",
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: []string{"projects/my-project", "folders/456", "organizations/123"},
+		},
+	}
+
+	got := EffectiveOrgPolicies(planned, existing)
+	var want []EffectiveOrgPolicy
+	for _, asset := range planned {
+		want = append(want,
+			EffectiveOrgPolicy{
+				Resource:   asset.Name,
+				AssetType:  asset.Type,
+				Constraint: serialPortConstraint,
+				Sources:    []string{"projects/my-project"},
+			},
+			EffectiveOrgPolicy{
+				Resource:     asset.Name,
+				AssetType:    asset.Type,
+				Constraint:   "constraints/compute.requireOsLogin",
+				Untranslated: []string{"projects/my-project"},
+			},
+			EffectiveOrgPolicy{
+				Resource:   asset.Name,
+				AssetType:  asset.Type,
+				Constraint: externalIPConstraint,
+				Policy:     &google.OrgPolicy{Constraint: externalIPConstraint, ListPolicy: &google.ListPolicy{AllValues: google.ListPolicyAllValuesDeny}},
+				Sources:    []string{"organizations/123"},
+			},
+			EffectiveOrgPolicy{
+				Resource:   asset.Name,
+				AssetType:  asset.Type,
+				Constraint: locationsConstraint,
+				Policy: &google.OrgPolicy{Constraint: locationsConstraint, ListPolicy: &google.ListPolicy{
+					AllowedValues:     []string{"in:us-locations", "europe-west1"},
+					InheritFromParent: true,
+				}},
+				Sources: []string{"organizations/123", "folders/456"},
+			},
+		)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EffectiveOrgPolicies() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestEffectiveOrgPolicies_plannedOverridesExisting(t *testing.T) {
+	existing := []google.Asset{
+		hierarchyAsset("projects/my-project",
+			&google.OrgPolicy{Constraint: serialPortConstraint, BooleanPolicy: &google.BooleanPolicy{Enforced: false}},
+		),
+	}
+	planned := []google.Asset{{
+		Name:      "//cloudresourcemanager.googleapis.com/projects/12345",
+		Type:      "cloudresourcemanager.googleapis.com/Project",
+		Resource:  &google.AssetResource{Data: map[string]interface{}{"projectId": "my-project"}},
+		Ancestors: []string{"projects/my-project", "organizations/123"},
+		OrgPolicy: []*google.OrgPolicy{{Constraint: serialPortConstraint, BooleanPolicy: &google.BooleanPolicy{Enforced: true}}},
+	}}
+
+	got := EffectiveOrgPolicies(planned, existing)
+	want := []EffectiveOrgPolicy{{
+		Resource:   planned[0].Name,
+		AssetType:  planned[0].Type,
+		Constraint: serialPortConstraint,
+		Policy:     &google.OrgPolicy{Constraint: serialPortConstraint, BooleanPolicy: &google.BooleanPolicy{Enforced: true}},
+		Sources:    []string{"projects/my-project"},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EffectiveOrgPolicies() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckListConstraints(t *testing.T) {
+	ancestors := []string{"projects/my-project", "organizations/123"}
+	existing := []google.Asset{
+		hierarchyAsset("organizations/123",
+			&google.OrgPolicy{Constraint: locationsConstraint, ListPolicy: &google.ListPolicy{
+				AllowedValues: []string{"in:us-locations", "europe-west1"},
+				DeniedValues:  []string{"us-west2"},
+			}},
+			&google.OrgPolicy{Constraint: externalIPConstraint, ListPolicy: &google.ListPolicy{
+				AllowedValues: []string{"projects/my-project/zones/us-central1-a/instances/allowed"},
+			}},
+		),
+	}
+	instance := func(name string, accessConfigs ...interface{}) google.Asset {
+		return google.Asset{
+			Name:      "//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/" + name,
+			Type:      "compute.googleapis.com/Instance",
+			Ancestors: ancestors,
+			Resource: &google.AssetResource{Data: map[string]interface{}{
+				"zone": "projects/my-project/zones/us-central1-a",
+				"networkInterfaces": []interface{}{
+					map[string]interface{}{"network": "default", "accessConfigs": accessConfigs},
+				},
+			}},
+		}
+	}
+	bucket := func(name, location string) google.Asset {
+		return google.Asset{
+			Name:      "//storage.googleapis.com/" + name,
+			Type:      "storage.googleapis.com/Bucket",
+			Ancestors: ancestors,
+			Resource:  &google.AssetResource{Data: map[string]interface{}{"location": location}},
+		}
+	}
+	planned := []google.Asset{
+		bucket("us-bucket", "US"),
+		bucket("europe-bucket", "europe-west1"),
+		bucket("asia-bucket", "asia-east1"),
+		bucket("denied-bucket", "us-west2"),
+		instance("allowed", map[string]interface{}{"natIP": "1.2.3.4"}),
+		instance("internal"),
+		instance("external", map[string]interface{}{}),
+	}
+
+	got := CheckListConstraints(planned, EffectiveOrgPolicies(planned, existing))
+	want := []OrgPolicyViolation{
+		{
+			Resource:   "//storage.googleapis.com/asia-bucket",
+			AssetType:  "storage.googleapis.com/Bucket",
+			Constraint: locationsConstraint,
+			Value:      "asia-east1",
+			Message:    "asia-east1 is not allowed by the effective policy of constraints/gcp.resourceLocations",
+		},
+		{
+			Resource:   "//storage.googleapis.com/denied-bucket",
+			AssetType:  "storage.googleapis.com/Bucket",
+			Constraint: locationsConstraint,
+			Value:      "us-west2",
+			Message:    "us-west2 is denied by the effective policy of constraints/gcp.resourceLocations",
+		},
+		{
+			Resource:   "//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/external",
+			AssetType:  "compute.googleapis.com/Instance",
+			Constraint: externalIPConstraint,
+			Value:      "projects/my-project/zones/us-central1-a/instances/external",
+			Message:    "projects/my-project/zones/us-central1-a/instances/external is not allowed by the effective policy of constraints/compute.vmExternalIpAccess",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CheckListConstraints() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -18,10 +18,12 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 
 	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
 	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google/plugin"
 	resources "github.com/GoogleCloudPlatform/terraform-validator/converters/google/resources"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
 	"github.com/pkg/errors"
@@ -30,44 +32,122 @@ import (
 
 type ReadPlannedAssetsFunc func(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string) ([]google.Asset, error)
 
+type ReadPlannedAssetsWithOptionsFunc func(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts ReadOptions) ([]google.Asset, error)
+
+// ReadOptions holds the optional inputs of ReadPlannedAssetsWithOptions.
+type ReadOptions struct {
+	// AncestryCache, if set, is read for ancestry information, and ancestry
+	// fetched using Google API is saved to it.
+	AncestryCache *ancestrymanager.DiskCache
+	// BucketProjects maps storage bucket names to the ID or number of their
+	// project, for buckets that are not part of the plan.
+	BucketProjects map[string]string
+	// ProjectNumbers maps project IDs to project numbers, so that the assets
+	// of a project addressed by ID and by number are merged without calling
+	// Google API. It is only used with MergeProjectAliases.
+	ProjectNumbers map[string]string
+	// MergeProjectAliases, if set, names project assets addressed by project
+	// ID by their project number, found in ProjectNumbers, the plan or, if
+	// online, with one resource manager API call per project. The assets of a
+	// project addressed by ID and by number are then merged.
+	MergeProjectAliases bool
+	// Concurrency is the maximum number of resource changes that are
+	// converted, fetched or looked up at the same time. Zero means
+	// google.DefaultConcurrency, and 1 converts them one at a time.
+	Concurrency int
+	// NormalizeOrgPolicies, if set, gives the organization policies of assets
+	// in both the v1 and v2 formats.
+	NormalizeOrgPolicies bool
+	// Plugins convert the resource types that have no built-in converter
+	// (see plugin.LoadDir).
+	Plugins []*plugin.Plugin
+	// ConverterDefinitions replace or add the converters of resource types
+	// of the google provider (see resources.ReadConverterDefinitions).
+	ConverterDefinitions []*resources.ConverterDefinition
+	// HTTPClient, if set, is used for Google API calls instead of a client
+	// authenticated with the default credentials.
+	HTTPClient *http.Client
+	// BasePaths overrides the base paths of Google APIs, keyed like
+	// resources.DefaultBasePaths (see resources.ReadBasePathsFile).
+	BasePaths map[string]string
+}
+
 // ReadPlannedAssets extracts CAI assets from a terraform plan file.
 // If ancestry path is provided, it assumes the project is in that path rather
-// than fetching the ancestry information using Google API. If convertUnchanged
-// is set then resources that do not have any change from their deployed state
-// are also reported in the output, otherwise only resources that are going to
-// be changed are reported.
+// than fetching the ancestry information using Google API. Folders, projects
+// and buckets created in the plan are used for the ancestry of resources
+// inside them. If convertUnchanged is set then resources that do not have any
+// change from their deployed state are also reported in the output, otherwise
+// only resources that are going to be changed are reported.
 // It ignores non-supported resources.
 func ReadPlannedAssets(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string) ([]google.Asset, error) {
-	converter, err := newConverter(ctx, path, project, zone, region, ancestry, offline, convertUnchanged, errorLogger, userAgent)
+	return ReadPlannedAssetsWithOptions(ctx, path, project, zone, region, ancestry, offline, convertUnchanged, errorLogger, userAgent, ReadOptions{})
+}
+
+// ReadPlannedAssetsWithOptions is like ReadPlannedAssets, with the optional
+// inputs given by opts.
+func ReadPlannedAssetsWithOptions(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts ReadOptions) ([]google.Asset, error) {
+	data, err := readTF12Data(path)
 	if err != nil {
 		return nil, err
 	}
+	return convertPlan(ctx, data, project, zone, region, ancestry, offline, convertUnchanged, errorLogger, userAgent, opts)
+}
 
-	data, err := readTF12Data(path)
+// convertPlan extracts CAI assets from the JSON of a terraform plan.
+func convertPlan(ctx context.Context, data []byte, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, opts ReadOptions) ([]google.Asset, error) {
+	plan, err := tfplan.ReadPlan(data)
 	if err != nil {
 		return nil, err
 	}
 
-	changes, err := tfplan.ReadResourceChanges(data)
+	amOpts := ancestrymanager.Options{
+		DiskCache:      opts.AncestryCache,
+		PlanData:       ancestrymanager.NewPlanData(plan.ResourceChanges, plan.Config),
+		BucketProjects: opts.BucketProjects,
+		ProjectNumbers: opts.ProjectNumbers,
+	}
+	converter, err := newConverter(ctx, project, zone, region, ancestry, amOpts, offline, convertUnchanged, errorLogger, userAgent, opts.HTTPClient, resources.ConfigOptions{BasePaths: opts.BasePaths})
 	if err != nil {
 		return nil, err
 	}
 
-	err = converter.AddResourceChanges(changes)
+	converter.SetNormalizeOrgPolicies(opts.NormalizeOrgPolicies)
+	converter.SetMergeProjectAliases(opts.MergeProjectAliases)
+	if opts.Concurrency != 0 {
+		converter.SetConcurrency(opts.Concurrency)
+	}
+	if err := converter.SetConverterDefinitions(opts.ConverterDefinitions); err != nil {
+		return nil, err
+	}
+	if err := converter.SetPlugins(opts.Plugins); err != nil {
+		return nil, err
+	}
+	err = converter.AddResourceChangesWithContext(ctx, plan.ResourceChanges)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.AncestryCache != nil {
+		if err := opts.AncestryCache.Save(); err != nil {
+			errorLogger.Warn(fmt.Sprintf("Failed to save ancestry cache: %s", err))
+		}
+	}
+
 	return converter.Assets(), nil
 }
 
-func newConverter(ctx context.Context, path, project, zone, region string, ancestry map[string]string, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string) (*google.Converter, error) {
-	cfg, err := resources.NewConfig(ctx, project, zone, region, offline, userAgent, nil)
+func newConverter(ctx context.Context, project, zone, region string, ancestry map[string]string, amOpts ancestrymanager.Options, offline, convertUnchanged bool, errorLogger *zap.Logger, userAgent string, client *http.Client, cfgOpts resources.ConfigOptions) (*google.Converter, error) {
+	cfg, err := resources.NewConfigWithOptions(ctx, project, zone, region, offline, userAgent, client, cfgOpts)
 	if err != nil {
 		return nil, fmt.Errorf("building google configuration: %w", err)
 	}
+	if !offline {
+		// Make API calls stop when ctx is done.
+		cfg.Client = clientWithContext(ctx, cfg.Client)
+	}
 
-	ancestryManager, err := ancestrymanager.New(cfg, offline, ancestry, errorLogger)
+	ancestryManager, err := ancestrymanager.NewWithOptions(cfg, offline, ancestry, errorLogger, amOpts)
 	if err != nil {
 		return nil, fmt.Errorf("building google ancestry manager: %w", err)
 	}
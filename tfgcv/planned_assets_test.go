@@ -1,11 +1,22 @@
 package tfgcv
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"net/http"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	crmv1 "google.golang.org/api/cloudresourcemanager/v1"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/cassette"
+	"github.com/GoogleCloudPlatform/terraform-validator/fakegcp"
 )
 
 const (
@@ -115,3 +126,227 @@ func TestReadPlannedAssets(t *testing.T) {
 		})
 	}
 }
+
+func TestReadPlannedAssets_mergeProjectAliases(t *testing.T) {
+	ancestry := map[string]string{
+		"projects/345":    testAncestryName,
+		"projects/foobar": testAncestryName,
+		"folders/567":     "organization/123",
+	}
+	for _, file := range []string{"tf0_12plan.applied.json", "tf1_0plan.applied.json"} {
+		t.Run(file, func(t *testing.T) {
+			got, err := ReadPlannedAssetsWithOptions(context.Background(), filepath.Join(testDataDir, file), "foobar", "", "", ancestry, true, true, zap.NewExample(), "", ReadOptions{MergeProjectAliases: true})
+			if err != nil {
+				t.Fatalf("ReadPlannedAssetsWithOptions() = %s, want = nil", err)
+			}
+			// The project and its IAM policy merge under the project number.
+			if len(got) != 6 {
+				t.Errorf("ReadPlannedAssetsWithOptions() = %d assets, want 6", len(got))
+			}
+		})
+	}
+}
+
+func TestReadPlannedAssets_sequential(t *testing.T) {
+	ancestry := map[string]string{
+		"projects/foobar":   testAncestryName,
+		"folders/my-folder": "organization/test-org",
+	}
+	got, err := ReadPlannedAssetsWithOptions(context.Background(), filepath.Join(testDataDir, "tf1_0plan.allcoverage.json"), "foobar", "", "", ancestry, true, false, zap.NewExample(), "", ReadOptions{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("ReadPlannedAssetsWithOptions() = %s, want = nil", err)
+	}
+	if len(got) != 9 {
+		t.Errorf("ReadPlannedAssetsWithOptions() = %d assets, want 9", len(got))
+	}
+}
+
+func TestReadPlannedAssets_newHierarchy(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.new_hierarchy.json")
+	ctx := context.Background()
+	// No ancestry is given, so the ancestry of the new project must come from
+	// the folders created in the plan.
+	got, err := ReadPlannedAssets(ctx, testFile, "", "", "", nil, true, false, zap.NewExample(), "")
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() = %s, want = nil", err)
+	}
+	newProjectAncestors := []string{"projects/my-new-project", "folders/unknown", "folders/unknown", "organizations/123"}
+	want := map[string][]string{
+		"cloudresourcemanager.googleapis.com/Project//cloudresourcemanager.googleapis.com/projects/my-new-project": newProjectAncestors,
+	}
+	gotAncestors := map[string][]string{}
+	for _, asset := range got {
+		if asset.Type == "cloudresourcemanager.googleapis.com/Project" {
+			gotAncestors[asset.Type+asset.Name] = asset.Ancestors
+			if asset.Resource != nil && asset.Resource.Parent != "//cloudresourcemanager.googleapis.com/folders/unknown" {
+				t.Errorf("project parent = %s, want = //cloudresourcemanager.googleapis.com/folders/unknown", asset.Resource.Parent)
+			}
+		}
+	}
+	if diff := cmp.Diff(want, gotAncestors); diff != "" {
+		t.Errorf("ReadPlannedAssets() returned unexpected ancestors (-want +got):\n%s", diff)
+	}
+}
+
+// onlinePlan grants roles on a project, a bucket and a pubsub topic, whose IAM
+// policies are fetched and merged online.
+const onlinePlan = `{
+  "format_version": "0.1",
+  "terraform_version": "1.3.0",
+  "resource_changes": [
+    {
+      "address": "google_project_iam_member.editor",
+      "mode": "managed",
+      "type": "google_project_iam_member",
+      "name": "editor",
+      "provider_name": "registry.terraform.io/hashicorp/google",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"project": "my-project", "role": "roles/editor", "member": "user:editor@example.com", "condition": []}
+      }
+    },
+    {
+      "address": "google_storage_bucket_iam_member.viewer",
+      "mode": "managed",
+      "type": "google_storage_bucket_iam_member",
+      "name": "viewer",
+      "provider_name": "registry.terraform.io/hashicorp/google",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"bucket": "my-bucket", "role": "roles/storage.objectViewer", "member": "user:viewer@example.com", "condition": []}
+      }
+    },
+    {
+      "address": "google_pubsub_topic_iam_member.publisher",
+      "mode": "managed",
+      "type": "google_pubsub_topic_iam_member",
+      "name": "publisher",
+      "provider_name": "registry.terraform.io/hashicorp/google",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"project": "my-project", "topic": "my-topic", "role": "roles/pubsub.publisher", "member": "user:publisher@example.com", "condition": []}
+      }
+    }
+  ]
+}`
+
+func TestConvertPlan_online(t *testing.T) {
+	fake := fakegcp.NewServer()
+	defer fake.Close()
+	fake.AddFolder(fakegcp.Folder{ID: "1", Parent: "organizations/100"})
+	fake.AddProject(fakegcp.Project{ID: "my-project", Number: "123", Parent: "folders/1"})
+	fake.AddBucket(fakegcp.Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	projectName := "//cloudresourcemanager.googleapis.com/projects/my-project"
+	fake.SetIamPolicy(projectName, &crmv1.Policy{
+		Bindings: []*crmv1.Binding{{Role: "roles/viewer", Members: []string{"user:existing@example.com"}}},
+	})
+	// The project's policy is fetched after a retry, the bucket's policy is
+	// not accessible, and the topic does not exist.
+	fake.AddFault(fakegcp.Fault{Name: projectName, Method: fakegcp.MethodGetIamPolicy, Code: http.StatusTooManyRequests, Count: 1})
+	fake.AddFault(fakegcp.Fault{Name: "//storage.googleapis.com/my-bucket", Method: fakegcp.MethodGetIamPolicy, Code: http.StatusForbidden})
+
+	var logs bytes.Buffer
+	errorLogger := zap.New(zapcore.NewCore(zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), zapcore.AddSync(&logs), zap.DebugLevel))
+	got, err := convertPlan(context.Background(), []byte(onlinePlan), "my-project", "", "", nil, false, false, errorLogger, "", ReadOptions{
+		HTTPClient: fake.Client(),
+		BasePaths:  fake.BasePaths(),
+	})
+	if err != nil {
+		t.Fatalf("convertPlan() = %s, want = nil", err)
+	}
+
+	wantAncestors := []string{"projects/my-project", "folders/1", "organizations/100"}
+	wantMembers := map[string][]string{
+		"cloudresourcemanager.googleapis.com/Project": {"user:editor@example.com", "user:existing@example.com"},
+		"storage.googleapis.com/Bucket":               {"user:viewer@example.com"},
+		"pubsub.googleapis.com/Topic":                 {"user:publisher@example.com"},
+	}
+	if len(got) != len(wantMembers) {
+		t.Fatalf("convertPlan() = %d assets, want = %d", len(got), len(wantMembers))
+	}
+	for _, asset := range got {
+		if diff := cmp.Diff(wantAncestors, asset.Ancestors); diff != "" {
+			t.Errorf("%s ancestors returned unexpected diff (-want +got):\n%s", asset.Name, diff)
+		}
+		var members []string
+		for _, b := range asset.IAMPolicy.Bindings {
+			members = append(members, b.Members...)
+		}
+		sort.Strings(members)
+		if diff := cmp.Diff(wantMembers[asset.Type], members); diff != "" {
+			t.Errorf("%s members returned unexpected diff (-want +got):\n%s", asset.Name, diff)
+		}
+	}
+	for _, address := range []string{"google_storage_bucket_iam_member.viewer", "google_pubsub_topic_iam_member.publisher"} {
+		if !strings.Contains(logs.String(), address+": Fetching") {
+			t.Errorf("logs = %q, want a warning that the policy of %s is not accessible", logs.String(), address)
+		}
+	}
+
+	codes := map[string][]int{}
+	for _, r := range fake.Requests() {
+		if r.Method == fakegcp.MethodGetIamPolicy {
+			codes[r.Name] = append(codes[r.Name], r.Code)
+		}
+	}
+	wantCodes := map[string][]int{
+		projectName:                          {http.StatusTooManyRequests, http.StatusOK},
+		"//storage.googleapis.com/my-bucket": {http.StatusForbidden},
+		"//pubsub.googleapis.com/projects/my-project/topics/my-topic": {http.StatusNotFound},
+	}
+	if diff := cmp.Diff(wantCodes, codes); diff != "" {
+		t.Errorf("getIamPolicy requests returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestConvertPlan_replay(t *testing.T) {
+	fake := fakegcp.NewServer()
+	fake.AddProject(fakegcp.Project{ID: "my-project", Number: "123", Parent: "organizations/100"})
+	fake.AddBucket(fakegcp.Bucket{Name: "my-bucket", ProjectNumber: "123"})
+	fake.SetIamPolicy("//cloudresourcemanager.googleapis.com/projects/my-project", &crmv1.Policy{
+		Bindings: []*crmv1.Binding{{Role: "roles/viewer", Members: []string{"user:existing@example.com"}}},
+	})
+	basePaths := fake.BasePaths()
+	dir := t.TempDir()
+	convert := func(plan string, rt http.RoundTripper) ([]byte, error) {
+		assets, err := convertPlan(context.Background(), []byte(plan), "my-project", "", "", nil, false, false, zap.NewExample(), "", ReadOptions{
+			HTTPClient: &http.Client{Transport: rt},
+			BasePaths:  basePaths,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(assets)
+	}
+
+	recorder, err := cassette.NewRecorder(dir, fake.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := convert(onlinePlan, recorder)
+	if err != nil {
+		t.Fatalf("recording convertPlan() = %s, want = nil", err)
+	}
+	fake.Close()
+
+	replayer, err := cassette.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := convert(onlinePlan, replayer)
+	if err != nil {
+		t.Fatalf("replaying convertPlan() = %s, want = nil", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("replayed assets differ from recorded assets:\nwant: %s\ngot:  %s", want, got)
+	}
+
+	// The requests of another project were not recorded.
+	_, err = convert(strings.ReplaceAll(onlinePlan, "my-project", "other-project"), replayer)
+	if err == nil || !strings.Contains(err.Error(), cassette.ErrUnmatched.Error()) {
+		t.Errorf("replaying convertPlan() with another project = %v, want = %v", err, cassette.ErrUnmatched)
+	}
+}
@@ -15,22 +15,59 @@
 package tfgcv
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 )
 
 // protoViaJSON uses JSON as an intermediary serialization to convert a value into
 // a protobuf message.
 func protoViaJSON(from interface{}, to proto.Message) error {
+	return unmarshalViaJSON(&jsonpb.Unmarshaler{}, from, to)
+}
+
+// assetToProto converts an asset into the message reviewed by GCV. The Access
+// Context Manager payload is a copy of the resource data, which may hold
+// fields that the API message does not know, so unknown fields are ignored in
+// it. The rest of the asset is converted strictly.
+func assetToProto(asset google.Asset) (*validator.Asset, error) {
+	payload := map[string]google.AccessContextPolicy{
+		"access_policy":     asset.AccessPolicy,
+		"access_level":      asset.AccessLevel,
+		"service_perimeter": asset.ServicePerimeter,
+	}
+	asset.AccessPolicy, asset.AccessLevel, asset.ServicePerimeter = nil, nil, nil
+
+	pbAsset := &validator.Asset{}
+	if err := protoViaJSON(asset, pbAsset); err != nil {
+		return nil, err
+	}
+	for field, policy := range payload {
+		if policy == nil {
+			continue
+		}
+		pbPolicy := &validator.Asset{}
+		if err := unmarshalViaJSON(&jsonpb.Unmarshaler{AllowUnknownFields: true}, map[string]interface{}{field: policy}, pbPolicy); err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		pbAsset.AccessContextPolicy = pbPolicy.AccessContextPolicy
+	}
+	return pbAsset, nil
+}
+
+func unmarshalViaJSON(u *jsonpb.Unmarshaler, from interface{}, to proto.Message) error {
 	jsn, err := json.Marshal(from)
 	if err != nil {
 		return fmt.Errorf("marshaling to json: %w", err)
 	}
 
-	if err := jsonpb.UnmarshalString(string(jsn), to); err != nil {
+	if err := u.Unmarshal(bytes.NewReader(jsn), to); err != nil {
 		return fmt.Errorf("unmarshaling to proto: %w", err)
 	}
 
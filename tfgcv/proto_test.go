@@ -22,6 +22,9 @@ import (
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/genproto/googleapis/cloud/asset/v1"
+	accesscontextmanager "google.golang.org/genproto/googleapis/identity/accesscontextmanager/v1"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 )
 
 func TestProtoViaJSON(t *testing.T) {
@@ -85,6 +88,35 @@ func TestProtoViaJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ServicePerimeter",
+			input: google.Asset{
+				Name: "//accesscontextmanager.googleapis.com/accessPolicies/987654/servicePerimeters/restrict_storage",
+				Type: "accesscontextmanager.googleapis.com/ServicePerimeter",
+				ServicePerimeter: google.AccessContextPolicy{
+					"name":          "accessPolicies/987654/servicePerimeters/restrict_storage",
+					"perimeterType": "PERIMETER_TYPE_REGULAR",
+					"status": map[string]interface{}{
+						"resources":          []interface{}{"projects/54321"},
+						"restrictedServices": []interface{}{"storage.googleapis.com"},
+					},
+				},
+			},
+			expected: &validator.Asset{
+				Name:      "//accesscontextmanager.googleapis.com/accessPolicies/987654/servicePerimeters/restrict_storage",
+				AssetType: "accesscontextmanager.googleapis.com/ServicePerimeter",
+				AccessContextPolicy: &validator.Asset_ServicePerimeter{
+					ServicePerimeter: &accesscontextmanager.ServicePerimeter{
+						Name:          "accessPolicies/987654/servicePerimeters/restrict_storage",
+						PerimeterType: accesscontextmanager.ServicePerimeter_PERIMETER_TYPE_REGULAR,
+						Status: &accesscontextmanager.ServicePerimeterConfig{
+							Resources:          []string{"projects/54321"},
+							RestrictedServices: []string{"storage.googleapis.com"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -97,3 +129,30 @@ func TestProtoViaJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAssetToProto_accessContextPolicyUnknownFields(t *testing.T) {
+	got, err := assetToProto(google.Asset{
+		Name: "//accesscontextmanager.googleapis.com/accessPolicies/987654/accessLevels/restrict_storage",
+		Type: "accesscontextmanager.googleapis.com/AccessLevel",
+		AccessLevel: google.AccessContextPolicy{
+			"name":  "accessPolicies/987654/accessLevels/restrict_storage",
+			"title": "restrict_storage",
+			// Copied from resource data that the API message has no field for.
+			"unknownField": "value",
+		},
+	})
+	if err != nil {
+		t.Fatalf("assetToProto() = %s, want = nil", err)
+	}
+	want := &validator.Asset{
+		Name:      "//accesscontextmanager.googleapis.com/accessPolicies/987654/accessLevels/restrict_storage",
+		AssetType: "accesscontextmanager.googleapis.com/AccessLevel",
+		AccessContextPolicy: &validator.Asset_AccessLevel{
+			AccessLevel: &accesscontextmanager.AccessLevel{
+				Name:  "accessPolicies/987654/accessLevels/restrict_storage",
+				Title: "restrict_storage",
+			},
+		},
+	}
+	require.True(t, proto.Equal(want, got), "assetToProto() = %v, want = %v", got, want)
+}
@@ -31,19 +31,29 @@ type ValidateAssetsFunc func(ctx context.Context, assets []google.Asset, policyR
 // ValidateAssets instantiates GCV and audits CAI assets using "policies"
 // and "lib" folder under policyRootPath.
 func ValidateAssets(ctx context.Context, assets []google.Asset, policyRootPath string) ([]*validator.Violation, error) {
+	policiesPath, libPath, err := policyLibraryPaths(policyRootPath)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateAssetsWithLibrary(ctx, assets,
+		[]string{policiesPath},
+		libPath)
+}
+
+// policyLibraryPaths returns the "policies" and "lib" folders under
+// policyRootPath.
+func policyLibraryPaths(policyRootPath string) (string, string, error) {
 	policiesPath := filepath.Join(policyRootPath, "policies")
 	libPath := filepath.Join(policyRootPath, "lib")
 	_, err := os.Stat(policiesPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read files in %s", policiesPath)
+		return "", "", fmt.Errorf("failed to read files in %s", policiesPath)
 	}
 	_, err = os.Stat(libPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read files in %s", libPath)
+		return "", "", fmt.Errorf("failed to read files in %s", libPath)
 	}
-	return ValidateAssetsWithLibrary(ctx, assets,
-		[]string{policiesPath},
-		libPath)
+	return policiesPath, libPath, nil
 }
 
 // ValidateAssetsWithLibrary instantiates GCV and audits CAI assets.
@@ -52,13 +62,19 @@ func ValidateAssetsWithLibrary(ctx context.Context, assets []google.Asset, polic
 	if err != nil {
 		return nil, fmt.Errorf("initializing gcv validator: %w", err)
 	}
+	return reviewAssets(ctx, valid, assets)
+}
 
+// reviewAssets audits CAI assets with an instantiated GCV. It stops with the
+// error of ctx once ctx is done.
+func reviewAssets(ctx context.Context, valid *gcv.Validator, assets []google.Asset) ([]*validator.Violation, error) {
 	pbAssets := make([]*validator.Asset, len(assets))
 	for i := range assets {
-		pbAssets[i] = &validator.Asset{}
-		if err := protoViaJSON(assets[i], pbAssets[i]); err != nil {
+		pbAsset, err := assetToProto(assets[i])
+		if err != nil {
 			return nil, fmt.Errorf("converting asset %s to proto: %w", assets[i].Name, err)
 		}
+		pbAssets[i] = pbAsset
 	}
 
 	pbSplitAssets := splitAssets(pbAssets)
@@ -67,7 +83,10 @@ func ValidateAssetsWithLibrary(ctx context.Context, assets []google.Asset, polic
 	// can be properly serialized to JSON.
 	violations := []*validator.Violation{}
 	for _, asset := range pbSplitAssets {
-		newViolations, err := valid.ReviewAsset(context.Background(), asset)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		newViolations, err := valid.ReviewAsset(ctx, asset)
 
 		if err != nil {
 			return nil, fmt.Errorf("reviewing asset %s: %w", asset, err)
@@ -0,0 +1,131 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/config-validator/pkg/api/validator"
+	"github.com/GoogleCloudPlatform/config-validator/pkg/gcv"
+	"go.uber.org/zap"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// Options configures a Validator.
+type Options struct {
+	// Project, Zone and Region are the defaults of the google provider, used
+	// for resources that do not set them.
+	Project string
+	Zone    string
+	Region  string
+	// Ancestry maps projects, folders and project numbers to ancestry paths,
+	// like the entries of an ancestry file. It is copied by NewValidator.
+	Ancestry map[string]string
+	// Offline disables Google API calls. The ancestry of all projects must
+	// then be known from Ancestry, the AncestryCache or the plan.
+	Offline bool
+	// ConvertUnchanged also converts the resources that the plan does not
+	// change.
+	ConvertUnchanged bool
+	// Logger receives the warnings and debug messages of conversions. It
+	// defaults to a no-op logger.
+	Logger *zap.Logger
+	// UserAgent is sent with Google API calls.
+	UserAgent string
+	// PolicyPath is a directory with the "policies" and "lib" directories of
+	// a Constraint Framework policy library.
+	PolicyPath string
+	// CELRules is a YAML file, or a directory of YAML files, of CEL rules
+	// (see CELRule).
+	CELRules string
+
+	// ReadOptions holds the other inputs of conversions, like the ancestry
+	// cache, plugins and the HTTP client of Google API calls.
+	ReadOptions
+}
+
+// Validator converts terraform plans to CAI assets and validates assets
+// against the policies of its Options, which are loaded once. It is safe for
+// concurrent use.
+type Validator struct {
+	opts     Options
+	gcv      *gcv.Validator
+	celRules []*CELRule
+}
+
+// NewValidator returns a Validator, loading the policies of opts.
+func NewValidator(opts Options) (*Validator, error) {
+	v := &Validator{opts: opts}
+	v.opts.Ancestry = make(map[string]string, len(opts.Ancestry))
+	for key, ancestry := range opts.Ancestry {
+		v.opts.Ancestry[key] = ancestry
+	}
+	if v.opts.Logger == nil {
+		v.opts.Logger = zap.NewNop()
+	}
+
+	if opts.PolicyPath != "" {
+		policiesPath, libPath, err := policyLibraryPaths(opts.PolicyPath)
+		if err != nil {
+			return nil, err
+		}
+		v.gcv, err = gcv.NewValidator([]string{policiesPath}, libPath)
+		if err != nil {
+			return nil, fmt.Errorf("initializing gcv validator: %w", err)
+		}
+	}
+	if opts.CELRules != "" {
+		var err error
+		v.celRules, err = LoadCELRules(opts.CELRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Convert extracts the CAI assets of the JSON of a terraform plan, like
+// ReadPlannedAssetsWithOptions. It stops with the error of ctx, including in
+// Google API calls, once ctx is done.
+func (v *Validator) Convert(ctx context.Context, plan []byte) ([]google.Asset, error) {
+	o := v.opts
+	return convertPlan(ctx, plan, o.Project, o.Zone, o.Region, o.Ancestry, o.Offline, o.ConvertUnchanged, o.Logger, o.UserAgent, o.ReadOptions)
+}
+
+// Validate audits CAI assets with the Constraint Framework policies and the
+// CEL rules of the Validator. It stops with the error of ctx once ctx is
+// done.
+func (v *Validator) Validate(ctx context.Context, assets []google.Asset) ([]*validator.Violation, error) {
+	// Make an empty slice, not a nil slice, so that this
+	// can be properly serialized to JSON.
+	violations := []*validator.Violation{}
+	if v.gcv != nil {
+		gcvViolations, err := reviewAssets(ctx, v.gcv, assets)
+		if err != nil {
+			return nil, fmt.Errorf("validating: %w", err)
+		}
+		violations = append(violations, gcvViolations...)
+	}
+	if len(v.celRules) > 0 {
+		celViolations, err := ReviewAssetsWithCELRules(ctx, assets, v.celRules)
+		if err != nil {
+			return nil, fmt.Errorf("validating with CEL rules: %w", err)
+		}
+		violations = append(violations, celViolations...)
+	}
+	return violations, nil
+}
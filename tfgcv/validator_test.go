@@ -0,0 +1,161 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidatorConvert(t *testing.T) {
+	plan, err := ioutil.ReadFile(filepath.Join(testDataDir, "tf0_12plan.allcoverage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ancestry := map[string]string{
+		"projects/foobar":         testAncestryName,
+		"folders/my-folder":       "organization/test-org",
+		"organizations/123456789": "",
+	}
+	wantAncestry := map[string]string{}
+	for k, v := range ancestry {
+		wantAncestry[k] = v
+	}
+	v, err := NewValidator(Options{Project: "foobar", Ancestry: ancestry, Offline: true})
+	if err != nil {
+		t.Fatalf("NewValidator() = %s, want = nil", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := v.Convert(context.Background(), plan)
+			if err != nil {
+				t.Errorf("Convert() = %s, want = nil", err)
+				return
+			}
+			if len(got) != 9 {
+				t.Errorf("Convert() = %d assets, want 9", len(got))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if diff := cmp.Diff(wantAncestry, ancestry); diff != "" {
+		t.Errorf("Convert() modified the ancestry option (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidatorConvert_canceled(t *testing.T) {
+	plan, err := ioutil.ReadFile(filepath.Join(testDataDir, "tf0_12plan.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewValidator(Options{
+		Project:  testProjectName,
+		Ancestry: map[string]string{"projects/" + testProjectName: testAncestryName},
+		Offline:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewValidator() = %s, want = nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := v.Convert(ctx, plan); !errors.Is(err, context.Canceled) {
+		t.Errorf("Convert() = %v, want = %s", err, context.Canceled)
+	}
+}
+
+func TestValidatorValidate(t *testing.T) {
+	plan, err := ioutil.ReadFile(filepath.Join(testDataDir, "tf0_12plan.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	v, err := NewValidator(Options{
+		Project: testProjectName,
+		// The always_violate constraint targets organizations/**.
+		Ancestry:   map[string]string{"projects/" + testProjectName: "organizations/123/" + testAncestryName},
+		Offline:    true,
+		PolicyPath: "../testdata/sample_policies/always_violate",
+		CELRules: writeCELRules(t, dir, "rules.yaml", `
+rules:
+- name: never-violates
+  condition: "true"
+`),
+	})
+	if err != nil {
+		t.Fatalf("NewValidator() = %s, want = nil", err)
+	}
+	assets, err := v.Convert(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Convert() = %s, want = nil", err)
+	}
+
+	violations, err := v.Validate(context.Background(), assets)
+	if err != nil {
+		t.Fatalf("Validate() = %s, want = nil", err)
+	}
+	if len(violations) != len(assets) {
+		t.Errorf("Validate() = %d violations, want %d", len(violations), len(assets))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := v.Validate(ctx, assets); !errors.Is(err, context.Canceled) {
+		t.Errorf("Validate() = %v, want = %s", err, context.Canceled)
+	}
+}
+
+func TestClientWithContext(t *testing.T) {
+	released := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-released:
+		}
+	}))
+	defer ts.Close()
+	defer close(released)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := clientWithContext(ctx, ts.Client())
+	done := make(chan error)
+	go func() {
+		// Like API calls, the request has no context of its own.
+		_, err := client.Get(ts.URL)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Get() = %v, want = %s", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Get() was not canceled with its context")
+	}
+}
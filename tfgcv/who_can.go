@@ -0,0 +1,195 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+// IAMQuery selects the IAM grants returned by WhoCan. Empty Role or Member
+// match any role or member.
+type IAMQuery struct {
+	// Resource is the asset name of the resource, such as
+	// "//storage.googleapis.com/my-bucket", or the name of a project, folder
+	// or organization, such as "projects/my-project".
+	Resource string
+	Role     string
+	Member   string
+}
+
+// EffectiveIAMGrant is the grant of a role to a member on a resource, by the
+// IAM policy of the resource or of one of its ancestors.
+type EffectiveIAMGrant struct {
+	Role   string `json:"role"`
+	Member string `json:"member"`
+	// Condition is nil for unconditional grants. Conditions are not evaluated.
+	Condition *google.Expr `json:"condition,omitempty"`
+	// Asset is the name of the asset whose IAM policy makes the grant.
+	Asset     string `json:"asset"`
+	AssetType string `json:"asset_type"`
+	// Addresses are the addresses of the Terraform resources making the
+	// grant. They are empty for grants of existing or fetched policies that
+	// the plan does not make.
+	Addresses []string `json:"addresses,omitempty"`
+	// Planned is false for grants that come from existing assets.
+	Planned bool `json:"planned"`
+}
+
+// WhoCanResult holds the IAM grants in effect on a resource.
+type WhoCanResult struct {
+	Resource string `json:"resource"`
+	// Ancestors of the resource, from the resource itself or its project to
+	// the root of the resource hierarchy.
+	Ancestors []string            `json:"ancestors"`
+	Grants    []EffectiveIAMGrant `json:"grants"`
+	// RolesByMember and MembersByRole summarize Grants.
+	RolesByMember map[string][]string `json:"roles_by_member"`
+	MembersByRole map[string][]string `json:"members_by_role"`
+}
+
+// iamAsset is an asset and whether it comes from the plan.
+type iamAsset struct {
+	asset   google.Asset
+	planned bool
+}
+
+// names returns the names a resource may be referred to by: the asset name
+// and, for projects, folders and organizations, the resource name, along with
+// "projects/<project id>" for projects named by number.
+func (a iamAsset) names() []string {
+	names := []string{a.asset.Name}
+	if !strings.HasPrefix(a.asset.Name, hierarchyAssetNamePrefix) {
+		return names
+	}
+	names = append(names, strings.TrimPrefix(a.asset.Name, hierarchyAssetNamePrefix))
+	if a.asset.Resource != nil {
+		if id, ok := a.asset.Resource.Data["projectId"].(string); ok && id != "" {
+			names = append(names, "projects/"+id)
+		}
+	}
+	return names
+}
+
+// WhoCan returns the IAM grants in effect on q.Resource that match the role
+// and member of q. The grants come from the IAM policies of the resource and
+// of its ancestors, listed from the root of the hierarchy down to the
+// resource. Existing assets, such as a CAI export, are overridden by the
+// planned assets of the same type and name.
+func WhoCan(planned, existing []google.Asset, q IAMQuery) (*WhoCanResult, error) {
+	var assets []iamAsset
+	plannedKeys := make(map[string]bool)
+	for _, a := range planned {
+		plannedKeys[a.Type+a.Name] = true
+	}
+	for _, a := range existing {
+		if !plannedKeys[a.Type+a.Name] {
+			assets = append(assets, iamAsset{asset: a})
+		}
+	}
+	for _, a := range planned {
+		assets = append(assets, iamAsset{asset: a, planned: true})
+	}
+
+	byName := make(map[string][]iamAsset)
+	for _, a := range assets {
+		for _, n := range a.names() {
+			byName[n] = append(byName[n], a)
+		}
+	}
+
+	ancestors := resourceAncestors(assets, byName, q.Resource)
+	if len(byName[q.Resource]) == 0 && len(ancestors) == 0 {
+		return nil, fmt.Errorf("resource %s not found in the plan or existing assets", q.Resource)
+	}
+
+	// Walk the hierarchy from the root, then the resource itself.
+	var chain []iamAsset
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		chain = append(chain, byName[ancestors[i]]...)
+	}
+	chain = append(chain, byName[q.Resource]...)
+
+	result := &WhoCanResult{
+		Resource:      q.Resource,
+		Ancestors:     ancestors,
+		Grants:        []EffectiveIAMGrant{},
+		RolesByMember: make(map[string][]string),
+		MembersByRole: make(map[string][]string),
+	}
+	seen := make(map[string]bool)
+	for _, a := range chain {
+		key := a.asset.Type + a.asset.Name
+		if seen[key] || a.asset.IAMPolicy == nil {
+			continue
+		}
+		seen[key] = true
+		for _, b := range a.asset.IAMPolicy.Bindings {
+			if q.Role != "" && b.Role != q.Role {
+				continue
+			}
+			var condition google.Expr
+			if b.Condition != nil {
+				condition = *b.Condition
+			}
+			for _, m := range b.Members {
+				if q.Member != "" && m != q.Member {
+					continue
+				}
+				result.Grants = append(result.Grants, EffectiveIAMGrant{
+					Role:      b.Role,
+					Member:    m,
+					Condition: b.Condition,
+					Asset:     a.asset.Name,
+					AssetType: a.asset.Type,
+					Addresses: a.asset.GrantAddresses[google.IAMGrant{Role: b.Role, Member: m, Condition: condition}],
+					Planned:   a.planned,
+				})
+				result.RolesByMember[m] = appendUnique(result.RolesByMember[m], []string{b.Role})
+				result.MembersByRole[b.Role] = appendUnique(result.MembersByRole[b.Role], []string{m})
+			}
+		}
+	}
+	for _, roles := range result.RolesByMember {
+		sort.Strings(roles)
+	}
+	for _, members := range result.MembersByRole {
+		sort.Strings(members)
+	}
+	return result, nil
+}
+
+// resourceAncestors returns the ancestors of the resource named name, taken
+// from its assets or, for a project, folder or organization without an
+// asset, from the ancestors of any asset below it.
+func resourceAncestors(assets []iamAsset, byName map[string][]iamAsset, name string) []string {
+	for _, a := range byName[name] {
+		if len(a.asset.Ancestors) > 0 {
+			return a.asset.Ancestors
+		}
+	}
+	short := strings.TrimPrefix(name, hierarchyAssetNamePrefix)
+	for _, a := range assets {
+		for i, ancestor := range a.asset.Ancestors {
+			if ancestor == short {
+				return a.asset.Ancestors[i:]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+func iamAssetFixture(name, assetType string, ancestors []string, bindings ...google.IAMBinding) google.Asset {
+	return google.Asset{
+		Name:      name,
+		Type:      assetType,
+		Ancestors: ancestors,
+		IAMPolicy: &google.IAMPolicy{Bindings: bindings},
+	}
+}
+
+func TestWhoCan(t *testing.T) {
+	const (
+		orgName     = "//cloudresourcemanager.googleapis.com/organizations/123"
+		folderName  = "//cloudresourcemanager.googleapis.com/folders/456"
+		projectName = "//cloudresourcemanager.googleapis.com/projects/789"
+		bucketName  = "//storage.googleapis.com/my-bucket"
+	)
+	ancestors := []string{"projects/789", "folders/456", "organizations/123"}
+	condition := &google.Expr{Title: "expires", Expression: `request.time < timestamp("2030-01-01T00:00:00Z")`}
+
+	existing := []google.Asset{
+		iamAssetFixture(orgName, "cloudresourcemanager.googleapis.com/Organization", nil,
+			google.IAMBinding{Role: "roles/owner", Members: []string{"group:org-admins@example.com"}}),
+		iamAssetFixture(folderName, "cloudresourcemanager.googleapis.com/Folder", nil,
+			google.IAMBinding{Role: "roles/viewer", Members: []string{"group:auditors@example.com"}}),
+		// Overridden by the planned project.
+		iamAssetFixture(projectName, "cloudresourcemanager.googleapis.com/Project", ancestors,
+			google.IAMBinding{Role: "roles/owner", Members: []string{"user:removed@example.com"}}),
+	}
+	project := iamAssetFixture(projectName, "cloudresourcemanager.googleapis.com/Project", ancestors,
+		google.IAMBinding{Role: "roles/owner", Members: []string{"user:alice@example.com", "user:existing@example.com"}},
+		google.IAMBinding{Role: "roles/owner", Members: []string{"user:bob@example.com"}, Condition: condition},
+	)
+	project.Resource = &google.AssetResource{Data: map[string]interface{}{"projectId": "my-project"}}
+	project.GrantAddresses = map[google.IAMGrant][]string{
+		{Role: "roles/owner", Member: "user:alice@example.com"}:                      {"google_project_iam_member.alice"},
+		{Role: "roles/owner", Member: "user:bob@example.com", Condition: *condition}: {"google_project_iam_member.bob"},
+	}
+	bucket := iamAssetFixture(bucketName, "storage.googleapis.com/Bucket", ancestors,
+		google.IAMBinding{Role: "roles/storage.admin", Members: []string{"user:alice@example.com"}})
+	bucket.GrantAddresses = map[google.IAMGrant][]string{
+		{Role: "roles/storage.admin", Member: "user:alice@example.com"}: {"google_storage_bucket_iam_member.alice"},
+	}
+	planned := []google.Asset{project, bucket}
+
+	orgOwner := EffectiveIAMGrant{Role: "roles/owner", Member: "group:org-admins@example.com", Asset: orgName, AssetType: "cloudresourcemanager.googleapis.com/Organization"}
+	folderViewer := EffectiveIAMGrant{Role: "roles/viewer", Member: "group:auditors@example.com", Asset: folderName, AssetType: "cloudresourcemanager.googleapis.com/Folder"}
+	alice := EffectiveIAMGrant{Role: "roles/owner", Member: "user:alice@example.com", Asset: projectName, AssetType: "cloudresourcemanager.googleapis.com/Project", Addresses: []string{"google_project_iam_member.alice"}, Planned: true}
+	existingOwner := EffectiveIAMGrant{Role: "roles/owner", Member: "user:existing@example.com", Asset: projectName, AssetType: "cloudresourcemanager.googleapis.com/Project", Planned: true}
+	bob := EffectiveIAMGrant{Role: "roles/owner", Member: "user:bob@example.com", Condition: condition, Asset: projectName, AssetType: "cloudresourcemanager.googleapis.com/Project", Addresses: []string{"google_project_iam_member.bob"}, Planned: true}
+	aliceBucket := EffectiveIAMGrant{Role: "roles/storage.admin", Member: "user:alice@example.com", Asset: bucketName, AssetType: "storage.googleapis.com/Bucket", Addresses: []string{"google_storage_bucket_iam_member.alice"}, Planned: true}
+
+	cases := []struct {
+		name  string
+		query IAMQuery
+		want  *WhoCanResult
+	}{
+		{
+			name:  "role on project by ID",
+			query: IAMQuery{Resource: "projects/my-project", Role: "roles/owner"},
+			want: &WhoCanResult{
+				Resource:      "projects/my-project",
+				Ancestors:     ancestors,
+				Grants:        []EffectiveIAMGrant{orgOwner, alice, existingOwner, bob},
+				RolesByMember: map[string][]string{"group:org-admins@example.com": {"roles/owner"}, "user:alice@example.com": {"roles/owner"}, "user:existing@example.com": {"roles/owner"}, "user:bob@example.com": {"roles/owner"}},
+				MembersByRole: map[string][]string{"roles/owner": {"group:org-admins@example.com", "user:alice@example.com", "user:bob@example.com", "user:existing@example.com"}},
+			},
+		},
+		{
+			name:  "member on bucket",
+			query: IAMQuery{Resource: bucketName, Member: "user:alice@example.com"},
+			want: &WhoCanResult{
+				Resource:      bucketName,
+				Ancestors:     ancestors,
+				Grants:        []EffectiveIAMGrant{alice, aliceBucket},
+				RolesByMember: map[string][]string{"user:alice@example.com": {"roles/owner", "roles/storage.admin"}},
+				MembersByRole: map[string][]string{"roles/owner": {"user:alice@example.com"}, "roles/storage.admin": {"user:alice@example.com"}},
+			},
+		},
+		{
+			name:  "folder without ancestors",
+			query: IAMQuery{Resource: "folders/456"},
+			want: &WhoCanResult{
+				Resource:      "folders/456",
+				Ancestors:     []string{"folders/456", "organizations/123"},
+				Grants:        []EffectiveIAMGrant{orgOwner, folderViewer},
+				RolesByMember: map[string][]string{"group:org-admins@example.com": {"roles/owner"}, "group:auditors@example.com": {"roles/viewer"}},
+				MembersByRole: map[string][]string{"roles/owner": {"group:org-admins@example.com"}, "roles/viewer": {"group:auditors@example.com"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := WhoCan(planned, existing, c.query)
+			if err != nil {
+				t.Fatalf("WhoCan() = %v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("WhoCan() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWhoCan_unknownResource(t *testing.T) {
+	if _, err := WhoCan(nil, nil, IAMQuery{Resource: "projects/unknown"}); err == nil {
+		t.Error("WhoCan() = nil error, want error")
+	}
+}
@@ -41,7 +41,16 @@ func IsNoOp(rc *tfjson.ResourceChange) bool {
 
 // ReadResourceChanges returns the list of resource changes from a json plan
 func ReadResourceChanges(data []byte) ([]*tfjson.ResourceChange, error) {
-	plan := tfjson.Plan{}
+	plan, err := ReadPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	return plan.ResourceChanges, nil
+}
+
+// ReadPlan parses and validates a JSON plan.
+func ReadPlan(data []byte) (*tfjson.Plan, error) {
+	plan := &tfjson.Plan{}
 	err := plan.UnmarshalJSON(data)
 	if err != nil {
 		return nil, fmt.Errorf("reading JSON plan: %w", err)
@@ -52,5 +61,5 @@ func ReadResourceChanges(data []byte) ([]*tfjson.ResourceChange, error) {
 		return nil, fmt.Errorf("validating JSON plan: %w", err)
 	}
 
-	return plan.ResourceChanges, nil
+	return plan, nil
 }